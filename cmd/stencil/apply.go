@@ -0,0 +1,61 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the apply command
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/internal/cmd/stencil"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// NewApplyCommand returns a new urfave/cli.Command for the apply
+// command
+func NewApplyCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name:  "apply",
+		Usage: "Renders a single module of the current project",
+		Description: "Renders the current project, including the module hooks other modules " +
+			"add to the provided module, but only writes the files generated by the provided module. " +
+			"Post-run commands are not ran",
+		ArgsUsage: "<import-path>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Don't write files to disk",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return errors.New("expected exactly one argument, the import path of the module")
+			}
+
+			manifest, err := configuration.LoadDefaultManifest()
+			if err != nil {
+				return fmt.Errorf("failed to parse stencil.yaml: %w", err)
+			}
+
+			return stencil.NewCommand(log, manifest, c.Bool("dry-run"), false).
+				Apply(c.Context, c.Args().Get(0))
+		},
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the arg command
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/internal/cmd/stencil"
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gopkg.in/yaml.v3"
+)
+
+// NewArgCommand returns a new urfave/cli.Command for the arg command
+// set
+func NewArgCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name:  "arg",
+		Usage: "examine the arguments passed to modules",
+		Subcommands: []*cli.Command{
+			NewArgExplainCommand(log),
+		},
+	}
+}
+
+// NewArgExplainCommand returns a new urfave/cli.Command for the arg
+// explain command.
+func NewArgExplainCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name:  "explain",
+		Usage: "Explains how the value of a module's argument is derived",
+		Description: "Shows the value of an argument as seen by a module's templates " +
+			"(stencil.Arg), where it came from and the schema it's validated against",
+		ArgsUsage: "<module> <path>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 2 {
+				return errors.New("expected exactly two arguments, module and argument path")
+			}
+
+			manifest, err := configuration.LoadDefaultManifest()
+			if err != nil {
+				return fmt.Errorf("failed to parse stencil.yaml: %w", err)
+			}
+
+			expl, err := stencil.NewCommand(log, manifest, false, false).
+				ExplainArg(c.Context, c.Args().Get(0), c.Args().Get(1))
+			if err != nil {
+				return err
+			}
+
+			return printArgExplanation(c.App.Writer, expl)
+		},
+	}
+}
+
+// printArgExplanation prints an argument explanation in a human
+// readable format.
+func printArgExplanation(w io.Writer, expl *codegen.ArgExplanation) error {
+	fmt.Fprintf(w, "Argument %q of module %s\n", expl.Path, expl.Module)
+
+	switch expl.Source {
+	case codegen.ArgSourceProject:
+		fmt.Fprintln(w, "  source: set in stencil.yaml")
+	case codegen.ArgSourceDefault:
+		fmt.Fprintf(w, "  source: default declared by %s\n", expl.DefinedBy)
+	case codegen.ArgSourceZeroValue:
+		fmt.Fprintln(w, "  source: not set, zero value of the schema's type")
+	}
+
+	if expl.DefinedBy != expl.Module {
+		fmt.Fprintf(w, "  defined by: %s (through \"from\")\n", expl.DefinedBy)
+	}
+
+	if err := printYAMLField(w, "value", expl.Value); err != nil {
+		return err
+	}
+	if expl.Schema != nil {
+		if err := printYAMLField(w, "schema", expl.Schema); err != nil {
+			return err
+		}
+	}
+	if len(expl.Examples) > 0 {
+		return printYAMLField(w, "examples", expl.Examples)
+	}
+	return nil
+}
+
+// printYAMLField prints the provided value as YAML under the provided
+// name, indented to match the rest of the explanation.
+func printYAMLField(w io.Writer, name string, v any) error {
+	b, err := yaml.Marshal(map[string]any{name: v})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	return nil
+}
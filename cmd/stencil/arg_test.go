@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"gotest.tools/v3/assert"
+)
+
+func TestPrintArgExplanation(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NilError(t, printArgExplanation(&buf, &codegen.ArgExplanation{
+		Module:    "github.com/rgst-io/a",
+		Path:      "hello",
+		DefinedBy: "github.com/rgst-io/b",
+		Source:    codegen.ArgSourceProject,
+		Value:     "world",
+		Schema:    map[string]any{"type": "string"},
+	}))
+
+	assert.Equal(t, buf.String(), `Argument "hello" of module github.com/rgst-io/a
+  source: set in stencil.yaml
+  defined by: github.com/rgst-io/b (through "from")
+  value: world
+  schema:
+      type: string
+`)
+}
+
+func TestPrintArgExplanationExamples(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NilError(t, printArgExplanation(&buf, &codegen.ArgExplanation{
+		Module:    "github.com/rgst-io/a",
+		Path:      "port",
+		DefinedBy: "github.com/rgst-io/a",
+		Source:    codegen.ArgSourceDefault,
+		Value:     8080,
+		Examples:  []any{8080, 443},
+	}))
+
+	assert.Equal(t, buf.String(), `Argument "port" of module github.com/rgst-io/a
+  source: default declared by github.com/rgst-io/a
+  value: 8080
+  examples:
+      - 8080
+      - 443
+`)
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the cache clean command
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/internal/modules/nativeext"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// NewCacheCleanCommand returns a new urfave/cli.Command for the cache
+// clean command.
+func NewCacheCleanCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name:  "clean",
+		Usage: "Removes entries from the stencil cache",
+		Description: "Removes cached native extension binaries. Entries are " +
+			"removed per extension version, optionally only when they are " +
+			"older than the provided duration",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "older-than",
+				Usage: "Only remove entries last modified longer ago than this duration (e.g., 720h)",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Report what would be removed without removing anything",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			dryRun := c.Bool("dry-run")
+			cleaned, err := nativeext.CleanCache(&nativeext.CleanCacheOptions{
+				OlderThan: c.Duration("older-than"),
+				DryRun:    dryRun,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to clean cache: %w", err)
+			}
+
+			verb := "Removed"
+			if dryRun {
+				verb = "Would remove"
+			}
+
+			var total int64
+			for _, e := range cleaned {
+				total += e.Size
+				log.Infof("%s %s (%s)", verb, e.Path, formatBytes(e.Size))
+			}
+
+			if dryRun {
+				log.Infof("Would reclaim %s from %d cache entries", formatBytes(total), len(cleaned))
+				return nil
+			}
+			log.Infof("Reclaimed %s from %d cache entries", formatBytes(total), len(cleaned))
+			return nil
+		},
+	}
+}
+
+// formatBytes returns a human readable representation of the provided
+// number of bytes (e.g., 1.5 MiB).
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
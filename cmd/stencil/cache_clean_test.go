@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+// createCacheEntry creates a fake native extension binary in the cache
+// with the provided modification time and returns the path to its
+// version directory.
+func createCacheEntry(t *testing.T, cacheDir, name, version string, modTime time.Time) string {
+	dir := filepath.Join(cacheDir, "stencil", "nativeexts", name, version)
+	assert.NilError(t, os.MkdirAll(dir, 0o755))
+
+	bin := filepath.Join(dir, "plugin")
+	assert.NilError(t, os.WriteFile(bin, []byte("binary"), 0o755))
+	assert.NilError(t, os.Chtimes(bin, modTime, modTime))
+	assert.NilError(t, os.Chtimes(dir, modTime, modTime))
+	return dir
+}
+
+func TestCacheCleanRemovesOldEntries(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	old := createCacheEntry(t, cacheDir, "github.com--rgst-io--old", "v1.0.0", time.Now().Add(-48*time.Hour))
+	recent := createCacheEntry(t, cacheDir, "github.com--rgst-io--new", "v1.0.0", time.Now())
+
+	cmd := NewCacheCleanCommand(slogext.NewTestLogger(t))
+	assert.NilError(t, testRunCommand(t, cmd, "", "--older-than", "24h"))
+
+	_, err := os.Stat(old)
+	assert.Assert(t, os.IsNotExist(err), "expected old entry to be removed")
+	_, err = os.Stat(filepath.Dir(old))
+	assert.Assert(t, os.IsNotExist(err), "expected empty extension directory to be removed")
+
+	_, err = os.Stat(recent)
+	assert.NilError(t, err, "expected recent entry to be kept")
+}
+
+func TestCacheCleanDryRun(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	old := createCacheEntry(t, cacheDir, "github.com--rgst-io--old", "v1.0.0", time.Now().Add(-48*time.Hour))
+
+	cmd := NewCacheCleanCommand(slogext.NewTestLogger(t))
+	assert.NilError(t, testRunCommand(t, cmd, "", "--dry-run"))
+
+	_, err := os.Stat(old)
+	assert.NilError(t, err, "expected entry to be kept in dry-run mode")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, formatBytes(512), "512 B")
+	assert.Equal(t, formatBytes(1536), "1.5 KiB")
+	assert.Equal(t, formatBytes(5*1024*1024), "5.0 MiB")
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the config command
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"gopkg.in/yaml.v3"
+)
+
+// NewConfigCommand returns a new urfave/cli.Command for the config
+// command set
+func NewConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "examine the project's configuration (stencil.yaml)",
+		Subcommands: []*cli.Command{
+			NewConfigShowCommand(),
+		},
+	}
+}
+
+// NewConfigShowCommand returns a new urfave/cli.Command for the config
+// show command.
+func NewConfigShowCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "show",
+		Usage: "Prints the resolved stencil.yaml",
+		Description: "Prints the manifest that stencil uses, after merging the manifests it extends " +
+			"and rendering the names and versions of modules",
+		Action: func(c *cli.Context) error {
+			manifest, err := configuration.LoadDefaultManifest()
+			if err != nil {
+				return fmt.Errorf("failed to parse stencil.yaml: %w", err)
+			}
+
+			return printResolvedManifest(c.App.Writer, manifest)
+		},
+	}
+}
+
+// printResolvedManifest prints the provided, already resolved, manifest
+// as YAML. Extends is omitted as it has already been applied.
+func printResolvedManifest(w io.Writer, m *configuration.Manifest) error {
+	resolved := *m
+	resolved.Extends = ""
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&resolved); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return enc.Close()
+}
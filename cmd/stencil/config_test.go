@@ -0,0 +1,61 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"gotest.tools/v3/assert"
+)
+
+func TestConfigShowPrintsResolvedManifest(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "base.yaml"), []byte(`arguments:
+  golangVersion: v1.2.3
+  org: rgst-io
+modules:
+  - name: github.com/rgst-io/stencil-base
+`), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "stencil.yaml"), []byte(`name: test
+extends: base.yaml
+arguments:
+  org: acme
+modules:
+  - name: github.com/rgst-io/stencil-golang
+    version: "{{ .Arguments.golangVersion }}"
+`), 0o644))
+
+	var buf bytes.Buffer
+	app := cli.NewApp()
+	app.Writer = &buf
+	app.Commands = []*cli.Command{NewConfigCommand()}
+	assert.NilError(t, testRunApp(t, dir, app, "config", "show"))
+
+	assert.Equal(t, buf.String(), `name: test
+modules:
+  - name: github.com/rgst-io/stencil-golang
+    version: v1.2.3
+  - name: github.com/rgst-io/stencil-base
+arguments:
+  golangVersion: v1.2.3
+  org: acme
+`)
+}
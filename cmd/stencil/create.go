@@ -31,6 +31,7 @@ func NewCreateCommand(log slogext.Logger) *cli.Command {
 		Description: "Commands to create template repositories, or stencil powered repositories",
 		Subcommands: []*cli.Command{
 			NewCreateModuleCommand(log),
+			NewCreateTestCommand(log),
 		},
 	}
 }
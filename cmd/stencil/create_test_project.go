@@ -0,0 +1,120 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the create test command
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// testProjectsDir is the directory, relative to the root of a module,
+// that test projects are created in.
+const testProjectsDir = "tests"
+
+// generateTestProjectYaml generates the stencil.yaml of a test project
+// named name for the provided module. The module is replaced with the
+// module at the root of the repository. Required arguments without a
+// default are set to their first example, if any.
+func generateTestProjectYaml(name string, tr *configuration.TemplateRepositoryManifest) *configuration.Manifest {
+	mf := &configuration.Manifest{
+		Name: name,
+		Modules: []*configuration.TemplateRepository{{
+			Name: tr.Name,
+		}},
+		Replacements: map[string]string{
+			tr.Name: "../..",
+		},
+		Arguments: map[string]any{},
+	}
+
+	for _, argName := range slices.Sorted(maps.Keys(tr.Arguments)) {
+		arg := tr.Arguments[argName]
+		if !arg.Required || arg.Default != nil || len(arg.Examples) == 0 || arg.From != "" {
+			continue
+		}
+
+		// Nested arguments are set as nested maps, e.g., "a.b" is set as
+		// {a: {b: ...}}.
+		args := mf.Arguments
+		keys := strings.Split(argName, ".")
+		for _, k := range keys[:len(keys)-1] {
+			next, ok := args[k].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				args[k] = next
+			}
+			args = next
+		}
+		args[keys[len(keys)-1]] = arg.Examples[0]
+	}
+
+	return mf
+}
+
+// NewCreateTestCommand returns a new urfave/cli.Command for the create
+// test command.
+func NewCreateTestCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name: "test",
+		Description: "Creates a test project, using the module in the current directory, " +
+			"in " + testProjectsDir + "/<name>. Run stencil in it to render the module",
+		ArgsUsage: "create test <name>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return errors.New("must provide a name for the test")
+			}
+
+			name := c.Args().Get(0)
+			if !filepath.IsLocal(name) || strings.ContainsAny(name, `/\`) {
+				return fmt.Errorf("invalid test name %q, must be a single directory name", name)
+			}
+
+			tr, err := configuration.LoadDefaultTemplateRepositoryManifest()
+			if err != nil {
+				return fmt.Errorf("failed to load manifest.yaml, must be ran in the root of a module: %w", err)
+			}
+
+			dir := filepath.Join(testProjectsDir, name)
+			if _, err := os.Stat(dir); err == nil {
+				return fmt.Errorf("test %q already exists at %s", name, dir)
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+
+			if err := encodeToFile(generateTestProjectYaml(name, tr), filepath.Join(dir, "stencil.yaml")); err != nil {
+				return fmt.Errorf("failed to serialize stencil.yaml: %w", err)
+			}
+
+			log.Info("Created test project successfully", "path", dir)
+			log.Infof("- Set the arguments to test with in %s", filepath.Join(dir, "stencil.yaml"))
+			log.Infof("- Run 'stencil' in %s to render the module", dir)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+func TestCreateTestScaffoldsProject(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(`name: github.com/rgst-io/test-module
+arguments:
+  org:
+    required: true
+    schema:
+      type: string
+    examples: [rgst-io]
+  license:
+    schema:
+      type: string
+`), 0o644))
+
+	log := slogext.NewTestLogger(t)
+	assert.NilError(t, testRunCommand(t, NewCreateTestCommand(log), dir, "basic"))
+
+	mf, err := configuration.LoadManifest(filepath.Join("tests", "basic", "stencil.yaml"))
+	assert.NilError(t, err)
+	assert.Equal(t, mf.Name, "basic")
+	assert.Equal(t, len(mf.Modules), 1)
+	assert.Equal(t, mf.Modules[0].Name, "github.com/rgst-io/test-module")
+	assert.DeepEqual(t, mf.Replacements, map[string]string{"github.com/rgst-io/test-module": "../.."})
+	assert.DeepEqual(t, mf.Arguments, map[string]any{"org": "rgst-io"})
+
+	// Existing tests aren't overwritten.
+	app := cli.NewApp()
+	app.Commands = []*cli.Command{NewCreateTestCommand(log)}
+	err = app.Run([]string{"test", "test", "basic"})
+	assert.ErrorContains(t, err, `test "basic" already exists`)
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the functions command
+
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/internal/cmd/stencil"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// NewFunctionsCommand returns a new urfave/cli.Command for the
+// functions command
+func NewFunctionsCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name:  "functions",
+		Usage: "Lists all functions available to templates",
+		Description: "Lists the functions available to the templates of the current project, " +
+			"including stencil's built-in functions, sprig functions and the functions provided " +
+			"by the native extensions of the project's modules. With --exported, only the library " +
+			"templates of the project's modules are rendered and the functions they export for " +
+			"module.Call are listed instead",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "exported",
+				Usage: "Render only library templates and list the functions exported by modules",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			manifest, err := configuration.LoadDefaultManifest()
+			if err != nil {
+				return fmt.Errorf("failed to parse stencil.yaml: %w", err)
+			}
+
+			if c.Bool("exported") {
+				funcs, err := stencil.NewCommand(log, manifest, false, false).ExportedFunctions(c.Context)
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tTEMPLATE")
+				for _, f := range funcs {
+					fmt.Fprintf(w, "%s\t%s\n", f.Name, f.Template)
+				}
+				return w.Flush()
+			}
+
+			funcs, err := stencil.NewCommand(log, manifest, false, false).Functions(c.Context)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tSIGNATURE\tSOURCE\tDOCS")
+			for _, f := range funcs {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Name, f.Signature, f.Source, f.Docs)
+			}
+			return w.Flush()
+		},
+	}
+}
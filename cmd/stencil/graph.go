@@ -0,0 +1,69 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the graph command
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/internal/cmd/stencil"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// NewGraphCommand returns a new urfave/cli.Command for the graph
+// command
+func NewGraphCommand(log slogext.Logger) *cli.Command {
+	return &cli.Command{
+		Name:  "graph",
+		Usage: "Prints the module dependency graph of the current project",
+		Description: "Resolves the modules of the current project and prints the graph of " +
+			"their dependencies, including resolved versions, as Graphviz DOT or Mermaid",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "The format to print the graph in (dot, mermaid)",
+				Value: "dot",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String("format")
+			if format != "dot" && format != "mermaid" {
+				return fmt.Errorf("unsupported format %q, expected dot or mermaid", format)
+			}
+
+			manifest, err := configuration.LoadDefaultManifest()
+			if err != nil {
+				return fmt.Errorf("failed to parse stencil.yaml: %w", err)
+			}
+
+			g, err := stencil.NewCommand(log, manifest, false, false).Graph(c.Context)
+			if err != nil {
+				return err
+			}
+
+			if format == "mermaid" {
+				fmt.Fprint(c.App.Writer, g.Mermaid())
+				return nil
+			}
+			fmt.Fprint(c.App.Writer, g.DOT())
+			return nil
+		},
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for the schema command
+
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+	"go.rgst.io/stencil/v2/schemas"
+)
+
+// NewSchemaCommand returns a new urfave/cli.Command for the schema
+// command set
+func NewSchemaCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "schema",
+		Usage: "prints the JSON schema of stencil's configuration files, e.g., for editor autocomplete",
+		Subcommands: []*cli.Command{
+			NewSchemaProjectCommand(),
+			NewSchemaModuleCommand(),
+		},
+	}
+}
+
+// NewSchemaProjectCommand returns a new urfave/cli.Command for the
+// schema project command.
+func NewSchemaProjectCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "project",
+		Usage: "Prints the JSON schema of a project's manifest (stencil.yaml)",
+		Action: func(c *cli.Context) error {
+			_, err := c.App.Writer.Write(schemas.Project)
+			return err
+		},
+	}
+}
+
+// NewSchemaModuleCommand returns a new urfave/cli.Command for the
+// schema module command.
+func NewSchemaModuleCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "module",
+		Usage: "Prints the JSON schema of a template repository's manifest (manifest.yaml)",
+		Action: func(c *cli.Context) error {
+			_, err := c.App.Writer.Write(schemas.Module)
+			return err
+		},
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestSchemaRequiresName(t *testing.T) {
+	tests := []struct {
+		command string
+		def     string
+	}{
+		{command: "project", def: "Manifest"},
+		{command: "module", def: "TemplateRepositoryManifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			var buf bytes.Buffer
+			app := cli.NewApp()
+			app.Writer = &buf
+			app.Commands = []*cli.Command{NewSchemaCommand()}
+			assert.NilError(t, testRunApp(t, t.TempDir(), app, "schema", tt.command))
+
+			var schema struct {
+				Ref  string `json:"$ref"`
+				Defs map[string]struct {
+					Required    []string `json:"required"`
+					Description string   `json:"description"`
+				} `json:"$defs"`
+			}
+			assert.NilError(t, json.Unmarshal(buf.Bytes(), &schema))
+			assert.Equal(t, schema.Ref, "#/$defs/"+tt.def)
+			assert.Assert(t, cmp.Contains(schema.Defs[tt.def].Required, "name"))
+			assert.Assert(t, schema.Defs[tt.def].Description != "", "expected the schema to contain descriptions")
+		})
+	}
+}
@@ -18,9 +18,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
 
 	"go.rgst.io/stencil/v2/internal/cmd/stencil"
 	"go.rgst.io/stencil/v2/internal/version"
@@ -60,13 +69,358 @@ func NewStencilAction(log slogext.Logger) cli.ActionFunc {
 			log.Debug("Debug logging enabled")
 		}
 
+		opts := &runOptions{
+			dryRun:      c.Bool("dry-run"),
+			adopt:       c.Bool("adopt"),
+			tags:        c.StringSlice("tag"),
+			excludeTags: c.StringSlice("exclude-tag"),
+			asOf:        c.String("as-of"),
+
+			validateSchemas: c.Bool("validate-schemas"),
+			targetOS:        c.String("target-os"),
+			targetArch:      c.String("target-arch"),
+			dumpValues:      c.String("dump-values"),
+			keepGoing:       c.Bool("keep-going"),
+			explainSkip:     c.Bool("explain-skip"),
+			explainBlocks:   c.String("explain-blocks"),
+			report:          c.String("report"),
+			requireClean:    c.Bool("require-clean"),
+			migrate:         c.Bool("migrate"),
+			modules:         c.StringSlice("module"),
+		}
+
+		// The action is also ran by commands without an --arg flag (e.g.,
+		// create module), those have no arguments to set.
+		var argFlags []string
+		if rf, ok := c.Generic("arg").(*repeatedFlag); ok {
+			argFlags = *rf
+		}
+
+		var err error
+		if opts.args, err = parseArgFlags(argFlags); err != nil {
+			return err
+		}
+
+		// Projects are ran in their own directory with --recursive, so
+		// the lockfile path must not be relative to them.
+		if pth := c.String("lockfile"); pth != "" {
+			abs, err := filepath.Abs(pth)
+			if err != nil {
+				return fmt.Errorf("failed to resolve --lockfile: %w", err)
+			}
+			opts.lockfile = abs
+		}
+
+		if c.Bool("recursive") {
+			if len(opts.modules) != 0 {
+				return fmt.Errorf("--module can't be used with --recursive")
+			}
+			return runRecursive(c.Context, log, opts)
+		}
+
+		return runProject(c.Context, log, opts)
+	}
+}
+
+// runOptions are the options for running stencil on a project, as
+// provided by the flags of the plain stencil command.
+type runOptions struct {
+	// dryRun denotes if files should not be written to disk
+	dryRun bool
+
+	// adopt denotes if adoption heuristics should be used
+	adopt bool
+
+	// tags and excludeTags filter which templates are rendered
+	tags        []string
+	excludeTags []string
+
+	// asOf is a git ref to read the manifest and lockfile from instead
+	// of the working tree, if set
+	asOf string
+
+	// validateSchemas denotes if all argument schemas should be
+	// validated before rendering
+	validateSchemas bool
+
+	// targetOS and targetArch override the platform templates generate
+	// for, if set
+	targetOS   string
+	targetArch string
+
+	// dumpValues is the path to write the values used to render the
+	// templates to, if set
+	dumpValues string
+
+	// keepGoing denotes if rendering should continue when a template
+	// fails
+	keepGoing bool
+
+	// explainSkip denotes if a summary of the skipped files should be
+	// logged
+	explainSkip bool
+
+	// explainBlocks is the path of a file whose blocks should be logged
+	// alongside the template that rendered them, if set
+	explainBlocks string
+
+	// report is the path to write a JSON report of the files written to,
+	// if set
+	report string
+
+	// requireClean denotes if the run should fail when generated files
+	// aren't committed to git
+	requireClean bool
+
+	// migrate denotes if the values of deprecated arguments should be
+	// moved to the arguments replacing them in stencil.yaml
+	migrate bool
+
+	// lockfile is the path to a lockfile, e.g., of a different project,
+	// to use the module versions of, if set
+	lockfile string
+
+	// args are arguments that override the ones set in stencil.yaml
+	args map[string]any
+
+	// modules are the modules to render instead of the ones in
+	// stencil.yaml, see [manifestForModules]
+	modules []string
+}
+
+// repeatedFlag is a [cli.Generic] flag that can be provided multiple
+// times. Unlike [cli.StringSliceFlag], values are not split on commas.
+type repeatedFlag []string
+
+// Set implements [cli.Generic].
+func (r *repeatedFlag) Set(v string) error {
+	*r = append(*r, v)
+	return nil
+}
+
+// String implements [cli.Generic].
+func (r *repeatedFlag) String() string {
+	return strings.Join(*r, ", ")
+}
+
+// parseArgFlags parses the provided --arg flags (key=value) into a map
+// of argument names to their values. Values that are valid JSON are
+// decoded (e.g., 3, true or ["a"]), all other values are used as
+// strings.
+func parseArgFlags(flags []string) (map[string]any, error) {
+	args := make(map[string]any)
+	for _, flag := range flags {
+		key, raw, ok := strings.Cut(flag, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --arg %q, expected key=value", flag)
+		}
+
+		var v any = raw
+		if json.Valid([]byte(raw)) {
+			// JSON is valid YAML, decoding it as such keeps the types
+			// consistent with the arguments read from stencil.yaml (e.g.,
+			// integers aren't turned into floats).
+			if err := yaml.Unmarshal([]byte(raw), &v); err != nil {
+				return nil, fmt.Errorf("failed to parse value of --arg %q: %w", key, err)
+			}
+		}
+		args[key] = v
+	}
+	return args, nil
+}
+
+// manifestForModules returns an in-memory manifest, named after the
+// current working directory, that uses the provided modules. Modules
+// are either an import path, or an import path and the path to a local
+// copy of the module to use (<import-path>=<path>).
+func manifestForModules(mods []string) (*configuration.Manifest, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	manifest := &configuration.Manifest{
+		Name:         filepath.Base(cwd),
+		Arguments:    make(map[string]any),
+		Replacements: make(map[string]string),
+	}
+	for _, mod := range mods {
+		name, local, ok := strings.Cut(mod, "=")
+		if name == "" || (ok && local == "") {
+			return nil, fmt.Errorf("invalid --module %q, expected <import-path> or <import-path>=<path>", mod)
+		}
+		manifest.Modules = append(manifest.Modules, &configuration.TemplateRepository{Name: name})
+
+		if ok {
+			abs, err := filepath.Abs(local)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve path of --module %q: %w", mod, err)
+			}
+			manifest.Replacements[name] = abs
+		}
+	}
+	return manifest, nil
+}
+
+// runProject runs stencil on the project in the current working
+// directory.
+func runProject(ctx context.Context, log slogext.Logger, opts *runOptions) error {
+	var cmd *stencil.Command
+	switch {
+	case opts.asOf != "":
+		// The working tree doesn't match the manifest and lockfile from
+		// the ref, so writing to it would clobber the project.
+		if !opts.dryRun {
+			return fmt.Errorf("--as-of can only be used with --dry-run")
+		}
+		if opts.migrate {
+			return fmt.Errorf("--migrate can't be used with --as-of")
+		}
+		if len(opts.modules) != 0 {
+			return fmt.Errorf("--module can't be used with --as-of")
+		}
+
+		manifest, lock, err := stencil.LoadAsOf(opts.asOf)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Using stencil.yaml and stencil.lock as of %s", opts.asOf)
+		cmd = stencil.NewCommand(log, setArgs(manifest, opts.args), opts.dryRun, opts.adopt).SetLockfile(lock)
+	case len(opts.modules) != 0:
+		if opts.migrate {
+			return fmt.Errorf("--migrate can't be used with --module")
+		}
+
+		manifest, err := manifestForModules(opts.modules)
+		if err != nil {
+			return err
+		}
+		cmd = stencil.NewCommand(log, setArgs(manifest, opts.args), opts.dryRun, opts.adopt)
+	default:
 		manifest, err := configuration.LoadDefaultManifest()
 		if err != nil {
 			return fmt.Errorf("failed to parse stencil.yaml: %w", err)
 		}
+		cmd = stencil.NewCommand(log, setArgs(manifest, opts.args), opts.dryRun, opts.adopt)
+	}
+
+	if opts.lockfile != "" {
+		lock, err := stencil.LoadLockfileAt(opts.lockfile)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Using module versions from %s", opts.lockfile)
+		cmd.SetModulesLockfile(lock)
+	}
+
+	return cmd.SetTagFilter(opts.tags, opts.excludeTags).
+		SetValidateSchemas(opts.validateSchemas).
+		SetTarget(opts.targetOS, opts.targetArch).
+		SetDumpValues(opts.dumpValues).
+		SetKeepGoing(opts.keepGoing).
+		SetExplainSkip(opts.explainSkip).
+		SetExplainBlocks(opts.explainBlocks).
+		SetReport(opts.report).
+		SetRequireClean(opts.requireClean).
+		SetMigrate(opts.migrate).
+		Run(ctx)
+}
 
-		return stencil.NewCommand(log, manifest, c.Bool("dry-run"), c.Bool("adopt")).Run(c.Context)
+// setArgs sets the provided arguments on the provided manifest,
+// overriding the values set in it, and returns it.
+func setArgs(manifest *configuration.Manifest, args map[string]any) *configuration.Manifest {
+	if len(args) == 0 {
+		return manifest
 	}
+
+	if manifest.Arguments == nil {
+		manifest.Arguments = make(map[string]any)
+	}
+	maps.Copy(manifest.Arguments, args)
+	return manifest
+}
+
+// runRecursive discovers all projects (directories containing a
+// stencil.yaml) under the current working directory and runs stencil
+// on each of them, in their own directory. All projects are ran, even
+// if one of them fails. An error is returned if any project failed.
+func runRecursive(ctx context.Context, log slogext.Logger, opts *runOptions) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	// Always change back into the original directory, regardless of
+	// whether or not a project failed.
+	defer func() {
+		if err := os.Chdir(cwd); err != nil {
+			log.WithError(err).Errorf("failed to change back into %s", cwd)
+		}
+	}()
+
+	projects, err := findProjects(cwd)
+	if err != nil {
+		return fmt.Errorf("failed to discover projects: %w", err)
+	}
+	if len(projects) == 0 {
+		return fmt.Errorf("no projects found (no stencil.yaml under %s)", cwd)
+	}
+
+	var errs []error
+	for _, dir := range projects {
+		plog := log.With("project", dir)
+		plog.Infof("Running stencil in %s", dir)
+
+		if err := os.Chdir(filepath.Join(cwd, dir)); err != nil {
+			return fmt.Errorf("failed to change into project %s: %w", dir, err)
+		}
+
+		if err := runProject(ctx, plog, opts); err != nil {
+			plog.WithError(err).Error("failed to run stencil")
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%d/%d project(s) failed: %w", len(errs), len(projects), errors.Join(errs...))
+	}
+
+	log.Infof("Successfully ran stencil on %d project(s)", len(projects))
+	return nil
+}
+
+// findProjects returns the directories, relative to root, that contain
+// a stencil.yaml. Hidden directories (e.g., .git), node_modules and
+// vendor directories are not searched.
+func findProjects(root string) ([]string, error) {
+	var projects []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.Name() != "stencil.yaml" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		projects = append(projects, rel)
+		return nil
+	})
+	return projects, err
 }
 
 // NewStencil returns a new CLI application for stencil.
@@ -92,12 +446,91 @@ func NewStencil(log slogext.Logger) *cli.App {
 				Name:  "adopt",
 				Usage: "Uses heuristics to detect code that should go into blocks to assist with first-time adoption of templates",
 			},
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "Runs stencil on every project (directory containing a stencil.yaml) under the current directory",
+			},
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "Only render templates with this tag (from templateTags in a module's manifest), can be provided multiple times",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude-tag",
+				Usage: "Don't render templates with this tag, can be provided multiple times",
+			},
+			&cli.StringFlag{
+				Name:  "as-of",
+				Usage: "Render using the stencil.yaml and stencil.lock from this git ref (e.g., a commit) instead of the working tree to reproduce a past generation, requires --dry-run",
+			},
+			&cli.StringFlag{
+				Name:  "lockfile",
+				Usage: "Use the module versions from the lockfile at this path (e.g., a lockfile shared across projects) instead of the project's stencil.lock",
+			},
+			&cli.BoolFlag{
+				Name:  "validate-schemas",
+				Usage: "Validate that the JSON schema of every argument declared by the project's modules compiles before rendering, instead of when the argument is read",
+			},
+			&cli.StringFlag{
+				Name:  "target-os",
+				Usage: "The operating system (in GOOS format, e.g., linux) returned by stencil.OS, defaults to the current one",
+			},
+			&cli.StringFlag{
+				Name:  "target-arch",
+				Usage: "The architecture (in GOARCH format, e.g., arm64) returned by stencil.Arch, defaults to the current one",
+			},
+			&cli.StringFlag{
+				Name:  "dump-values",
+				Usage: "Write the values used to render the templates, including the resolved arguments of every module, as JSON to this path. Arguments marked as secret are redacted",
+			},
+			&cli.BoolFlag{
+				Name:  "keep-going",
+				Usage: "Keep rendering when a template fails, writing the files of the templates that succeeded, and report all failures at the end",
+			},
+			&cli.BoolFlag{
+				Name:  "explain-skip",
+				Usage: "Log a summary of all skipped files, grouped by the reason they were skipped",
+			},
+			&cli.StringFlag{
+				Name:  "explain-blocks",
+				Usage: "Log every block of the file at this path alongside the template that rendered it",
+			},
+			&cli.StringFlag{
+				Name:  "report",
+				Usage: "Write a JSON report of every file written, and the action taken on it, to the provided path",
+			},
+			&cli.BoolFlag{
+				Name:  "require-clean",
+				Usage: "Fail if any generated file, or the lockfile, has changes that are not committed to git after rendering",
+			},
+			&cli.BoolFlag{
+				Name:  "migrate",
+				Usage: "Move the values of deprecated arguments in stencil.yaml to the arguments replacing them",
+			},
+			&cli.GenericFlag{
+				Name:  "arg",
+				Value: &repeatedFlag{},
+				Usage: "Set an argument, overriding its value in stencil.yaml (key=value, values that are valid JSON " +
+					"are decoded). Can be provided multiple times",
+			},
+			&cli.StringSliceFlag{
+				Name: "module",
+				Usage: "Render the provided module (<import-path> or <import-path>=<path> to use a local copy) " +
+					"without reading stencil.yaml. Can be provided multiple times",
+			},
 		},
 		Commands: []*cli.Command{
 			NewDescribeCommand(),
 			NewCreateCommand(log),
 			NewUpgradeCommand(log),
 			NewLockfileCommand(log),
+			NewCacheCommand(log),
+			NewArgCommand(log),
+			NewGraphCommand(log),
+			NewApplyCommand(log),
+			NewFunctionsCommand(log),
+			NewConfigCommand(),
+			NewSchemaCommand(),
 		},
 	}
 }
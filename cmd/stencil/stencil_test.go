@@ -1,8 +1,13 @@
 package main
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/urfave/cli/v2"
+
 	"go.rgst.io/stencil/v2/pkg/slogext"
 	"gotest.tools/v3/assert"
 )
@@ -14,3 +19,133 @@ func TestFailsWhenUnknownArgumentsArePassed(t *testing.T) {
 	err := testRunApp(t, "", app, "im-not-a-command")
 	assert.ErrorContains(t, err, "unexpected arguments: [im-not-a-command]")
 }
+
+// writeFile writes contents to path, creating any parent directories.
+func writeFile(t *testing.T, path, contents string) {
+	assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NilError(t, os.WriteFile(path, []byte(contents), 0o644))
+}
+
+func TestRecursiveRendersAllProjects(t *testing.T) {
+	dir := t.TempDir()
+
+	modDir := filepath.Join(dir, ".module")
+	writeFile(t, filepath.Join(modDir, "manifest.yaml"), "name: testing\n")
+	writeFile(t, filepath.Join(modDir, "templates", "hello.tpl"), "hello from {{ .Config.Name }}\n")
+
+	for _, project := range []string{"a", filepath.Join("b", "nested")} {
+		writeFile(t, filepath.Join(dir, project, "stencil.yaml"), "name: "+filepath.Base(project)+"\n"+
+			"modules:\n  - name: testing\n"+
+			"replacements:\n  testing: "+modDir+"\n")
+	}
+
+	app := NewStencil(slogext.NewTestLogger(t))
+	assert.NilError(t, testRunApp(t, dir, app, "--recursive"))
+
+	for project, name := range map[string]string{"a": "a", filepath.Join("b", "nested"): "nested"} {
+		b, err := os.ReadFile(filepath.Join(dir, project, "hello"))
+		assert.NilError(t, err, "expected project %s to be rendered", project)
+		assert.Equal(t, string(b), "hello from "+name+"\n")
+
+		_, err = os.Stat(filepath.Join(dir, project, "stencil.lock"))
+		assert.NilError(t, err, "expected project %s to have its own lockfile", project)
+	}
+}
+
+func TestRecursiveFailsIfAnyProjectFails(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a", "stencil.yaml"), "name: a\n")
+	writeFile(t, filepath.Join(dir, "b", "stencil.yaml"), "name: [\n")
+
+	app := NewStencil(slogext.NewTestLogger(t))
+	err := testRunApp(t, dir, app, "--recursive")
+	assert.ErrorContains(t, err, "1/2 project(s) failed")
+
+	// Ensure we changed back into the original directory.
+	cwd, err := os.Getwd()
+	assert.NilError(t, err)
+	want, err := filepath.EvalSymlinks(dir)
+	assert.NilError(t, err)
+	got, err := filepath.EvalSymlinks(cwd)
+	assert.NilError(t, err)
+	assert.Equal(t, got, want)
+}
+
+func TestModuleAndArgFlagsRenderWithoutManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	modDir := filepath.Join(t.TempDir(), "module")
+	writeFile(t, filepath.Join(modDir, "manifest.yaml"), "name: testing\n"+
+		"arguments:\n"+
+		"  greeting:\n    schema:\n      type: string\n"+
+		"  replicas:\n    schema:\n      type: integer\n"+
+		"  tags:\n    schema:\n      type: array\n")
+	writeFile(t, filepath.Join(modDir, "templates", "out.tpl"),
+		`{{ stencil.Arg "greeting" }} {{ stencil.Arg "replicas" | add 1 }} {{ stencil.Arg "tags" | join "," }}`+"\n")
+
+	app := NewStencil(slogext.NewTestLogger(t))
+	assert.NilError(t, testRunApp(t, dir, app, "--module", "testing="+modDir,
+		"--arg", "greeting=hello, world", "--arg", "replicas=2", "--arg", `tags=["a","b"]`))
+
+	b, err := os.ReadFile(filepath.Join(dir, "out"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "hello, world 3 a,b\n")
+
+	_, err = os.Stat(filepath.Join(dir, "stencil.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "expected no stencil.yaml to be written")
+}
+
+func TestArgFlagOverridesManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	modDir := filepath.Join(t.TempDir(), "module")
+	writeFile(t, filepath.Join(modDir, "manifest.yaml"), "name: testing\n"+
+		"arguments:\n  greeting:\n    schema:\n      type: string\n")
+	writeFile(t, filepath.Join(modDir, "templates", "out.tpl"), `{{ stencil.Arg "greeting" }}`+"\n")
+	writeFile(t, filepath.Join(dir, "stencil.yaml"), "name: test\n"+
+		"arguments:\n  greeting: from stencil.yaml\n"+
+		"modules:\n  - name: testing\n"+
+		"replacements:\n  testing: "+modDir+"\n")
+
+	app := NewStencil(slogext.NewTestLogger(t))
+	assert.NilError(t, testRunApp(t, dir, app, "--arg", "greeting=from the CLI"))
+
+	b, err := os.ReadFile(filepath.Join(dir, "out"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "from the CLI\n")
+}
+
+func TestStencilActionWithoutArgFlag(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "stencil.yaml"), "name: test\n")
+	prepareTestRun(t, dir)
+
+	// Commands like create module run the action with their own flags,
+	// which don't include --arg.
+	c := cli.NewContext(cli.NewApp(), flag.NewFlagSet("", flag.ContinueOnError), nil)
+	assert.NilError(t, NewStencilAction(slogext.NewTestLogger(t))(c))
+}
+
+func TestAsOfRequiresDryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "stencil.yaml"), "name: test\n")
+
+	app := NewStencil(slogext.NewTestLogger(t))
+	err := testRunApp(t, dir, app, "--as-of", "HEAD")
+	assert.ErrorContains(t, err, "--as-of can only be used with --dry-run")
+}
+
+func TestParseArgFlags(t *testing.T) {
+	args, err := parseArgFlags([]string{"a=1", "b=true", "c=text", `d={"e":"f"}`, "g="})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, map[string]any{
+		"a": 1,
+		"b": true,
+		"c": "text",
+		"d": map[string]any{"e": "f"},
+		"g": "",
+	})
+
+	_, err = parseArgFlags([]string{"missing-value"})
+	assert.ErrorContains(t, err, `invalid --arg "missing-value", expected key=value`)
+}
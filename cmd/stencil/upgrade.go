@@ -16,6 +16,7 @@ package main
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/urfave/cli/v2"
 	"go.rgst.io/stencil/v2/internal/cmd/stencil"
@@ -31,6 +32,21 @@ func NewUpgradeCommand(log slogext.Logger) *cli.Command {
 		Usage:       "upgrade stencil modules",
 		Description: "Runs stencil with newer modules and updates stencil.lock to use them",
 		UsageText:   "upgrade",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Only report the new versions and show a diff of the changes upgrading would make, without writing any files or the lockfile",
+			},
+			&cli.BoolFlag{
+				Name:  "commit",
+				Usage: "Commit the changes to the branch set by --commit-branch, creating it if needed, with a message summarizing the module upgrades",
+			},
+			&cli.StringFlag{
+				Name:  "commit-branch",
+				Usage: "The git branch to commit the changes to when using --commit",
+				Value: "stencil-upgrade",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			log.Infof("stencil %s", c.App.Version)
 
@@ -44,7 +60,19 @@ func NewUpgradeCommand(log slogext.Logger) *cli.Command {
 				return fmt.Errorf("failed to parse stencil.yaml: %w", err)
 			}
 
-			return stencil.NewCommand(log, manifest, c.Bool("dry-run"), c.Bool("adopt")).Upgrade(c.Context)
+			// --dry-run can be passed to either stencil or upgrade.
+			dryRun := slices.ContainsFunc(c.Lineage(), func(lc *cli.Context) bool {
+				return lc.Bool("dry-run")
+			})
+
+			cmd := stencil.NewCommand(log, manifest, dryRun, c.Bool("adopt"))
+			if dryRun {
+				cmd.SetDiffOutput(c.App.Writer)
+			}
+			if c.Bool("commit") {
+				cmd.SetCommitBranch(c.String("commit-branch"))
+			}
+			return cmd.Upgrade(c.Context)
 		},
 	}
 }
@@ -0,0 +1,54 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ci contains helpers for detecting if stencil is being ran
+// inside of a CI environment.
+package ci
+
+import "os"
+
+// EnvVars are the environment variables that, when set, denote that
+// we're running inside of a CI environment. This is the single source
+// of truth for CI detection in stencil.
+var EnvVars = []string{
+	"CI",
+	"BUILD_NUMBER",
+	"BUILDKITE",
+	"CIRCLECI",
+	"CODEBUILD_BUILD_ID",
+	"DRONE",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+	"TRAVIS",
+}
+
+// IsCI returns true if any of the environment variables in [EnvVars]
+// are set. A value of "false" or "0" is treated as not being set to
+// allow explicitly opting out (e.g., CI=false).
+func IsCI() bool {
+	for _, k := range EnvVars {
+		switch os.Getenv(k) {
+		case "", "false", "0":
+			continue
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,39 @@
+package ci
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// clearEnv unsets all of the CI environment variables for the duration
+// of the test.
+func clearEnv(t *testing.T) {
+	for _, k := range EnvVars {
+		t.Setenv(k, "")
+	}
+}
+
+func TestIsCI(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{name: "should not detect CI without env vars", want: false},
+		{name: "should detect CI", env: map[string]string{"CI": "true"}, want: true},
+		{name: "should detect GitHub Actions", env: map[string]string{"GITHUB_ACTIONS": "true"}, want: true},
+		{name: "should detect Jenkins", env: map[string]string{"JENKINS_URL": "https://jenkins.example.com"}, want: true},
+		{name: "should allow opting out", env: map[string]string{"CI": "false"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			assert.Equal(t, IsCI(), tt.want)
+		})
+	}
+}
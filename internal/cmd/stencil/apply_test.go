@@ -0,0 +1,50 @@
+package stencil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestApplyOnlyWritesFilesOfModule(t *testing.T) {
+	testdata, err := filepath.Abs(filepath.Join("testdata", "apply"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	manifest := &configuration.Manifest{
+		Name: "testing",
+		Modules: []*configuration.TemplateRepository{
+			{Name: "a"}, {Name: "c"},
+		},
+		Replacements: map[string]string{
+			"a": filepath.Join(testdata, "a"),
+			"b": filepath.Join(testdata, "b"),
+			"c": filepath.Join(testdata, "c"),
+		},
+	}
+
+	log := slogext.NewTestLogger(t)
+	err = NewCommand(log, manifest, false, false).Apply(context.Background(), "a")
+	assert.NilError(t, err)
+
+	// b and c are rendered, as they add to the module hooks of a, but
+	// only the files of a are written.
+	b, err := os.ReadFile("a.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "from-b,from-c\n")
+
+	for _, name := range []string{"b.txt", "c.txt"} {
+		_, err := os.Stat(name)
+		assert.Assert(t, os.IsNotExist(err), "expected %s to not be written", name)
+	}
+
+	err = NewCommand(log, manifest, false, false).Apply(context.Background(), "d")
+	assert.ErrorContains(t, err, `module "d" is not used by this project`)
+}
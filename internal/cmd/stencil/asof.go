@@ -0,0 +1,138 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for reading the manifest and
+// lockfile of a project as they were at a git ref.
+
+package stencil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/stencil"
+)
+
+// LoadAsOf reads the manifest and lockfile of the project in the
+// current working directory as they were at the provided git ref
+// (e.g., a commit, tag or "HEAD~2"). The files are read from the git
+// repository, the working tree is not changed. A nil lockfile is
+// returned if the project had no lockfile at that ref.
+func LoadAsOf(ref string) (*configuration.Manifest, *stencil.Lockfile, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	r, err := gogit.PlainOpenWithOptions(cwd, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve %q: %w", ref, err)
+	}
+
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	// Files in the commit are relative to the root of the repository,
+	// which isn't necessarily the project.
+	wrk, err := r.Worktree()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get git worktree: %w", err)
+	}
+	dir, err := relToRoot(wrk.Filesystem.Root(), cwd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var manifest *configuration.Manifest
+	manifestFiles := []string{"stencil.yaml", "service.yaml"}
+	for _, name := range manifestFiles {
+		f, err := commit.File(path.Join(dir, name))
+		if errors.Is(err, object.ErrFileNotFound) {
+			continue
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s at %s: %w", name, ref, err)
+		}
+
+		rc, err := f.Reader()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s at %s: %w", name, ref, err)
+		}
+		manifest, err = configuration.ReadManifest(rc, name)
+		rc.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s at %s: %w", name, ref, err)
+		}
+		if err := manifest.RenderModules(); err != nil {
+			return nil, nil, fmt.Errorf("failed to render modules of %s at %s: %w", name, ref, err)
+		}
+		break
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("no manifest found at %s (searched %v)", ref, manifestFiles)
+	}
+
+	f, err := commit.File(path.Join(dir, stencil.LockfileName))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return manifest, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s at %s: %w", stencil.LockfileName, ref, err)
+	}
+
+	rc, err := f.Reader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s at %s: %w", stencil.LockfileName, ref, err)
+	}
+	defer rc.Close()
+
+	lock, err := stencil.ReadLockfile(rc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s at %s: %w", stencil.LockfileName, ref, err)
+	}
+	return manifest, lock, nil
+}
+
+// relToRoot returns dir relative to root as a slash separated path,
+// resolving symlinks in both first.
+func relToRoot(root, dir string) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine path of %s in git repository: %w", dir, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
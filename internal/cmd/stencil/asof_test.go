@@ -0,0 +1,72 @@
+package stencil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+// commitManifest writes a stencil.yaml with the provided greeting
+// argument, using the asof-module test module, and commits it.
+func commitManifest(t *testing.T, wrk *gogit.Worktree, modulePath, greeting string) {
+	t.Helper()
+
+	manifest := "name: testing\n" +
+		"modules:\n  - name: testing\n" +
+		"replacements:\n  testing: " + modulePath + "\n" +
+		"arguments:\n  greeting: " + greeting + "\n"
+	assert.NilError(t, os.WriteFile("stencil.yaml", []byte(manifest), 0o644))
+
+	_, err := wrk.Add("stencil.yaml")
+	assert.NilError(t, err)
+	_, err = wrk.Commit("set greeting to "+greeting, &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Stencil", Email: "email@example.com", When: time.Now()},
+	})
+	assert.NilError(t, err)
+}
+
+func TestRunAsOf(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "asof-module"))
+	assert.NilError(t, err)
+
+	tmpDir := t.TempDir()
+	env.ChangeWorkingDir(t, tmpDir)
+
+	r, err := gogit.PlainInit(tmpDir, false)
+	assert.NilError(t, err)
+	wrk, err := r.Worktree()
+	assert.NilError(t, err)
+
+	commitManifest(t, wrk, modulePath, "hello")
+	commitManifest(t, wrk, modulePath, "goodbye")
+
+	manifest, lock, err := LoadAsOf("HEAD~1")
+	assert.NilError(t, err)
+	assert.Assert(t, lock == nil, "expected no lockfile at HEAD~1")
+	assert.Equal(t, manifest.Arguments["greeting"], "hello")
+
+	log := slogext.NewTestLogger(t)
+	err = NewCommand(log, manifest, false, false).SetLockfile(lock).Run(context.Background())
+	assert.NilError(t, err)
+
+	b, err := os.ReadFile("hello.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "hello\n")
+
+	// The manifest in the working tree is left untouched.
+	b, err = os.ReadFile("stencil.yaml")
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(b), "greeting: goodbye"))
+
+	_, _, err = LoadAsOf("not-a-ref")
+	assert.ErrorContains(t, err, `failed to resolve "not-a-ref"`)
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for ensuring that the files
+// generated by stencil are committed to git.
+
+package stencil
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"go.rgst.io/stencil/v2/pkg/stencil"
+)
+
+// uncommittedFiles returns the files generated by the provided
+// templates, and the lockfile, that have changes that aren't committed
+// to git (modified, untracked, deleted, etc.) alongside a description
+// of their status, sorted by path.
+func (g *gitProject) uncommittedFiles(tpls []*codegen.Template) ([]string, error) {
+	status, err := g.wrk.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git status: %w", err)
+	}
+
+	names := []string{stencil.LockfileName}
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Skipped {
+				continue
+			}
+			names = append(names, filepath.ToSlash(f.Name()))
+		}
+	}
+	slices.Sort(names)
+
+	var dirty []string
+	for _, name := range slices.Compact(names) {
+		fs, ok := status[path.Join(g.dir, name)]
+		if !ok || (fs.Worktree == gogit.Unmodified && fs.Staging == gogit.Unmodified) {
+			continue
+		}
+
+		code := fs.Worktree
+		if code == gogit.Unmodified {
+			code = fs.Staging
+		}
+		dirty = append(dirty, fmt.Sprintf("%s (%s)", name, describeStatusCode(code)))
+	}
+	return dirty, nil
+}
+
+// describeStatusCode returns a human readable description of the
+// provided git status code.
+func describeStatusCode(code gogit.StatusCode) string {
+	switch code {
+	case gogit.Untracked:
+		return "untracked"
+	case gogit.Modified:
+		return "modified"
+	case gogit.Added:
+		return "added"
+	case gogit.Deleted:
+		return "deleted"
+	case gogit.Renamed:
+		return "renamed"
+	case gogit.Copied:
+		return "copied"
+	default:
+		return strings.TrimSpace(string(code))
+	}
+}
+
+// checkClean returns an error listing the files generated by the
+// provided templates that have changes that aren't committed to git.
+func (c *Command) checkClean(tpls []*codegen.Template) error {
+	gp, err := openGitProject()
+	if err != nil {
+		return err
+	}
+
+	dirty, err := gp.uncommittedFiles(tpls)
+	if err != nil {
+		return err
+	}
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d generated file(s) have changes that are not committed to git:\n  - %s",
+		len(dirty), strings.Join(dirty, "\n  - "))
+}
@@ -0,0 +1,71 @@
+package stencil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRunRequireClean(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "commit"))
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	env.ChangeWorkingDir(t, dir)
+
+	r, err := gogit.PlainInit(dir, false)
+	assert.NilError(t, err)
+	wrk, err := r.Worktree()
+	assert.NilError(t, err)
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+	}
+	log := slogext.NewTestLogger(t)
+
+	// Nothing is committed yet, so the generated files are untracked.
+	err = NewCommand(log, manifest, false, false).SetRequireClean(true).Run(context.Background())
+	assert.Error(t, err, "2 generated file(s) have changes that are not committed to git:\n"+
+		"  - hello.txt (untracked)\n"+
+		"  - stencil.lock (untracked)")
+
+	_, err = wrk.Add(".")
+	assert.NilError(t, err)
+	_, err = wrk.Commit("add generated files", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NilError(t, err)
+
+	err = NewCommand(log, manifest, false, false).SetRequireClean(true).Run(context.Background())
+	assert.NilError(t, err)
+
+	// Files that aren't generated by stencil are ignored.
+	assert.NilError(t, os.WriteFile("notes.txt", []byte("mine\n"), 0o644))
+	err = NewCommand(log, manifest, false, false).SetRequireClean(true).Run(context.Background())
+	assert.NilError(t, err)
+
+	// A generated file that was changed and committed is regenerated,
+	// leaving it modified.
+	assert.NilError(t, os.WriteFile("hello.txt", []byte("changed\n"), 0o644))
+	_, err = wrk.Add("hello.txt")
+	assert.NilError(t, err)
+	_, err = wrk.Commit("change generated file", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NilError(t, err)
+
+	err = NewCommand(log, manifest, false, false).SetRequireClean(true).Run(context.Background())
+	assert.Error(t, err, "1 generated file(s) have changes that are not committed to git:\n"+
+		"  - hello.txt (modified)")
+}
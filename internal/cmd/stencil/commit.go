@@ -0,0 +1,190 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for committing the changes made
+// by stencil to a git branch, e.g., for automated pull requests.
+
+package stencil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/pkg/stencil"
+)
+
+// defaultCommitAuthor is the author of commits created by stencil when
+// no user is configured in git.
+var defaultCommitAuthor = object.Signature{Name: "stencil", Email: "stencil@rgst.io"}
+
+// gitProject is the git repository containing the project in the
+// current working directory.
+type gitProject struct {
+	repo *gogit.Repository
+	wrk  *gogit.Worktree
+
+	// dir is the path of the project relative to the root of the
+	// repository, as a slash separated path.
+	dir string
+}
+
+// openGitProject opens the git repository containing the project in
+// the current working directory.
+func openGitProject() (*gitProject, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	r, err := gogit.PlainOpenWithOptions(cwd, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	wrk, err := r.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git worktree: %w", err)
+	}
+
+	dir, err := relToRoot(wrk.Filesystem.Root(), cwd)
+	if err != nil {
+		return nil, err
+	}
+	return &gitProject{repo: r, wrk: wrk, dir: dir}, nil
+}
+
+// checkoutBranch switches to the provided branch, creating it from the
+// current HEAD if it doesn't exist yet. Changes in the worktree are
+// kept.
+func (g *gitProject) checkoutBranch(branch string) error {
+	ref := plumbing.NewBranchReferenceName(branch)
+	if head, err := g.repo.Head(); err == nil && head.Name() == ref {
+		return nil
+	}
+
+	_, err := g.repo.Reference(ref, false)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("failed to look up branch %q: %w", branch, err)
+	}
+
+	if err := g.wrk.Checkout(&gogit.CheckoutOptions{
+		Branch: ref,
+		Create: err != nil,
+		Keep:   true,
+	}); err != nil {
+		return fmt.Errorf("failed to checkout branch %q: %w", branch, err)
+	}
+	return nil
+}
+
+// commit stages the provided paths, relative to the project, and
+// commits them with the provided message. Any other changes in the
+// worktree are left untouched. false is returned if there were no
+// changes to commit.
+func (g *gitProject) commit(msg string, paths []string) (bool, error) {
+	status, err := g.wrk.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to get git status: %w", err)
+	}
+
+	changed := 0
+	for _, p := range slices.Sorted(slices.Values(paths)) {
+		name := path.Join(g.dir, filepath.ToSlash(p))
+		if fstatus, ok := status[name]; !ok || fstatus.Worktree == gogit.Unmodified {
+			continue
+		}
+
+		if _, err := g.wrk.Add(name); err != nil {
+			return false, fmt.Errorf("failed to stage %q: %w", name, err)
+		}
+		changed++
+	}
+	if changed == 0 {
+		return false, nil
+	}
+
+	opts := &gogit.CommitOptions{}
+	cfg, err := g.repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return false, fmt.Errorf("failed to read git config: %w", err)
+	}
+	if cfg.User.Name == "" || cfg.User.Email == "" {
+		author := defaultCommitAuthor
+		author.When = time.Now()
+		opts.Author = &author
+	}
+
+	if _, err := g.wrk.Commit(msg, opts); err != nil {
+		return false, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return true, nil
+}
+
+// writtenPaths returns the paths, relative to the project, of the files
+// written by stencil when rendering the provided templates, including
+// the lockfile.
+func writtenPaths(tpls []*codegen.Template) []string {
+	paths := []string{stencil.LockfileName}
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if !f.Skipped {
+				paths = append(paths, f.Name())
+			}
+		}
+	}
+	return paths
+}
+
+// commitMessage returns the message for a commit of the changes made
+// by rendering the provided modules, summarizing the versions of the
+// modules that changed compared to the provided lockfile.
+func commitMessage(lock *stencil.Lockfile, mods []*modules.Module) string {
+	var changes []string
+	for _, m := range mods {
+		var old *stencil.LockfileModuleEntry
+		if lock != nil {
+			if i := slices.IndexFunc(lock.Modules, func(lm *stencil.LockfileModuleEntry) bool {
+				return lm.Name == m.Name
+			}); i != -1 {
+				old = lock.Modules[i]
+			}
+		}
+
+		switch {
+		case old == nil:
+			changes = append(changes, fmt.Sprintf("- %s: %s (new)", m.Name, printVersion(m.Version)))
+		case !old.Version.Equal(m.Version):
+			changes = append(changes, fmt.Sprintf("- %s: %s -> %s", m.Name, printVersion(old.Version), printVersion(m.Version)))
+		}
+	}
+
+	if len(changes) == 0 {
+		return "chore: run stencil\n"
+	}
+	return "chore: update stencil modules\n\n" + strings.Join(changes, "\n") + "\n"
+}
@@ -0,0 +1,84 @@
+package stencil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRunCommitBranch(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "commit"))
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	env.ChangeWorkingDir(t, dir)
+
+	r, err := gogit.PlainInit(dir, false)
+	assert.NilError(t, err)
+	wrk, err := r.Worktree()
+	assert.NilError(t, err)
+
+	assert.NilError(t, os.WriteFile("README.md", []byte("readme\n"), 0o644))
+	_, err = wrk.Add("README.md")
+	assert.NilError(t, err)
+	initial, err := wrk.Commit("initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NilError(t, err)
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+	}
+
+	// Changes not made by stencil are not committed.
+	assert.NilError(t, os.WriteFile("README.md", []byte("changed\n"), 0o644))
+	assert.NilError(t, os.WriteFile("notes.txt", []byte("notes\n"), 0o644))
+
+	log := slogext.NewTestLogger(t)
+	err = NewCommand(log, manifest, false, false).SetCommitBranch("stencil-upgrade").Run(context.Background())
+	assert.NilError(t, err)
+
+	head, err := r.Head()
+	assert.NilError(t, err)
+	assert.Equal(t, head.Name(), plumbing.NewBranchReferenceName("stencil-upgrade"))
+
+	commit, err := r.CommitObject(head.Hash())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, commit.ParentHashes, []plumbing.Hash{initial})
+	assert.Equal(t, commit.Message, "chore: update stencil modules\n\n- testing: local (new)\n")
+
+	stats, err := commit.Stats()
+	assert.NilError(t, err)
+	var files []string
+	for _, s := range stats {
+		files = append(files, s.Name)
+	}
+	slices.Sort(files)
+	assert.DeepEqual(t, files, []string{"hello.txt", "stencil.lock"})
+
+	status, err := wrk.Status()
+	assert.NilError(t, err)
+	assert.Equal(t, status.File("README.md").Worktree, gogit.Modified)
+	assert.Equal(t, status.File("notes.txt").Worktree, gogit.Untracked)
+
+	// Running again without changes doesn't create a commit.
+	err = NewCommand(log, manifest, false, false).SetCommitBranch("stencil-upgrade").Run(context.Background())
+	assert.NilError(t, err)
+
+	head2, err := r.Head()
+	assert.NilError(t, err)
+	assert.Equal(t, head2.Hash(), head.Hash())
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for showing the changes a run
+// would make to the files of a project.
+
+package stencil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"go.rgst.io/stencil/v2/internal/codegen"
+)
+
+// writeDiffs writes a unified diff, between the files on disk and the
+// files generated by the provided templates, to the provided writer.
+// Files that would not change are left out.
+func writeDiffs(w io.Writer, tpls []*codegen.Template) error {
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Skipped {
+				continue
+			}
+
+			if err := writeDiff(w, tpl, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDiff writes a unified diff between the provided file on disk
+// and its generated contents to the provided writer.
+func writeDiff(w io.Writer, tpl *codegen.Template, f *codegen.File) error {
+	fromFile, toFile := "a/"+f.Name(), "b/"+f.Name()
+
+	existing, err := os.ReadFile(f.Name())
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %q: %w", f.Name(), err)
+	}
+
+	var generated []byte
+	switch {
+	case f.Deleted && !exists:
+		return nil
+	case f.Deleted:
+		toFile = "/dev/null"
+	case !exists:
+		fromFile = "/dev/null"
+		generated = f.Bytes()
+	default:
+		generated = f.Bytes()
+		if bytes.Equal(existing, generated) {
+			return nil
+		}
+	}
+
+	if tpl.Binary {
+		_, err := fmt.Fprintf(w, "Binary files %s and %s differ\n", fromFile, toFile)
+		return err
+	}
+
+	return difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        splitLines(string(existing)),
+		B:        splitLines(string(generated)),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+}
+
+// splitLines splits the provided string into lines for diffing, keeping
+// their line endings. Unlike [difflib.SplitLines], no empty line is
+// added when the string ends with a newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	// Ensure the last line is terminated so that it doesn't run into the
+	// next line of the diff.
+	lines[len(lines)-1] += "\n"
+	return lines
+}
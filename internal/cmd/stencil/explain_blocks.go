@@ -0,0 +1,66 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for explaining which templates
+// rendered the blocks of a file.
+
+package stencil
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"go.rgst.io/stencil/v2/internal/codegen"
+)
+
+// explainBlocks logs every block of the file at c.explainBlocksPath, as
+// rendered by the provided templates, at info level alongside the
+// template that rendered it. When multiple templates rendered the
+// file, the last one is used as it is the one that was written.
+func (c *Command) explainBlocks(tpls []*codegen.Template) error {
+	pth := filepath.Clean(c.explainBlocksPath)
+
+	var file *codegen.File
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Skipped || f.Deleted || filepath.Clean(f.Name()) != pth {
+				continue
+			}
+			file = f
+		}
+	}
+	if file == nil {
+		c.log.Infof("No template rendered %s", pth)
+		return nil
+	}
+
+	blocks, err := file.RenderedBlocks()
+	if err != nil {
+		return fmt.Errorf("failed to parse blocks of %s: %w", pth, err)
+	}
+	if len(blocks) == 0 {
+		c.log.Infof("No blocks in %s", pth)
+		return nil
+	}
+
+	c.log.Infof("Blocks in %s:", pth)
+	for _, name := range slices.Sorted(maps.Keys(blocks)) {
+		c.log.Infof("  - %s: %s", name, blocks[name])
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package stencil
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRunExplainBlocks(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "explain-blocks"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+	}
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+	err = NewCommand(log, manifest, false, false).SetExplainBlocks("config.yaml").Run(context.Background())
+	assert.NilError(t, err)
+
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, "Blocks in config.yaml:"), out)
+	assert.Assert(t, strings.Contains(out, "- extra: testing/config.yaml.tpl"), out)
+	assert.Assert(t, strings.Contains(out, "- settings: testing/config.yaml.tpl"), out)
+	assert.Assert(t, strings.Index(out, "- extra:") < strings.Index(out, "- settings:"))
+
+	buf.Reset()
+	err = NewCommand(log, manifest, false, false).SetExplainBlocks("missing.yaml").Run(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(buf.String(), "No template rendered missing.yaml"))
+}
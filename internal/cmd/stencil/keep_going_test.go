@@ -0,0 +1,47 @@
+package stencil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRunKeepGoing(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "keep-going"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+	}
+
+	log := slogext.NewTestLogger(t)
+	err = NewCommand(log, manifest, false, false).SetKeepGoing(true).Run(context.Background())
+	assert.ErrorContains(t, err, "1 template(s) failed")
+	assert.ErrorContains(t, err, `failed to render template "testing/bad.txt.tpl"`)
+	assert.ErrorContains(t, err, "something is broken")
+
+	b, err := os.ReadFile("good.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "good\n")
+
+	_, err = os.Stat("bad.txt")
+	assert.Assert(t, os.IsNotExist(err), "expected bad.txt to not be written")
+
+	// Without keep going, nothing is written.
+	assert.NilError(t, os.Remove("good.txt"))
+	err = NewCommand(log, manifest, false, false).Run(context.Background())
+	assert.ErrorContains(t, err, "something is broken")
+
+	_, err = os.Stat("good.txt")
+	assert.Assert(t, os.IsNotExist(err), "expected good.txt to not be written")
+}
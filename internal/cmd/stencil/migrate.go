@@ -0,0 +1,76 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for migrating the values of
+// deprecated arguments in the project's manifest.
+
+package stencil
+
+import (
+	"fmt"
+
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+)
+
+// migrateArguments moves the values of deprecated arguments set in the
+// project's manifest to the arguments replacing them, rewriting the
+// manifest on disk. The manifest used by this command is updated to
+// match. In dry-run mode, the moves are only logged.
+func (c *Command) migrateArguments(mods []*modules.Module) error {
+	var moves []configuration.ArgumentMove
+	for _, da := range codegen.DeprecatedArguments(c.manifest, mods) {
+		if da.ReplacedBy == "" {
+			continue
+		}
+		moves = append(moves, configuration.ArgumentMove{From: da.Name, To: da.ReplacedBy})
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+
+	if c.dryRun {
+		for _, mv := range moves {
+			c.log.Infof("Would move argument %q to %q (dry-run)", mv.From, mv.To)
+		}
+		return nil
+	}
+
+	path, err := configuration.DefaultManifestPath()
+	if err != nil {
+		return err
+	}
+
+	applied, err := configuration.MigrateArguments(path, moves)
+	if err != nil {
+		return fmt.Errorf("failed to migrate deprecated arguments: %w", err)
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+	for _, mv := range applied {
+		c.log.Infof("Moved argument %q to %q in %s", mv.From, mv.To, path)
+	}
+
+	// Reload the manifest so that this run uses the new arguments.
+	mf, err := configuration.LoadManifest(path)
+	if err != nil {
+		return fmt.Errorf("failed to load migrated manifest: %w", err)
+	}
+	c.manifest.Arguments = mf.Arguments
+	return nil
+}
@@ -0,0 +1,52 @@
+package stencil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRunMigrateMovesDeprecatedArguments(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "migrate"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.WriteFile("stencil.yaml", []byte(`name: testing
+arguments:
+  # Shown on the first line.
+  greeting: hello
+modules:
+  - name: testing
+replacements:
+  testing: `+modulePath+`
+`), 0o644))
+
+	manifest, err := configuration.LoadDefaultManifest()
+	assert.NilError(t, err)
+
+	log := slogext.NewTestLogger(t)
+	assert.NilError(t, NewCommand(log, manifest, false, false).SetMigrate(true).Run(context.Background()))
+
+	b, err := os.ReadFile("stencil.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `name: testing
+arguments:
+  # Shown on the first line.
+  message: hello
+modules:
+  - name: testing
+replacements:
+  testing: `+modulePath+`
+`)
+
+	// The migrated value is used by the same run.
+	b, err = os.ReadFile("message.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "hello\n")
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for writing a machine-readable
+// report of the files written by a run.
+
+package stencil
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"go.rgst.io/stencil/v2/internal/codegen"
+)
+
+// LastRunPath is the path, relative to the project, that the report of
+// the last run is written to when recordLastRun is set in stencil.yaml.
+const LastRunPath = ".stencil/last-run.json"
+
+// lastRunGitignore is written next to [LastRunPath] so that the report,
+// which changes on every run, is never committed.
+const lastRunGitignore = "# Created by stencil, the contents of this directory aren't meant to be committed.\n*\n"
+
+// Report describes the files written by a run, see
+// [Command.SetReport].
+type Report struct {
+	// DryRun denotes if the files were not actually written to disk
+	DryRun bool `json:"dryRun"`
+
+	// Files contains every file written by the run, sorted by path
+	Files []ReportedFile `json:"files"`
+}
+
+// ReportedFile is a file written by a run.
+type ReportedFile struct {
+	// Path is the path of the file, relative to the project
+	Path string `json:"path"`
+
+	// Action is the action taken on the file
+	Action codegen.FileAction `json:"action"`
+
+	// Changed denotes if the file was created, or updated with different
+	// contents
+	Changed bool `json:"changed"`
+
+	// Module is the name of the module that generated the file
+	Module string `json:"module"`
+
+	// Template is the path of the template that generated the file,
+	// relative to the module
+	Template string `json:"template"`
+
+	// SkippedReason is the reason the file was skipped, if it was
+	SkippedReason string `json:"skippedReason,omitempty"`
+}
+
+// newReport returns a [Report] of the files written by the provided
+// templates.
+func newReport(tpls []*codegen.Template, dryRun bool) *Report {
+	r := &Report{DryRun: dryRun, Files: make([]ReportedFile, 0)}
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Action() == "" {
+				continue
+			}
+
+			r.Files = append(r.Files, ReportedFile{
+				Path:          filepath.ToSlash(f.Name()),
+				Action:        f.Action(),
+				Changed:       f.Changed(),
+				Module:        tpl.Module.Name,
+				Template:      tpl.Path,
+				SkippedReason: f.SkippedReason,
+			})
+		}
+	}
+	slices.SortStableFunc(r.Files, func(a, b ReportedFile) int { return cmp.Compare(a.Path, b.Path) })
+	return r
+}
+
+// writeReport writes a report of the files written by the provided
+// templates to the provided path as JSON, creating its parent
+// directories if needed.
+func (c *Command) writeReport(pth string, tpls []*codegen.Template) error {
+	b, err := json.MarshalIndent(newReport(tpls, c.dryRun), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	c.log.Infof("Writing report to %s", pth)
+	if err := os.MkdirAll(filepath.Dir(pth), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for report: %w", err)
+	}
+	if err := os.WriteFile(pth, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// writeLastRun writes a report of the files written by the provided
+// templates to [LastRunPath], ignoring it from git.
+func (c *Command) writeLastRun(tpls []*codegen.Template) error {
+	if err := c.writeReport(LastRunPath, tpls); err != nil {
+		return err
+	}
+
+	gitignore := filepath.Join(filepath.Dir(LastRunPath), ".gitignore")
+	if _, err := os.Stat(gitignore); err == nil {
+		return nil
+	}
+	if err := os.WriteFile(gitignore, []byte(lastRunGitignore), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", gitignore, err)
+	}
+	return nil
+}
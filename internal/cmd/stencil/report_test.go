@@ -0,0 +1,95 @@
+package stencil
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+// readReport reads the report at the provided path.
+func readReport(t *testing.T, pth string) *Report {
+	b, err := os.ReadFile(pth)
+	assert.NilError(t, err)
+
+	var r Report
+	assert.NilError(t, json.Unmarshal(b, &r))
+	return &r
+}
+
+func TestRunWritesReport(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "report"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+	}
+
+	log := slogext.NewTestLogger(t)
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	assert.NilError(t, NewCommand(log, manifest, false, false).SetReport(reportPath).Run(context.Background()))
+	assert.DeepEqual(t, readReport(t, reportPath), &Report{Files: []ReportedFile{
+		{Path: "deleted.txt", Action: codegen.FileActionDeleted, Module: "testing", Template: "deleted.txt.tpl"},
+		{Path: "hello.txt", Action: codegen.FileActionCreated, Changed: true, Module: "testing", Template: "hello.txt.tpl"},
+		{
+			Path: "skipped.txt", Action: codegen.FileActionSkipped, Module: "testing", Template: "skipped.txt.tpl",
+			SkippedReason: "not needed",
+		},
+	}})
+
+	// Files that already exist with the same contents are updated, but
+	// not changed.
+	assert.NilError(t, NewCommand(log, manifest, true, false).SetReport(reportPath).Run(context.Background()))
+	r := readReport(t, reportPath)
+	assert.Assert(t, r.DryRun)
+	assert.DeepEqual(t, r.Files[1], ReportedFile{
+		Path: "hello.txt", Action: codegen.FileActionUpdated, Module: "testing", Template: "hello.txt.tpl",
+	})
+}
+
+func TestRunRecordsLastRun(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "report"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	manifest := &configuration.Manifest{
+		Name:          "testing",
+		Modules:       []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements:  map[string]string{"testing": modulePath},
+		RecordLastRun: true,
+	}
+
+	log := slogext.NewTestLogger(t)
+	assert.NilError(t, NewCommand(log, manifest, false, false).Run(context.Background()))
+	r := readReport(t, LastRunPath)
+	assert.Equal(t, len(r.Files), 3)
+	assert.Equal(t, r.Files[1].Path, "hello.txt")
+	assert.Equal(t, r.Files[1].Action, codegen.FileActionCreated)
+
+	// The report changes on every run, so it must not be committed.
+	b, err := os.ReadFile(filepath.Join(".stencil", ".gitignore"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), lastRunGitignore)
+
+	// The report reflects the latest run, dry-runs aren't recorded.
+	assert.NilError(t, os.WriteFile("hello.txt", []byte("changed\n"), 0o644))
+	assert.NilError(t, NewCommand(log, manifest, true, false).Run(context.Background()))
+	assert.Equal(t, readReport(t, LastRunPath).Files[1].Action, codegen.FileActionCreated)
+
+	assert.NilError(t, NewCommand(log, manifest, false, false).Run(context.Background()))
+	r = readReport(t, LastRunPath)
+	assert.Equal(t, r.Files[1].Action, codegen.FileActionUpdated)
+	assert.Assert(t, r.Files[1].Changed)
+}
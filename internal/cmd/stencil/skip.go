@@ -0,0 +1,65 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for explaining why files were
+// skipped during a run.
+
+package stencil
+
+import (
+	"maps"
+	"slices"
+
+	"go.rgst.io/stencil/v2/internal/codegen"
+)
+
+// noSkipReason is used in place of the reason of skipped files that
+// weren't given one.
+const noSkipReason = "no reason given"
+
+// explainSkipped logs every skipped file of the provided templates at
+// info level, grouped by the reason they were skipped.
+func (c *Command) explainSkipped(tpls []*codegen.Template) {
+	byReason := make(map[string][]string)
+	total := 0
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if !f.Skipped {
+				continue
+			}
+
+			reason := f.SkippedReason
+			if reason == "" {
+				reason = noSkipReason
+			}
+			byReason[reason] = append(byReason[reason], f.Name())
+			total++
+		}
+	}
+
+	if total == 0 {
+		c.log.Info("No files were skipped")
+		return
+	}
+
+	c.log.Infof("Skipped %d file(s):", total)
+	for _, reason := range slices.Sorted(maps.Keys(byReason)) {
+		c.log.Infof("  %s:", reason)
+		for _, name := range slices.Sorted(slices.Values(byReason[reason])) {
+			c.log.Infof("    - %s", name)
+		}
+	}
+}
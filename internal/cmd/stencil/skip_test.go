@@ -0,0 +1,59 @@
+package stencil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestRunExplainSkip(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "explain-skip"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+	}
+
+	// The once file already exists, so it is skipped.
+	assert.NilError(t, os.WriteFile("once.txt", []byte("mine\n"), 0o644))
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+	err = NewCommand(log, manifest, false, false).SetExplainSkip(true).Run(context.Background())
+	assert.NilError(t, err)
+
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, "Skipped 2 file(s):"), out)
+	summary := out[strings.Index(out, "Skipped 2 file(s):"):]
+	assertBefore(t, summary, "Not needed:", "- skipped.txt")
+	assertBefore(t, summary, "Once file, output already exists:", "- once.txt")
+	assert.Assert(t, !strings.Contains(summary, "always.txt"), "expected always.txt to not be skipped")
+
+	// Without the flag, skipped files are only logged at debug level.
+	buf.Reset()
+	err = NewCommand(log, manifest, false, false).Run(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(buf.String(), "Skipped 2 file(s)"))
+}
+
+// assertBefore asserts that s contains both first and second, with
+// first appearing before second.
+func assertBefore(t *testing.T, s, first, second string) {
+	t.Helper()
+
+	assert.Assert(t, strings.Contains(s, first), "expected %q in %q", first, s)
+	assert.Assert(t, strings.Contains(s, second), "expected %q in %q", second, s)
+	assert.Assert(t, strings.Index(s, first) < strings.Index(s, second), "expected %q before %q in %q", first, second, s)
+}
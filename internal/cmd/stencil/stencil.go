@@ -24,6 +24,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"slices"
 
@@ -58,12 +59,71 @@ type Command struct {
 	// adopt denotes if we should use heuristics to detect code that should go
 	// into blocks to assist with first-time adoption of templates
 	adopt bool
+
+	// tags and excludeTags filter which templates are rendered, see
+	// [Command.SetTagFilter].
+	tags        []string
+	excludeTags []string
+
+	// validateSchemas denotes if all argument schemas should be
+	// validated before rendering, see [Command.SetValidateSchemas].
+	validateSchemas bool
+
+	// targetOS and targetArch override the platform templates generate
+	// for, see [Command.SetTarget].
+	targetOS   string
+	targetArch string
+
+	// dumpValuesPath is the path to write the values used to render
+	// the templates to, see [Command.SetDumpValues].
+	dumpValuesPath string
+
+	// keepGoing denotes if rendering should continue when a template
+	// fails, see [Command.SetKeepGoing].
+	keepGoing bool
+
+	// explainSkip denotes if a summary of the skipped files should be
+	// logged, see [Command.SetExplainSkip].
+	explainSkip bool
+
+	// explainBlocksPath is the path of a file whose blocks should be
+	// logged alongside the template that rendered them, see
+	// [Command.SetExplainBlocks].
+	explainBlocksPath string
+
+	// reportPath is the path to write a report of the files written by
+	// the run to, see [Command.SetReport].
+	reportPath string
+
+	// commitBranch is the git branch to commit the changes made by a
+	// run to, see [Command.SetCommitBranch].
+	commitBranch string
+
+	// requireClean denotes if the run should fail when generated files
+	// have changes that aren't committed to git, see
+	// [Command.SetRequireClean].
+	requireClean bool
+
+	// migrate denotes if the values of deprecated arguments should be
+	// moved to the arguments replacing them, see [Command.SetMigrate].
+	migrate bool
+
+	// diffOut is where a diff of the changes to the project's files is
+	// written to in dry-run mode, see [Command.SetDiffOutput].
+	diffOut io.Writer
+
+	// modulesLock is a lockfile, e.g., of a different project, whose
+	// module versions are used instead of the ones in lock, see
+	// [Command.SetModulesLockfile].
+	modulesLock *stencil.Lockfile
 }
 
 // printVersion is a command line friendly version of
 // resolver.Version.String()
 func printVersion(v *resolver.Version) string {
 	switch {
+	case v.Virtual != "":
+		return v.Virtual
 	case v.Tag != "":
 		return fmt.Sprintf("%s (%s)", v.Tag, v.Commit)
 	case v.Branch != "":
@@ -89,18 +149,174 @@ func NewCommand(log slogext.Logger, s *configuration.Manifest, dryRun, adopt boo
 	}
 }
 
-// useModulesFromLockfile returns a list of modules from the lockfile
-// that should be used for this run of the stencil command.
+// LoadLockfileAt loads the lockfile at the provided path, which, unlike
+// [stencil.LoadLockfile], is the path of the file itself. This is used
+// to load the lockfile of a different project, see
+// [Command.SetModulesLockfile].
+func LoadLockfileAt(path string) (*stencil.Lockfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lockfile: %w", err)
+	}
+	defer f.Close()
+
+	lock, err := stencil.ReadLockfile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %q: %w", path, err)
+	}
+	if lock == nil {
+		return nil, fmt.Errorf("lockfile %q is empty", path)
+	}
+	return lock, nil
+}
+
+// SetTagFilter limits the templates that are rendered to those tagged
+// with at least one of the provided tags, if any, and none of the
+// excluded tags. See [codegen.Stencil.SetTagFilter].
+func (c *Command) SetTagFilter(tags, excludeTags []string) *Command {
+	c.tags = tags
+	c.excludeTags = excludeTags
+	return c
+}
+
+// SetValidateSchemas enables validating that the JSON schema of every
+// argument declared by the project's modules compiles before rendering,
+// see [codegen.Stencil.ValidateArgumentSchemas].
+func (c *Command) SetValidateSchemas(validate bool) *Command {
+	c.validateSchemas = validate
+	return c
+}
+
+// SetTarget overrides the operating system and architecture that
+// templates generate for, see [codegen.Stencil.SetTarget].
+func (c *Command) SetTarget(goos, goarch string) *Command {
+	c.targetOS = goos
+	c.targetArch = goarch
+	return c
+}
+
+// SetDumpValues writes the values used to render the templates,
+// including the resolved arguments of every module, as JSON to the
+// provided path after rendering. An empty path disables this. See
+// [codegen.Stencil.DumpValues].
+func (c *Command) SetDumpValues(pth string) *Command {
+	c.dumpValuesPath = pth
+	return c
+}
+
+// SetDiffOutput writes a unified diff of the changes that would be
+// made to the files of the project to the provided writer when running
+// in dry-run mode. A nil writer disables this.
+func (c *Command) SetDiffOutput(w io.Writer) *Command {
+	c.diffOut = w
+	return c
+}
+
+// SetKeepGoing continues rendering, and writing the files of the
+// templates that succeeded, when a template fails. All failures are
+// returned as a single error at the end of the run. See
+// [codegen.Stencil.SetKeepGoing].
+func (c *Command) SetKeepGoing(keepGoing bool) *Command {
+	c.keepGoing = keepGoing
+	return c
+}
+
+// SetExplainSkip logs every file that was skipped, grouped by the
+// reason it was skipped (e.g., by file.Skip or file.Once), at info
+// level after writing files. Otherwise, skipped files are only logged
+// at debug level.
+func (c *Command) SetExplainSkip(explainSkip bool) *Command {
+	c.explainSkip = explainSkip
+	return c
+}
+
+// SetExplainBlocks logs every block of the file at the provided path,
+// relative to the project, at info level after writing files alongside
+// the template that rendered it. This is useful for debugging files
+// that are shared by multiple templates. An empty path disables this.
+func (c *Command) SetExplainBlocks(pth string) *Command {
+	c.explainBlocksPath = pth
+	return c
+}
+
+// SetReport writes a JSON report (see [Report]) of every file written
+// by the run, and the action taken on it, to the provided path. This
+// allows tooling (e.g., CI) to inspect the changes made by stencil
+// without parsing its logs. The report is also written in dry-run
+// mode. An empty path disables this.
+func (c *Command) SetReport(pth string) *Command {
+	c.reportPath = pth
+	return c
+}
+
+// SetCommitBranch switches to the provided git branch, creating it if
+// it doesn't exist, before rendering and commits the changed files of
+// the project to it after a successful run. The commit message
+// summarizes the module versions that changed. An empty branch
+// disables this. It can't be used in dry-run mode.
+func (c *Command) SetCommitBranch(branch string) *Command {
+	c.commitBranch = branch
+	return c
+}
+
+// SetRequireClean fails the run, after running post-run commands, if
+// any of the generated files or the lockfile have changes that aren't
+// committed to git (e.g., they are modified or untracked). Unlike
+// comparing a re-render, this checks the state of the git repository.
+func (c *Command) SetRequireClean(requireClean bool) *Command {
+	c.requireClean = requireClean
+	return c
+}
+
+// SetMigrate moves the values of deprecated arguments set in the
+// project's manifest (stencil.yaml) to the arguments that replace them
+// before rendering, rewriting the manifest on disk.
+func (c *Command) SetMigrate(migrate bool) *Command {
+	c.migrate = migrate
+	return c
+}
+
+// SetLockfile replaces the lockfile that was loaded from disk when the
+// command was created, e.g., with one from a previous commit (see
+// [LoadAsOf]). A nil lockfile is treated as if none existed.
+func (c *Command) SetLockfile(lock *stencil.Lockfile) *Command {
+	c.lock = lock
+	return c
+}
+
+// SetModulesLockfile uses the module versions of the provided lockfile,
+// e.g., a "golden" lockfile shared across projects, instead of the
+// ones in the project's lockfile when resolving modules. Modules in it
+// that the project doesn't use are ignored. The files in the project's
+// lockfile are still used and it is updated as usual. A nil lockfile
+// disables this.
+func (c *Command) SetModulesLockfile(lock *stencil.Lockfile) *Command {
+	c.modulesLock = lock
+	return c
+}
+
+// modulesLockfile returns the lockfile to use the module versions of,
+// see [Command.SetModulesLockfile].
+func (c *Command) modulesLockfile() *stencil.Lockfile {
+	if c.modulesLock != nil {
+		return c.modulesLock
+	}
+	return c.lock
+}
+
+// useModulesFromLockfile returns a list of modules from the provided
+// lockfile that should be used for this run of the stencil command.
 //
 // Modules import paths provided in 'skip' will be skipped and not
 // returned in the modules slice.
-func (c *Command) useModulesFromLockfile(ctx context.Context, skip map[string]struct{}) ([]*modules.Module, error) {
+func (c *Command) useModulesFromLockfile(ctx context.Context, lock *stencil.Lockfile,
+	skip map[string]struct{}) ([]*modules.Module, error) {
 	if skip == nil {
 		skip = make(map[string]struct{})
 	}
 
-	mods := make([]*modules.Module, 0, len(c.lock.Modules))
-	for _, me := range c.lock.Modules {
+	mods := make([]*modules.Module, 0, len(lock.Modules))
+	for _, me := range lock.Modules {
 		if _, ok := skip[me.Name]; ok {
 			continue
 		}
@@ -124,6 +340,28 @@ func (c *Command) useModulesFromLockfile(ctx context.Context, skip map[string]st
 // instead of resolving them. If ignoreLockfile is true, it will ignore
 // the lockfile and resolve the modules anyways.
 func (c *Command) resolveModules(ctx context.Context, ignoreLockfile bool) ([]*modules.Module, error) {
+	opts, err := c.resolveOptions(ctx, ignoreLockfile)
+	if err != nil {
+		return nil, err
+	}
+
+	return modules.FetchModules(ctx, opts)
+}
+
+// Graph resolves the modules for the project, like [Command.Run], and
+// returns their dependency graph.
+func (c *Command) Graph(ctx context.Context) (*modules.Graph, error) {
+	opts, err := c.resolveOptions(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return modules.FetchModuleGraph(ctx, opts)
+}
+
+// resolveOptions returns the options used to resolve the modules for the
+// project. See [Command.resolveModules] for how the lockfile is used.
+func (c *Command) resolveOptions(ctx context.Context, ignoreLockfile bool) (*modules.ModuleResolveOptions, error) {
 	// replacements contains module versions that should be used instead
 	// of being resolved.
 	replacements := make([]*modules.Module, 0)
@@ -131,7 +369,7 @@ func (c *Command) resolveModules(ctx context.Context, ignoreLockfile bool) ([]*m
 	// If we have a lockfile, we also need to check if the modules list
 	// has changed since the last run. If it has, we need to re-resolve
 	// the changed modules.
-	if c.lock != nil && !ignoreLockfile {
+	if lock := c.modulesLockfile(); lock != nil && !ignoreLockfile {
 		manifestModulesHM := make(map[string]string)
 		for _, m := range c.manifest.Modules {
 			manifestModulesHM[m.Name] = m.Version
@@ -140,7 +378,7 @@ func (c *Command) resolveModules(ctx context.Context, ignoreLockfile bool) ([]*m
 		// Compare the modules from the lockfile vs the manifest to
 		// determine which ones have changed.
 		changed := make(map[string]struct{})
-		for _, m := range c.lock.Modules {
+		for _, m := range lock.Modules {
 			// If a version was changed to be replaced with a local version,
 			// we also need to re-resolve it. We check before determining if
 			// we're a "latest" module because we do want to allow
@@ -150,6 +388,17 @@ func (c *Command) resolveModules(ctx context.Context, ignoreLockfile bool) ([]*m
 				continue
 			}
 
+			// Overrides take precedence over the requested version, and may
+			// apply to modules that are only depended on transitively. Unlike
+			// versions in the modules list, overrides may be constraints, so
+			// the locked version is kept as long as it satisfies them.
+			if v, ok := c.manifest.ModuleOverrides[m.Name]; ok {
+				if !modules.VersionSatisfies(m.Version, v) {
+					changed[m.Name] = struct{}{}
+				}
+				continue
+			}
+
 			manifestEntryVer, ok := manifestModulesHM[m.Name]
 			if manifestEntryVer == "" {
 				// We shouldn't automatically re-resolve modules that don't ask
@@ -175,7 +424,7 @@ func (c *Command) resolveModules(ctx context.Context, ignoreLockfile bool) ([]*m
 		}
 
 		var err error
-		replacements, err = c.useModulesFromLockfile(ctx, changed)
+		replacements, err = c.useModulesFromLockfile(ctx, lock, changed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to use modules from lock: %w", err)
 		}
@@ -185,11 +434,11 @@ func (c *Command) resolveModules(ctx context.Context, ignoreLockfile bool) ([]*m
 
 	// On first run, we need to resolve the modules. Otherwise, the user
 	// will be expected to run 'stencil upgrade' to update the lockfile.
-	return modules.FetchModules(ctx, &modules.ModuleResolveOptions{
+	return &modules.ModuleResolveOptions{
 		Manifest:     c.manifest,
 		Log:          c.log,
 		Replacements: replacementsHM,
-	})
+	}, nil
 }
 
 // Upgrade checks for upgrades to the modules in the project and
@@ -252,6 +501,63 @@ func (c *Command) Run(ctx context.Context) error {
 		return err
 	}
 
+	if err := c.checkModules(mods); err != nil {
+		return err
+	}
+
+	return c.runWithModules(ctx, mods)
+}
+
+// Apply is like [Command.Run], but only writes the files generated by
+// the provided module. The other modules of the project are still
+// rendered, as they can provide its arguments and add to its module
+// hooks. Post-run commands are not ran.
+func (c *Command) Apply(ctx context.Context, module string) error {
+	c.log.Info("Fetching dependencies")
+	mods, err := c.resolveModules(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	// Every module is rendered, not just the ones the module depends
+	// on, as any module can add to the module hooks of this module.
+	if !slices.ContainsFunc(mods, func(m *modules.Module) bool { return m.Name == module }) {
+		return fmt.Errorf("module %q is not used by this project", module)
+	}
+
+	if err := c.checkModules(mods); err != nil {
+		return err
+	}
+
+	st := codegen.NewStencil(c.manifest, c.lock, mods, c.log, c.adopt)
+	defer st.Close()
+	st.SetTagFilter(c.tags, c.excludeTags)
+	st.SetTarget(c.targetOS, c.targetArch)
+
+	c.log.Info("Loading native extensions")
+	if err := st.RegisterExtensions(ctx); err != nil {
+		return err
+	}
+
+	c.log.Info("Rendering templates")
+	tpls, err := st.Render(ctx, c.log)
+	if err != nil {
+		return err
+	}
+
+	tpls = slices.DeleteFunc(tpls, func(t *codegen.Template) bool {
+		return t.Module.Name != module
+	})
+	return c.writeFiles(st, tpls)
+}
+
+// checkModules logs the provided modules and ensures that this version
+// of stencil satisfies their minimum required version.
+func (c *Command) checkModules(mods []*modules.Module) error {
+	if _, err := c.manifest.ModuleAliases(); err != nil {
+		return err
+	}
+
 	for _, m := range mods {
 		c.log.Infof(" -> %s %s", m.Name, printVersion(m.Version))
 
@@ -263,14 +569,107 @@ func (c *Command) Run(ctx context.Context) error {
 			}
 		}
 	}
+	return nil
+}
 
-	return c.runWithModules(ctx, mods)
+// ExplainArg explains how the value of an argument is derived for the
+// provided module in the current project.
+func (c *Command) ExplainArg(ctx context.Context, module, pth string) (*codegen.ArgExplanation, error) {
+	mods, err := c.resolveModules(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	st := codegen.NewStencil(c.manifest, c.lock, mods, c.log, c.adopt)
+	defer st.Close()
+
+	return st.ExplainArg(module, pth)
+}
+
+// Functions returns all functions available to the templates of the
+// current project, including the functions provided by the native
+// extensions of its modules.
+func (c *Command) Functions(ctx context.Context) ([]codegen.FunctionInfo, error) {
+	mods, err := c.resolveModules(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	st := codegen.NewStencil(c.manifest, c.lock, mods, c.log, c.adopt)
+	defer st.Close()
+
+	if err := st.RegisterExtensions(ctx); err != nil {
+		return nil, err
+	}
+
+	return st.Functions()
+}
+
+// ExportedFunctions renders only the library templates of the current
+// project and returns the functions that its modules exported, see
+// [codegen.Stencil.RenderLibraries].
+func (c *Command) ExportedFunctions(ctx context.Context) ([]codegen.ExportedFunctionInfo, error) {
+	mods, err := c.resolveModules(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkModules(mods); err != nil {
+		return nil, err
+	}
+
+	st := codegen.NewStencil(c.manifest, c.lock, mods, c.log, c.adopt)
+	defer st.Close()
+
+	if err := st.RegisterExtensions(ctx); err != nil {
+		return nil, err
+	}
+
+	return st.RenderLibraries(ctx, c.log)
 }
 
 // runWithModules runs the stencil command with the given modules
 func (c *Command) runWithModules(ctx context.Context, mods []*modules.Module) error {
+	var gp *gitProject
+	if c.commitBranch != "" {
+		if c.dryRun {
+			return fmt.Errorf("committing changes is not supported in dry-run mode")
+		}
+
+		var err error
+		gp, err = openGitProject()
+		if err != nil {
+			return err
+		}
+
+		c.log.Infof("Switching to branch %s", c.commitBranch)
+		if err := gp.checkoutBranch(c.commitBranch); err != nil {
+			return err
+		}
+	}
+
+	if c.migrate {
+		if err := c.migrateArguments(mods); err != nil {
+			return err
+		}
+	}
+
 	st := codegen.NewStencil(c.manifest, c.lock, mods, c.log, c.adopt)
 	defer st.Close()
+	st.SetTagFilter(c.tags, c.excludeTags)
+	st.SetTarget(c.targetOS, c.targetArch)
+	st.SetKeepGoing(c.keepGoing)
+
+	if err := st.ValidateArgumentGroups(); err != nil {
+		return err
+	}
+
+	if c.validateSchemas {
+		c.log.Info("Validating argument schemas")
+		if err := st.ValidateArgumentSchemas(); err != nil {
+			return err
+		}
+	}
 
 	c.log.Info("Loading native extensions")
 	if err := st.RegisterExtensions(ctx); err != nil {
@@ -287,13 +686,69 @@ func (c *Command) runWithModules(ctx context.Context, mods []*modules.Module) er
 		return err
 	}
 
+	if c.dumpValuesPath != "" {
+		if err := c.dumpValues(ctx, st); err != nil {
+			return err
+		}
+	}
+
+	// Post-run commands are likely to fail, or do the wrong thing, with
+	// files missing, so don't run them when a template failed.
+	if err := st.RenderErrors(); err != nil {
+		return err
+	}
+
 	// Can't dry run post run yet
 	if c.dryRun {
 		c.log.Info("Skipping post-run commands, dry-run")
 		return nil
 	}
 
-	return st.PostRun(ctx, c.log)
+	if err := st.PostRun(ctx, c.log, tpls); err != nil {
+		return err
+	}
+
+	if c.requireClean {
+		if err := c.checkClean(tpls); err != nil {
+			return err
+		}
+	}
+
+	if gp == nil {
+		return nil
+	}
+
+	committed, err := gp.commit(commitMessage(c.lock, mods), writtenPaths(tpls))
+	if err != nil {
+		return err
+	}
+	if !committed {
+		c.log.Info("No changes to commit")
+		return nil
+	}
+	c.log.Infof("Committed changes to branch %s", c.commitBranch)
+	return nil
+}
+
+// dumpValues writes the values used to render the templates to
+// c.dumpValuesPath.
+func (c *Command) dumpValues(ctx context.Context, st *codegen.Stencil) error {
+	if c.dryRun {
+		c.log.Infof("Skipping writing values to %s, dry-run", c.dumpValuesPath)
+		return nil
+	}
+
+	c.log.Infof("Writing values to %s", c.dumpValuesPath)
+	f, err := os.Create(c.dumpValuesPath)
+	if err != nil {
+		return fmt.Errorf("failed to create values file: %w", err)
+	}
+	defer f.Close()
+
+	if err := st.DumpValues(ctx, f); err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 // writeFiles writes the files to disk
@@ -307,11 +762,36 @@ func (c *Command) writeFiles(st *codegen.Stencil, tpls []*codegen.Template) erro
 		}
 	}
 
+	if c.explainSkip {
+		c.explainSkipped(tpls)
+	}
+
+	if c.explainBlocksPath != "" {
+		if err := c.explainBlocks(tpls); err != nil {
+			return err
+		}
+	}
+
+	if c.reportPath != "" {
+		if err := c.writeReport(c.reportPath, tpls); err != nil {
+			return err
+		}
+	}
+
 	// Don't generate a lockfile in dry-run mode
 	if c.dryRun {
+		if c.diffOut != nil {
+			return writeDiffs(c.diffOut, tpls)
+		}
 		return nil
 	}
 
+	if c.manifest.RecordLastRun {
+		if err := c.writeLastRun(tpls); err != nil {
+			return err
+		}
+	}
+
 	l := st.GenerateLockfile(tpls)
 	if c.lock != nil {
 		// Pull in older missing files (if any) from the last lock file
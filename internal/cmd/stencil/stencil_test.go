@@ -4,6 +4,7 @@ package stencil
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -44,6 +45,52 @@ func TestResolveModulesShouldUseModulesFromLockfile(t *testing.T) {
 	assert.DeepEqual(t, mods[0].Version, s.lock.Modules[0].Version)
 }
 
+// TestResolveModulesShouldUseModulesFromExternalLockfile ensures that
+// the module versions of a lockfile set with SetModulesLockfile are used
+// instead of the ones in the project's lockfile.
+func TestResolveModulesShouldUseModulesFromExternalLockfile(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+
+	lockPath := filepath.Join(t.TempDir(), "golden.lock")
+	assert.NilError(t, os.WriteFile(lockPath, []byte(`modules:
+  - name: github.com/rgst-io/stencil-golang
+    url: https://github.com/rgst-io/stencil-golang
+    version:
+      commit: 3c3213721335c53fd78f4fede1b3704801616615
+      tag: v0.5.0
+  - name: github.com/rgst-io/stencil-module
+    url: https://github.com/rgst-io/stencil-module
+    version:
+      commit: 8a953c803b4762fbe90da806f39ad7af404aca0a
+      tag: v0.1.0
+`), 0o644))
+	golden, err := LoadLockfileAt(lockPath)
+	assert.NilError(t, err)
+
+	s := NewCommand(log, &configuration.Manifest{
+		Modules: []*configuration.TemplateRepository{{
+			Name: "github.com/rgst-io/stencil-golang",
+		}},
+	}, false, false).SetModulesLockfile(golden)
+	s.lock = &stencil.Lockfile{
+		Modules: []*stencil.LockfileModuleEntry{{
+			Name: "github.com/rgst-io/stencil-golang",
+			Version: &resolver.Version{
+				Commit: "fc954774dd29f0505158e86afbd18771ac92d50e",
+				Tag:    "v0.4.0",
+			},
+		}},
+	}
+
+	mods, err := s.resolveModules(context.Background(), false)
+	assert.NilError(t, err, "failed to resolve modules")
+	assert.Equal(t, len(mods), 1, "expected exactly one module")
+	assert.DeepEqual(t, mods[0].Version, &resolver.Version{
+		Commit: "3c3213721335c53fd78f4fede1b3704801616615",
+		Tag:    "v0.5.0",
+	})
+}
+
 // TestResolveModulesShouldUpgradeWhenExplicitlyAsked ensures that when
 // 'stencil.yaml' is modified, the version in the lockfile is not used.
 func TestResolveModulesShouldUpgradeWhenExplicitlyAsked(t *testing.T) {
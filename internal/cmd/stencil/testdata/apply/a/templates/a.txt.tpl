@@ -0,0 +1 @@
+{{ stencil.GetModuleHook "greetings" | uniq | sortAlpha | join "," }}
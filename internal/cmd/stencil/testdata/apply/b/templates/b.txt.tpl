@@ -0,0 +1,2 @@
+{{- stencil.AddToModuleHook "a" "greetings" "from-b" }}
+b
@@ -0,0 +1,6 @@
+## <<Stencil::Block(extra)>>
+{{ file.Block "extra" }}
+## <</Stencil::Block>>
+## <<Stencil::Block(settings)>>
+{{ file.Block "settings" }}
+## <</Stencil::Block>>
@@ -0,0 +1 @@
+always
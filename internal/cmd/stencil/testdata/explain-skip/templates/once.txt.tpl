@@ -0,0 +1,2 @@
+{{- file.Once }}
+once
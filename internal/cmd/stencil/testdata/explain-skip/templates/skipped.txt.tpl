@@ -0,0 +1 @@
+{{- file.Skip "Not needed" }}
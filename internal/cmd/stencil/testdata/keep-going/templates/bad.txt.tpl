@@ -0,0 +1 @@
+{{ fail "something is broken" }}
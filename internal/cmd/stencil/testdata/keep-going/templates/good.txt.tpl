@@ -0,0 +1 @@
+good
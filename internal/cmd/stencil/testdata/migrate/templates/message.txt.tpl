@@ -0,0 +1 @@
+{{ stencil.Arg "message" }}
@@ -0,0 +1 @@
+{{- file.Delete }}
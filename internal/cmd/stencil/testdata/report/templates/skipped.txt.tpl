@@ -0,0 +1 @@
+{{- file.Skip "not needed" }}
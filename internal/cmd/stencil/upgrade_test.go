@@ -0,0 +1,59 @@
+package stencil
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaredallard/vcs/resolver"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"go.rgst.io/stencil/v2/pkg/stencil"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestUpgradeDryRunShowsDiff(t *testing.T) {
+	modulePath, err := filepath.Abs(filepath.Join("testdata", "asof-module"))
+	assert.NilError(t, err)
+
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.WriteFile("hello.txt", []byte("hi\n"), 0o644))
+
+	lock := &stencil.Lockfile{
+		Modules: []*stencil.LockfileModuleEntry{{
+			Name:    "testing",
+			Version: &resolver.Version{Tag: "v0.1.0", Commit: "8a953c803b4762fbe90da806f39ad7af404aca0a"},
+		}},
+	}
+	assert.NilError(t, lock.Write())
+	origLock, err := os.ReadFile("stencil.lock")
+	assert.NilError(t, err)
+
+	manifest := &configuration.Manifest{
+		Name:         "testing",
+		Modules:      []*configuration.TemplateRepository{{Name: "testing"}},
+		Replacements: map[string]string{"testing": modulePath},
+		Arguments:    map[string]any{"greeting": "hello"},
+	}
+
+	var buf bytes.Buffer
+	log := slogext.NewTestLogger(t)
+	err = NewCommand(log, manifest, true, false).
+		SetLockfile(lock).
+		SetDiffOutput(&buf).
+		Upgrade(context.Background())
+	assert.NilError(t, err)
+
+	assert.Equal(t, buf.String(), "--- a/hello.txt\n+++ b/hello.txt\n@@ -1 +1 @@\n-hi\n+hello\n")
+
+	b, err := os.ReadFile("hello.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "hi\n")
+
+	b, err = os.ReadFile("stencil.lock")
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), string(origLock))
+}
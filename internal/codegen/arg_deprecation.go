@@ -0,0 +1,98 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for finding deprecated arguments
+// set by a project.
+
+package codegen
+
+import (
+	"maps"
+	"slices"
+
+	"go.rgst.io/stencil/v2/internal/dotnotation"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// DeprecatedArgument is a deprecated argument of a module that is set
+// by a project.
+type DeprecatedArgument struct {
+	// Module is the name of the module that declares the argument.
+	Module string
+
+	// Name is the name of the argument.
+	Name string
+
+	// Message is the deprecation message of the argument, if any.
+	Message string
+
+	// ReplacedBy is the name of the argument that replaces it, if any.
+	ReplacedBy string
+}
+
+// DeprecatedArguments returns the deprecated arguments (see
+// [configuration.Argument.Deprecated]) of the provided modules that are
+// set in the provided manifest, sorted by module and name.
+func DeprecatedArguments(m *configuration.Manifest, mods []*modules.Module) []DeprecatedArgument {
+	args := make(map[any]any, len(m.Arguments))
+	for k, v := range m.Arguments {
+		args[k] = v
+	}
+
+	var deprecated []DeprecatedArgument
+	for _, mod := range mods {
+		if mod.Manifest == nil {
+			continue
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(mod.Manifest.Arguments)) {
+			arg := mod.Manifest.Arguments[name]
+			if arg.Deprecated == "" && arg.ReplacedBy == "" {
+				continue
+			}
+			if _, err := dotnotation.Get(args, name); err != nil {
+				continue
+			}
+
+			deprecated = append(deprecated, DeprecatedArgument{
+				Module:     mod.Name,
+				Name:       name,
+				Message:    arg.Deprecated,
+				ReplacedBy: arg.ReplacedBy,
+			})
+		}
+	}
+	return deprecated
+}
+
+// warnDeprecatedArgs logs a warning for every deprecated argument that
+// is set by the project.
+func (s *Stencil) warnDeprecatedArgs(log slogext.Logger) {
+	for _, da := range DeprecatedArguments(s.m, s.modules) {
+		l := log.With("module", da.Module, "argument", da.Name)
+		if da.Message != "" {
+			l = l.With("reason", da.Message)
+		}
+		if da.ReplacedBy == "" {
+			l.Warn("Argument is deprecated")
+			continue
+		}
+		l.With("replacedBy", da.ReplacedBy).
+			Warn("Argument is deprecated, run stencil with --migrate to move its value to the argument replacing it")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for validating the argument
+// groups declared by modules.
+
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.rgst.io/stencil/v2/internal/dotnotation"
+	"go.rgst.io/stencil/v2/internal/modules"
+)
+
+// ValidateArgumentGroups ensures that the arguments of every module
+// satisfy the argument groups it declares (see
+// [configuration.TemplateRepositoryManifest.ArgumentGroups]). All
+// violations are returned as a single error.
+func (s *Stencil) ValidateArgumentGroups() error {
+	args := make(map[any]any, len(s.m.Arguments))
+	for k, v := range s.m.Arguments {
+		args[k] = v
+	}
+
+	errs := make([]error, 0)
+	for _, m := range s.modules {
+		if m.Manifest == nil {
+			continue
+		}
+
+		for _, g := range m.Manifest.ArgumentGroups {
+			set, err := setArguments(m, g.Arguments, args)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if g.Exclusive && len(set) > 1 {
+				errs = append(errs, fmt.Errorf("module %q allows only one of the arguments %s to be set, but %s are set",
+					m.Name, quoteList(g.Arguments), quoteList(set)))
+			}
+			if g.AtLeastOne && len(set) == 0 {
+				errs = append(errs, fmt.Errorf("module %q requires at least one of the arguments %s to be set",
+					m.Name, quoteList(g.Arguments)))
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("invalid arguments: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// setArguments returns the provided arguments of the provided module
+// that are set, either by the project (args) or by their default, to
+// a non-empty value.
+func setArguments(m *modules.Module, names []string, args map[any]any) ([]string, error) {
+	var set []string
+	for _, name := range names {
+		arg, ok := m.Manifest.Arguments[name]
+		if !ok {
+			return nil, fmt.Errorf("module %q declares an argument group with unknown argument %q", m.Name, name)
+		}
+
+		v, err := dotnotation.Get(args, name)
+		if err != nil {
+			v = arg.Default
+		}
+
+		if v != nil && !reflect.ValueOf(v).IsZero() && !isEmptyCollection(v) {
+			set = append(set, name)
+		}
+	}
+	return set, nil
+}
+
+// isEmptyCollection returns true if the provided value is a slice or
+// map without any elements.
+func isEmptyCollection(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	default:
+		return false
+	}
+}
+
+// quoteList returns the provided strings quoted and separated by
+// commas, e.g., "a", "b".
+func quoteList(s []string) string {
+	quoted := make([]string, len(s))
+	for i := range s {
+		quoted[i] = fmt.Sprintf("%q", s[i])
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,70 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+// newArgumentGroupsStencil returns a Stencil for a module with a cache
+// argument group, rendered with the provided project arguments.
+func newArgumentGroupsStencil(t *testing.T, group *configuration.ArgumentGroup, args map[string]any) *Stencil {
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+		Arguments: map[string]configuration.Argument{
+			"useRedis":     {Schema: map[string]any{"type": "boolean"}},
+			"useMemcached": {Schema: map[string]any{"type": "boolean"}},
+			"useInMemory":  {Schema: map[string]any{"type": "boolean"}, Default: false},
+		},
+		ArgumentGroups: []*configuration.ArgumentGroup{group},
+	})
+	assert.NilError(t, err)
+
+	mf := &configuration.Manifest{Name: "test", Arguments: args}
+	return NewStencil(mf, nil, []*modules.Module{m}, slogext.NewTestLogger(t), false)
+}
+
+func TestValidateArgumentGroupsExclusive(t *testing.T) {
+	group := &configuration.ArgumentGroup{
+		Arguments: []string{"useRedis", "useMemcached", "useInMemory"},
+		Exclusive: true,
+	}
+
+	st := newArgumentGroupsStencil(t, group, map[string]any{"useRedis": true, "useMemcached": true})
+	err := st.ValidateArgumentGroups()
+	assert.ErrorContains(t, err, `module "testing" allows only one of the arguments "useRedis", "useMemcached", "useInMemory" `+
+		`to be set, but "useRedis", "useMemcached" are set`)
+
+	// Arguments set to an empty value don't count as set.
+	st = newArgumentGroupsStencil(t, group, map[string]any{"useRedis": true, "useMemcached": false})
+	assert.NilError(t, st.ValidateArgumentGroups())
+}
+
+func TestValidateArgumentGroupsAtLeastOne(t *testing.T) {
+	group := &configuration.ArgumentGroup{
+		Arguments:  []string{"useRedis", "useMemcached", "useInMemory"},
+		AtLeastOne: true,
+	}
+
+	st := newArgumentGroupsStencil(t, group, map[string]any{"useMemcached": false})
+	err := st.ValidateArgumentGroups()
+	assert.ErrorContains(t, err, `module "testing" requires at least one of the arguments `+
+		`"useRedis", "useMemcached", "useInMemory" to be set`)
+
+	st = newArgumentGroupsStencil(t, group, map[string]any{"useMemcached": true})
+	assert.NilError(t, st.ValidateArgumentGroups())
+}
+
+func TestValidateArgumentGroupsUnknownArgument(t *testing.T) {
+	group := &configuration.ArgumentGroup{Arguments: []string{"useRedis", "useEtcd"}, Exclusive: true}
+
+	st := newArgumentGroupsStencil(t, group, nil)
+	err := st.ValidateArgumentGroups()
+	assert.ErrorContains(t, err, `module "testing" declares an argument group with unknown argument "useEtcd"`)
+	assert.Assert(t, !strings.Contains(err.Error(), "allows only one"), err.Error())
+}
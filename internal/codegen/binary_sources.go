@@ -0,0 +1,91 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for creating binary templates
+// whose contents are selected from the files of a module.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// binarySourceTemplates returns a binary template for every entry in
+// the binarySources of the provided module (see
+// [configuration.TemplateRepositoryManifest.BinarySources]), with the
+// contents of the files selected by rendering the entry. fs is the
+// filesystem of the module, the files are read from it.
+func (s *Stencil) binarySourceTemplates(m *modules.Module, fs billy.Filesystem, vals *Values,
+	log slogext.Logger) ([]*Template, error) {
+	tpls := make([]*Template, 0, len(m.Manifest.BinarySources))
+	for _, dest := range slices.Sorted(maps.Keys(m.Manifest.BinarySources)) {
+		rt, err := NewTemplate(m, "binarySource", 0o000, time.Time{}, []byte(m.Manifest.BinarySources[dest]), s.log, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rt.Render(s, vals); err != nil {
+			return nil, fmt.Errorf("failed to render binarySources for %q in module %q: %w", dest, m.Name, err)
+		}
+
+		var contents bytes.Buffer
+		var found bool
+		for _, src := range strings.Split(rt.Files[0].String(), "\n") {
+			src = strings.TrimSpace(src)
+			if src == "" {
+				continue
+			}
+
+			if !filepath.IsLocal(src) {
+				return nil, fmt.Errorf("binarySources for %q in module %q selected %q, which is not inside of the module",
+					dest, m.Name, src)
+			}
+
+			b, err := util.ReadFile(fs, src)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %q selected by binarySources for %q in module %q: %w",
+					src, dest, m.Name, err)
+			}
+			contents.Write(b)
+			found = true
+		}
+		if !found {
+			log.Debugf("Skipping binary source %q, binarySources rendered to an empty string", dest)
+			continue
+		}
+
+		tpl, err := NewTemplate(m, dest, 0o644, time.Time{}, contents.Bytes(), log, &NewTemplateOpts{
+			Adopt:  s.adoptMode,
+			Binary: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create template %q from module %q: %w", dest, m.Name, err)
+		}
+		tpls = append(tpls, tpl)
+	}
+	return tpls, nil
+}
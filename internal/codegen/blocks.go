@@ -25,7 +25,9 @@ import (
 	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -47,6 +49,65 @@ var blockPattern = regexp.MustCompile(`^\s*(///|###|<!---)\s*([a-zA-Z ]+)\(([a-z
 // For unit testing of this regex and explanation, see https://regex101.com/r/EHkH5O/1
 var v2BlockPattern = regexp.MustCompile(`^\s*(//|##|--|<!--)\s{0,1}<<(/?)Stencil::([a-zA-Z ]+)(\([a-zA-Z0-9 -]+\))?>>`)
 
+// adoptAnchorPattern is the regex for parsing adopt anchors, which tell
+// adoptBlocks which lines surround a block in existing files, e.g.:
+//
+//	{{- /* <<Stencil::AdoptAfter(version)>> "  deploymentEnvironment: prod" */}}
+//	{{- /* <<Stencil::AdoptBefore(version)>> "somechart:" */}}
+//
+// The line is a quoted Go string so that anchors can be written inside
+// of any kind of comment.
+var adoptAnchorPattern = regexp.MustCompile(`<<Stencil::Adopt(After|Before)\(([a-zA-Z0-9 -]+)\)>>\s*("(?:[^"\\]|\\.)*")`)
+
+// adoptAnchor contains the lines that surround a block in existing
+// files, as declared by adopt anchors in its template.
+type adoptAnchor struct {
+	// After is the line directly before the contents of the block.
+	After string
+
+	// Before is the line directly after the contents of the block.
+	Before string
+}
+
+// v2BlockPatterns caches the v2 block patterns created by
+// v2BlockPatternFor, keyed by their additional comment prefixes.
+var v2BlockPatterns sync.Map
+
+// v2BlockPatternFor returns v2BlockPattern with the provided comment
+// prefixes (e.g., ";" or "REM") recognized in addition to the default
+// ones.
+func v2BlockPatternFor(prefixes []string) *regexp.Regexp {
+	if len(prefixes) == 0 {
+		return v2BlockPattern
+	}
+
+	key := strings.Join(prefixes, "\x00")
+	if re, ok := v2BlockPatterns.Load(key); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	alts := []string{"//", "##", "--", "<!--"}
+	for _, p := range prefixes {
+		alts = append(alts, regexp.QuoteMeta(p))
+	}
+	re := regexp.MustCompile(`^\s*(` + strings.Join(alts, "|") + `)\s{0,1}<<(/?)Stencil::([a-zA-Z ]+)(\([a-zA-Z0-9 -]+\))?>>`)
+	v2BlockPatterns.Store(key, re)
+	return re
+}
+
+// blockCommentPrefixes returns the additional block comment prefixes
+// declared by the module of the provided template, if any.
+func blockCommentPrefixes(t *Template) []string {
+	if t == nil || t.Module == nil || t.Module.Manifest == nil {
+		return nil
+	}
+	return t.Module.Manifest.BlockCommentPrefixes
+}
+
+// maxBlockLineSize is the maximum size of a single line in a file that
+// blocks are parsed from.
+const maxBlockLineSize = 10 * 1024 * 1024
+
 // parseBlocks reads the blocks from an existing file, potentially adopting blocks based on the source template,
 // if so specified
 func parseBlocks(filePath string, sourceTemplate *Template) (map[string]*blockInfo, error) {
@@ -58,16 +119,35 @@ func parseBlocks(filePath string, sourceTemplate *Template) (map[string]*blockIn
 	}
 	defer f.Close()
 
-	return parseBlocksInner(f, filePath, sourceTemplate)
+	// Directories (e.g., the target of a symlink created with
+	// file.Symlink) don't contain any blocks.
+	if inf, err := f.Stat(); err == nil && inf.IsDir() {
+		return make(map[string]*blockInfo), nil
+	}
+
+	return parseBlocksInner(f, filePath, sourceTemplate, blockCommentPrefixes(sourceTemplate))
 }
 
 // parseBlocksInner is the inner implementation of parseBlocks, reusable from inside adoptBlocks to parse blocks
 // from the source template contents
+//
+// On success, every returned block starts before it ends, and parsed
+// (non-adopted) blocks never overlap. Anything else (unbalanced or
+// nested blocks, lines over maxBlockLineSize) results in an error.
+//
+// prefixes are the comment prefixes recognized in front of v2 blocks in
+// addition to the default ones, see v2BlockPatternFor.
 // nolint:funlen // Why: Will refactor in the future.
-func parseBlocksInner(r io.ReadSeeker, filePath string, sourceTemplate *Template) (map[string]*blockInfo, error) {
+func parseBlocksInner(r io.ReadSeeker, filePath string, sourceTemplate *Template, prefixes []string) (map[string]*blockInfo, error) {
+	v2Pattern := v2BlockPatternFor(prefixes)
 	blocks := make(map[string]*blockInfo)
 	var curBlock *blockInfo
+
+	// curContents contains the contents of curBlock, this is built
+	// separately to avoid re-allocating the contents on every line.
+	var curContents strings.Builder
 	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxBlockLineSize)
 	for i := 0; scanner.Scan(); i++ {
 		line := scanner.Text()
 		matches := blockPattern.FindStringSubmatch(line)
@@ -77,7 +157,7 @@ func parseBlocksInner(r io.ReadSeeker, filePath string, sourceTemplate *Template
 			// 2: / if end of block
 			// 3: block name
 			// 4: block args, if present
-			v2Matches := v2BlockPattern.FindStringSubmatch(line)
+			v2Matches := v2Pattern.FindStringSubmatch(line)
 			if len(v2Matches) == 5 {
 				cmd := v2Matches[3]
 				if v2Matches[2] == "/" {
@@ -149,7 +229,9 @@ func parseBlocksInner(r io.ReadSeeker, filePath string, sourceTemplate *Template
 				}
 
 				curBlock.EndLine = i
+				curBlock.Contents = curContents.String()
 				curBlock = nil
+				curContents.Reset()
 			default:
 				isCommand = false
 			}
@@ -165,11 +247,14 @@ func parseBlocksInner(r io.ReadSeeker, filePath string, sourceTemplate *Template
 		// and account for having an existing curVal or not. If we
 		// don't then we assign curVal to start with the line we
 		// just found.
-		if curBlock.Contents != "" {
-			curBlock.Contents += "\n" + line
-		} else {
-			curBlock.Contents = line
+		if curContents.Len() != 0 {
+			curContents.WriteString("\n")
 		}
+		curContents.WriteString(line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read blocks from %s", filePath)
 	}
 
 	if curBlock != nil {
@@ -190,7 +275,13 @@ func parseBlocksInner(r io.ReadSeeker, filePath string, sourceTemplate *Template
 // adoptBlocks adopts the blocks from the source template into the existing blocks
 func adoptBlocks(r io.ReadSeeker, blocks map[string]*blockInfo, sourceTemplate *Template) (map[string]*blockInfo, error) {
 	tr := bytes.NewReader(sourceTemplate.Contents)
-	templateBlocks, err := parseBlocksInner(tr, sourceTemplate.Path, nil)
+	prefixes := blockCommentPrefixes(sourceTemplate)
+	templateBlocks, err := parseBlocksInner(tr, sourceTemplate.Path, nil, prefixes)
+	if err != nil {
+		return nil, err
+	}
+	v2Pattern := v2BlockPatternFor(prefixes)
+	anchors, err := parseAdoptAnchors(sourceTemplate.Contents, sourceTemplate.Path)
 	if err != nil {
 		return nil, err
 	}
@@ -213,6 +304,15 @@ func adoptBlocks(r io.ReadSeeker, blocks map[string]*blockInfo, sourceTemplate *
 			continue
 		}
 
+		// Anchors are preferred over the heuristic below, which is only
+		// used when they don't match exactly one place in the file.
+		if a, ok := anchors[k]; ok {
+			if b := adoptAnchoredBlock(fileLines, k, a); b != nil {
+				blocks[k] = b
+				continue
+			}
+		}
+
 		for numLines := 1; ; numLines++ {
 			if v.StartLine-numLines < 0 || v.EndLine+numLines >= len(templateLines) {
 				break
@@ -222,9 +322,9 @@ func adoptBlocks(r io.ReadSeeker, blocks map[string]*blockInfo, sourceTemplate *
 
 			// Make sure none of the pre or post lines are block start/ends
 			if slices.IndexFunc(preLines, func(x string) bool {
-				return blockPattern.MatchString(x) || v2BlockPattern.MatchString(x)
+				return blockPattern.MatchString(x) || v2Pattern.MatchString(x)
 			}) != -1 || slices.IndexFunc(postLines, func(x string) bool {
-				return blockPattern.MatchString(x) || v2BlockPattern.MatchString(x)
+				return blockPattern.MatchString(x) || v2Pattern.MatchString(x)
 			}) != -1 {
 				continue
 			}
@@ -237,14 +337,14 @@ func adoptBlocks(r io.ReadSeeker, blocks map[string]*blockInfo, sourceTemplate *
 				break
 			}
 			// Filter out any posts before the first pre
-			_ = slices.DeleteFunc(postPositions, func(x int) bool {
+			postPositions = slices.DeleteFunc(postPositions, func(x int) bool {
 				return x < prePositions[0]+numLines
 			})
 			if len(postPositions) == 0 {
 				break
 			}
 			// Filter out any pres before the last post
-			_ = slices.DeleteFunc(prePositions, func(x int) bool {
+			prePositions = slices.DeleteFunc(prePositions, func(x int) bool {
 				return x > postPositions[len(postPositions)-1]-numLines
 			})
 			if len(prePositions) == 0 {
@@ -284,6 +384,75 @@ func adoptBlocks(r io.ReadSeeker, blocks map[string]*blockInfo, sourceTemplate *
 	return blocks, nil
 }
 
+// parseAdoptAnchors returns the adopt anchors (see adoptAnchorPattern)
+// in the provided template contents, keyed by the name of the block.
+// Every anchored block must have both an AdoptAfter and an AdoptBefore
+// anchor.
+func parseAdoptAnchors(contents []byte, filePath string) (map[string]*adoptAnchor, error) {
+	anchors := make(map[string]*adoptAnchor)
+	for i, line := range strings.Split(string(contents), "\n") {
+		for _, m := range adoptAnchorPattern.FindAllStringSubmatch(line, -1) {
+			anchorLine, err := strconv.Unquote(m[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid Adopt%s anchor for block %q, at %s:%d: %w", m[1], m[2], filePath, i+1, err)
+			}
+
+			a, ok := anchors[m[2]]
+			if !ok {
+				a = &adoptAnchor{}
+				anchors[m[2]] = a
+			}
+
+			field := &a.After
+			if m[1] == "Before" {
+				field = &a.Before
+			}
+			if *field != "" {
+				return nil, fmt.Errorf("duplicate Adopt%s anchor for block %q, at %s:%d", m[1], m[2], filePath, i+1)
+			}
+			*field = anchorLine
+		}
+	}
+
+	for name, a := range anchors {
+		if a.After == "" || a.Before == "" {
+			return nil, fmt.Errorf("block %q in %s must have both an AdoptAfter and an AdoptBefore anchor", name, filePath)
+		}
+	}
+	return anchors, nil
+}
+
+// adoptAnchoredBlock returns the block surrounded by the lines of the
+// provided anchor in fileLines. nil is returned unless the anchor
+// matches exactly one place in the file.
+func adoptAnchoredBlock(fileLines []string, name string, a *adoptAnchor) *blockInfo {
+	var found *blockInfo
+	for start, line := range fileLines {
+		if strings.TrimSuffix(line, "\r") != a.After {
+			continue
+		}
+
+		end := slices.IndexFunc(fileLines[start+1:], func(x string) bool {
+			return strings.TrimSuffix(x, "\r") == a.Before
+		})
+		if end == -1 {
+			continue
+		}
+		end += start + 1
+
+		if found != nil {
+			return nil
+		}
+		found = &blockInfo{
+			Name:      name,
+			StartLine: start,
+			EndLine:   end,
+			Contents:  strings.Join(fileLines[start+1:end], "\n"),
+		}
+	}
+	return found
+}
+
 func findSubsetPositions(haystack, needles []string) []int {
 	res := []int{}
 	for i := 0; i < len(haystack)-len(needles)+1; i++ {
@@ -1,8 +1,12 @@
 package codegen
 
 import (
+	"bytes"
 	"context"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -132,6 +136,36 @@ func TestAdoptWithBadBlock(t *testing.T) {
 	assert.Equal(t, *blocks["version"], exp, "expected parseBlocks() to parse wacky version block")
 }
 
+// Same as TestAdoptWithBadBlock, but the template's adopt anchors point
+// at the right block.
+func TestAdoptWithAnchors(t *testing.T) {
+	blocks := adoptTestHelper(t, "testdata/adopt/adoptanchor1.tpl", "testdata/adopt/adoptanchor1.yaml")
+	exp := blockInfo{
+		Name:      "version",
+		StartLine: 5,
+		EndLine:   7,
+		Contents:  "  version: abc",
+	}
+	assert.Equal(t, *blocks["version"], exp, "expected parseBlocks() to use the adopt anchors")
+}
+
+func TestAdoptAnchorsAmbiguousFallsBack(t *testing.T) {
+	anchors, err := parseAdoptAnchors([]byte(`{{/* <<Stencil::AdoptAfter(a)>> "x" */}}`+"\n"+
+		`{{/* <<Stencil::AdoptBefore(a)>> "y" */}}`), "test.tpl")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, anchors, map[string]*adoptAnchor{"a": {After: "x", Before: "y"}})
+
+	assert.Assert(t, adoptAnchoredBlock([]string{"x", "1", "y", "x", "2", "y"}, "a", anchors["a"]) == nil,
+		"expected anchors matching twice to not adopt a block")
+	assert.DeepEqual(t, adoptAnchoredBlock([]string{"x", "1", "y", "z"}, "a", anchors["a"]),
+		&blockInfo{Name: "a", StartLine: 0, EndLine: 2, Contents: "1"})
+}
+
+func TestAdoptAnchorsRequireBothSides(t *testing.T) {
+	_, err := parseAdoptAnchors([]byte(`{{/* <<Stencil::AdoptAfter(a)>> "x" */}}`), "test.tpl")
+	assert.ErrorContains(t, err, `block "a" in test.tpl must have both an AdoptAfter and an AdoptBefore anchor`)
+}
+
 func adoptTestHelper(t *testing.T, templateFile, targetFile string) map[string]*blockInfo {
 	fs, err := testmemfs.WithManifest("name: testing\n")
 	assert.NilError(t, err, "failed to testmemfs.WithManifest")
@@ -150,3 +184,135 @@ func adoptTestHelper(t *testing.T, templateFile, targetFile string) map[string]*
 	assert.NilError(t, err, "expected parseBlocks() not to fail")
 	return blocks
 }
+
+func TestParseBlocksLongLines(t *testing.T) {
+	// Lines longer than bufio.Scanner's default 64KiB token size should
+	// not be silently dropped.
+	long := strings.Repeat("a", 128*1024)
+	contents := "## <<Stencil::Block(long)>>\n" + long + "\n## <</Stencil::Block>>\n"
+	blocks, err := parseBlocksInner(strings.NewReader(contents), "long.txt", nil, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, blocks["long"].Contents, long)
+
+	tooLong := strings.Repeat("a", maxBlockLineSize+1)
+	_, err = parseBlocksInner(strings.NewReader(tooLong), "too-long.txt", nil, nil)
+	assert.ErrorContains(t, err, "failed to read blocks from too-long.txt")
+}
+
+func TestParseBlocksCustomCommentPrefixes(t *testing.T) {
+	contents := strings.Join([]string{
+		"[server]",
+		"; <<Stencil::Block(settings)>>",
+		"port = 8080",
+		"; <</Stencil::Block>>",
+		"REM <<Stencil::Block(script)>>",
+		"echo hello",
+		"REM <</Stencil::Block>>",
+		"## <<Stencil::Block(defaults)>>",
+		"still works",
+		"## <</Stencil::Block>>",
+	}, "\n")
+
+	blocks, err := parseBlocksInner(strings.NewReader(contents), "config.ini", nil, []string{";", "REM"})
+	assert.NilError(t, err)
+	assert.Equal(t, blocks["settings"].Contents, "port = 8080")
+	assert.Equal(t, blocks["script"].Contents, "echo hello")
+	assert.Equal(t, blocks["defaults"].Contents, "still works")
+
+	// Without the prefixes, only the default ones are recognized.
+	blocks, err = parseBlocksInner(strings.NewReader(contents), "config.ini", nil, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, slices.Sorted(maps.Keys(blocks)), []string{"defaults"})
+}
+
+func TestParseBlocksModuleCommentPrefixes(t *testing.T) {
+	fs, err := testmemfs.WithManifest("name: testing\nblockCommentPrefixes: [\";\"]\n")
+	assert.NilError(t, err, "failed to testmemfs.WithManifest")
+	m, err := modulestest.NewWithFS(context.Background(), "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	tpl, err := NewTemplate(m, "config.ini.tpl", 0o644, time.Now(), nil, slogext.NewTestLogger(t), nil)
+	assert.NilError(t, err, "failed to NewTemplate")
+
+	target := t.TempDir() + "/config.ini"
+	assert.NilError(t, os.WriteFile(target, []byte("; <<Stencil::Block(settings)>>\nport = 8080\n; <</Stencil::Block>>\n"), 0o644))
+
+	blocks, err := parseBlocks(target, tpl)
+	assert.NilError(t, err)
+	assert.Equal(t, blocks["settings"].Contents, "port = 8080")
+}
+
+// FuzzParseBlocksInner ensures that parseBlocksInner never panics and
+// that, when it succeeds, the blocks it returns are well-formed: they
+// start before they end, don't overlap and don't reach past the end of
+// the input. Malformed input must result in an error instead.
+func FuzzParseBlocksInner(f *testing.F) {
+	for _, file := range []string{
+		"testdata/blocks-test.txt",
+		"testdata/v2blocks-test.txt",
+		"testdata/v2blocks-invalid.txt",
+		"testdata/danglingblock-test.txt",
+		"testdata/blockinsideblock-test.txt",
+	} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b, []byte{})
+	}
+	for _, name := range []string{"adopt1", "adopt2", "adopt3", "adopt4", "adoptbad1", "adoptanchor1"} {
+		b, err := os.ReadFile("testdata/adopt/" + name + ".yaml")
+		if err != nil {
+			f.Fatal(err)
+		}
+		tpl, err := os.ReadFile("testdata/adopt/" + name + ".tpl")
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b, tpl)
+	}
+	f.Add(
+		[]byte("a\n## <<Stencil::Block(x)>>\nb\n## <</Stencil::Block>>\nc\n"),
+		[]byte("a\nb\nc\n"),
+	)
+	f.Add([]byte("b\nb\nb\n"), []byte("b\n## <<Stencil::Block(x)>>\n## <</Stencil::Block>>\nb\nb\n"))
+
+	f.Fuzz(func(t *testing.T, contents, tplContents []byte) {
+		var tpl *Template
+		if len(tplContents) != 0 {
+			tpl = &Template{Path: "fuzz.tpl", Contents: tplContents, adoptMode: true}
+		}
+
+		blocks, err := parseBlocksInner(bytes.NewReader(contents), "fuzz.txt", tpl, nil)
+		if err != nil {
+			return
+		}
+
+		// Adopted blocks are found through heuristics and may overlap,
+		// so only check the invariants of parsed blocks.
+		if tpl != nil {
+			for name, b := range blocks {
+				if b.StartLine >= b.EndLine {
+					t.Fatalf("block %q starts (%d) after it ends (%d)", name, b.StartLine, b.EndLine)
+				}
+			}
+			return
+		}
+
+		numLines := bytes.Count(contents, []byte("\n")) + 1
+		parsed := slices.SortedFunc(maps.Values(blocks), func(a, b *blockInfo) int {
+			return a.StartLine - b.StartLine
+		})
+		for i, b := range parsed {
+			if b.StartLine >= b.EndLine {
+				t.Fatalf("block %q starts (%d) after it ends (%d)", b.Name, b.StartLine, b.EndLine)
+			}
+			if b.EndLine >= numLines {
+				t.Fatalf("block %q ends (%d) past the end of the input (%d lines)", b.Name, b.EndLine, numLines)
+			}
+			if i > 0 && parsed[i-1].EndLine >= b.StartLine {
+				t.Fatalf("block %q overlaps with block %q", b.Name, parsed[i-1].Name)
+			}
+		}
+	})
+}
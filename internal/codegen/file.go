@@ -16,14 +16,31 @@
 package codegen
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"time"
 
 	"go.rgst.io/stencil/v2/pkg/slogext"
+	"go.rgst.io/stencil/v2/pkg/stencil"
+	"golang.org/x/text/encoding"
 )
 
+// chown changes the owner of a file, this is a variable so that it can
+// be replaced in tests.
+var chown = os.Chown
+
+// supportsSymlinks denotes if symlinks can be created on the current
+// platform, this is a variable so that it can be replaced in tests.
+var supportsSymlinks = runtime.GOOS != "windows"
+
+// fileOwner is the owner and group of a file
+type fileOwner struct {
+	UID, GID int
+}
+
 // _ ensures that we implement the os.FileInfo interface
 var _ os.FileInfo = &File{}
 
@@ -51,6 +68,54 @@ type File struct {
 	// sourceTemplate is the template that is currently acting on this file
 	sourceTemplate *Template
 
+	// owner is the owner to set on the file when it is written, if nil
+	// the owner is not changed.
+	owner *fileOwner
+
+	// changed denotes if this file was created, or updated with
+	// different contents, when it was written, see [File.Write].
+	changed bool
+
+	// action is the action taken when this file was written, see
+	// [File.Action].
+	action FileAction
+
+	// migratedTo is the path this file was migrated to with
+	// file.MigrateTo, if any.
+	migratedTo string
+
+	// allowEmpty denotes that this file is allowed to be empty, which
+	// otherwise causes a warning when it is written.
+	allowEmpty bool
+
+	// untracked denotes that this file is not stored in the lockfile,
+	// see [TplFile.Untrack].
+	untracked bool
+
+	// symlinkTarget is the target of the symlink this file is written
+	// as, if set the contents of the file are not used. See
+	// [TplFile.Symlink].
+	symlinkTarget string
+
+	// encoding is the encoding the contents of the file are transcoded
+	// to when it is written, if nil they are written as-is (UTF-8). See
+	// [File.SetEncoding].
+	encoding encoding.Encoding
+
+	// blocksEncoded denotes that the blocks were read from the existing
+	// file, so their contents are in its encoding rather than UTF-8.
+	// They are decoded when read, see [File.Block].
+	blocksEncoded bool
+
+	// blockTemplates contains the import path of the template that
+	// rendered the contents of each block, keyed by the name of the
+	// block, see [File.RenderedBlocks].
+	blockTemplates map[string]string
+
+	// blockReadUndecoded denotes that a block was read before the
+	// encoding of the file was set, see [File.SetEncoding].
+	blockReadUndecoded bool
+
 	// Below are public fields that are useful for determining
 	// how to process this file.
 
@@ -79,16 +144,58 @@ func NewFile(path string, mode os.FileMode, modTime time.Time, sourceTemplate *T
 		return nil, err
 	}
 
-	return &File{path: path, mode: mode, modTime: modTime, blocks: blocks, sourceTemplate: sourceTemplate}, nil
+	return &File{
+		path: path, mode: mode, modTime: modTime, blocks: blocks, blocksEncoded: len(blocks) != 0,
+		sourceTemplate: sourceTemplate,
+	}, nil
 }
 
-// Block returns the contents of a given block.
+// recoverBlocksFromLockfile restores the blocks of this file from the
+// blocks stored in the lockfile for its module if the file does not
+// exist on disk. This allows blocks to survive a file, or the template
+// generating it, being renamed. See [configuration.Manifest.LockBlocks].
+func (f *File) recoverBlocksFromLockfile(lock *stencil.Lockfile) {
+	if lock == nil || f.sourceTemplate == nil {
+		return
+	}
+
+	// Blocks on disk always take precedence.
+	if _, err := os.Stat(f.path); err == nil {
+		return
+	}
+
+	blocks := lock.BlocksForModule(f.sourceTemplate.Module.Name)
+	for name, contents := range blocks {
+		if _, ok := f.blocks[name]; ok {
+			continue
+		}
+		f.blocks[name] = &blockInfo{Name: name, Contents: contents}
+	}
+}
+
+// Block returns the contents of a given block, decoded to UTF-8 if an
+// encoding was set (see [File.SetEncoding]).
 func (f *File) Block(name string) string {
 	bi, ok := f.blocks[name]
 	if !ok {
 		return ""
 	}
-	return bi.Contents
+
+	if !f.blocksEncoded {
+		return bi.Contents
+	}
+	if f.encoding == nil {
+		f.blockReadUndecoded = true
+		return bi.Contents
+	}
+
+	// Decoding errors are returned by SetEncoding, which decodes every
+	// block once to check for them.
+	contents, err := f.encoding.NewDecoder().String(bi.Contents)
+	if err != nil {
+		return bi.Contents
+	}
+	return contents
 }
 
 // AddDeprecationNotice adds a deprecation notice to a file
@@ -109,6 +216,7 @@ func (f *File) SetPath(path string) error {
 		return err
 	}
 	f.blocks = blocks
+	f.blocksEncoded = len(blocks) != 0
 	f.path = path
 
 	return nil
@@ -119,11 +227,86 @@ func (f *File) SetMode(mode os.FileMode) {
 	f.mode = mode
 }
 
+// SetOwner sets the owner (uid) and group (gid) of the file when it is
+// written. A value of -1 leaves the respective id unchanged.
+func (f *File) SetOwner(uid, gid int) {
+	f.owner = &fileOwner{UID: uid, GID: gid}
+}
+
+// AllowEmpty marks the file as allowed to be empty, which disables the
+// warning logged when an empty file is written.
+func (f *File) AllowEmpty() {
+	f.allowEmpty = true
+}
+
+// SetSymlink marks the file as a symlink to the provided target, which
+// is written instead of its contents.
+func (f *File) SetSymlink(target string) {
+	f.symlinkTarget = target
+}
+
+// SetEncoding sets the encoding that the contents of the file are
+// transcoded to, from UTF-8, when it is written. Binary files are
+// written as-is. As the existing file is in the provided encoding, the
+// contents of the blocks read from it are decoded to UTF-8 when read,
+// so this must be called before any block is read.
+func (f *File) SetEncoding(enc encoding.Encoding) error {
+	if f.blockReadUndecoded {
+		return fmt.Errorf("the encoding of %q must be set before reading its blocks", f.path)
+	}
+
+	if f.blocksEncoded {
+		dec := enc.NewDecoder()
+		for _, bi := range f.blocks {
+			if _, err := dec.String(bi.Contents); err != nil {
+				return fmt.Errorf("failed to decode block %q of %q: %w", bi.Name, f.path, err)
+			}
+		}
+	}
+
+	f.encoding = enc
+	return nil
+}
+
 // SetContents updates the contents of the current file
 func (f *File) SetContents(contents string) {
 	f.contents = []byte(contents)
 }
 
+// setBlockTemplate records the import path of the template that
+// rendered the contents of the provided block.
+func (f *File) setBlockTemplate(name, importPath string) {
+	if f.blockTemplates == nil {
+		f.blockTemplates = make(map[string]string)
+	}
+	f.blockTemplates[name] = importPath
+}
+
+// RenderedBlocks returns the names of the blocks in the rendered
+// contents of this file, mapped to the import path of the template that
+// rendered them. This is used to debug which template wrote a block.
+// Blocks whose contents weren't rendered with file.Block (or
+// file.AppendBlock) are attributed to the template of the file.
+func (f *File) RenderedBlocks() (map[string]string, error) {
+	blocks, err := parseBlocksInner(bytes.NewReader(f.contents), f.path, nil, blockCommentPrefixes(f.sourceTemplate))
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]string, len(blocks))
+	for name := range blocks {
+		switch {
+		case f.blockTemplates[name] != "":
+			owners[name] = f.blockTemplates[name]
+		case f.sourceTemplate != nil:
+			owners[name] = f.sourceTemplate.ImportPath()
+		default:
+			owners[name] = ""
+		}
+	}
+	return owners, nil
+}
+
 // Bytes returns the contents of this file as bytes
 func (f *File) Bytes() []byte {
 	return f.contents
@@ -169,32 +352,96 @@ func (f *File) Sys() interface{} {
 	return nil
 }
 
+// FileAction is the action taken on a file when it was written, see
+// [File.Write].
+type FileAction string
+
+// This block contains all of the FileAction values
+const (
+	// FileActionCreated is used for files that didn't exist before
+	FileActionCreated FileAction = "Created"
+
+	// FileActionUpdated is used for files that already existed, their
+	// contents may be unchanged, see [File.Changed]
+	FileActionUpdated FileAction = "Updated"
+
+	// FileActionSkipped is used for files that were skipped
+	FileActionSkipped FileAction = "Skipped"
+
+	// FileActionDeleted is used for files that were deleted
+	FileActionDeleted FileAction = "Deleted"
+)
+
+// Action returns the action taken when this file was written, or an
+// empty string if it hasn't been written.
+func (f *File) Action() FileAction {
+	return f.action
+}
+
+// Changed returns true if this file was created, or updated with
+// different contents, when it was written.
+func (f *File) Changed() bool {
+	return f.changed
+}
+
 // Write writes a [codegen.File] to disk based on its current state, logging appropriately
 func (f *File) Write(log slogext.Logger, dryRun bool) error {
-	action := "Created"
+	action := FileActionCreated
 	if f.Deleted {
-		action = "Deleted"
+		action = FileActionDeleted
 
 		if !dryRun {
 			os.Remove(f.Name())
 		}
 	} else if f.Skipped {
-		action = "Skipped"
+		action = FileActionSkipped
+	} else if f.symlinkTarget != "" {
+		return f.writeSymlink(log, dryRun)
 	} else if _, err := os.Stat(f.Name()); err == nil {
-		action = "Updated"
+		action = FileActionUpdated
 	}
 
-	if action == "Created" || action == "Updated" {
+	if action == FileActionCreated || action == FileActionUpdated {
+		// An empty file is usually the result of a bug in a template
+		// (e.g., everything being trimmed), binary files are copied
+		// as-is so they are never warned about.
+		binary := f.sourceTemplate != nil && f.sourceTemplate.Binary
+		if len(f.Bytes()) == 0 && !f.allowEmpty && !binary {
+			log.With("path", f.Name()).
+				Warn("Generated file is empty, use file.AllowEmpty in its template if this is intended")
+		}
+
+		contents := f.Bytes()
+		if f.encoding != nil && !binary {
+			var err error
+			if contents, err = f.encoding.NewEncoder().Bytes(contents); err != nil {
+				return fmt.Errorf("failed to encode file %q: %w", f.Name(), err)
+			}
+		}
+
+		// Files that already exist are only changed if their contents
+		// differ from what's being written.
+		changed := true
+		if action == FileActionUpdated {
+			if existing, err := os.ReadFile(f.Name()); err == nil && bytes.Equal(existing, contents) {
+				changed = false
+			}
+		}
+
 		if !dryRun {
 			if err := os.MkdirAll(filepath.Dir(f.Name()), 0o755); err != nil {
 				return fmt.Errorf("failed to create directory %q: %w", filepath.Dir(f.Name()), err)
 			}
 
-			if err := os.WriteFile(f.Name(), f.Bytes(), f.Mode()); err != nil {
+			if err := writeFileAtomic(f.Name(), contents, f.Mode()); err != nil {
 				return fmt.Errorf("failed to write file %q: %w", f.Name(), err)
 			}
+
+			f.applyOwner(log)
 		}
+		f.changed = changed
 	}
+	f.action = action
 
 	msg := fmt.Sprintf("  -> %s %s", action, f.Name())
 	if dryRun {
@@ -209,3 +456,104 @@ func (f *File) Write(log slogext.Logger, dryRun bool) error {
 	}
 	return nil
 }
+
+// writeSymlink writes the file as a symlink to its symlinkTarget,
+// replacing any existing file or symlink at its path. On platforms
+// without symlink support, a warning is logged instead.
+func (f *File) writeSymlink(log slogext.Logger, dryRun bool) error {
+	if !supportsSymlinks {
+		log.With("path", f.Name(), "target", f.symlinkTarget).
+			Warn("Skipping creating symlink, not supported on this platform")
+		f.action = FileActionSkipped
+		return nil
+	}
+
+	action := FileActionCreated
+	changed := true
+	if _, err := os.Lstat(f.Name()); err == nil {
+		action = FileActionUpdated
+		if target, err := os.Readlink(f.Name()); err == nil && target == f.symlinkTarget {
+			changed = false
+		}
+	}
+
+	if !dryRun && changed {
+		if err := os.MkdirAll(filepath.Dir(f.Name()), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %q: %w", filepath.Dir(f.Name()), err)
+		}
+
+		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove existing file %q: %w", f.Name(), err)
+		}
+
+		if err := os.Symlink(f.symlinkTarget, f.Name()); err != nil {
+			return fmt.Errorf("failed to create symlink %q: %w", f.Name(), err)
+		}
+	}
+	f.changed = changed
+	f.action = action
+
+	msg := fmt.Sprintf("  -> %s %s -> %s", action, f.Name(), f.symlinkTarget)
+	if dryRun {
+		msg += " (dry-run)"
+	}
+	log.Info(msg)
+	return nil
+}
+
+// writeFileAtomic writes data to the file at the provided path by
+// writing it to a temporary file in the same directory and renaming it
+// into place, so that an interrupted write never leaves a partially
+// written file behind. Like [os.WriteFile], the mode of an existing
+// file is preserved and perm is only used for new files. Symlinks are
+// followed, the file they point to is replaced.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(name); err == nil {
+		name = resolved
+	}
+
+	mode := perm
+	if inf, err := os.Stat(name); err == nil {
+		mode = inf.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".stencil-*")
+	if err != nil {
+		return err
+	}
+	// Clean up the temporary file if we fail before renaming it.
+	defer os.Remove(tmp.Name()) //nolint:errcheck // Why: Best effort.
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// applyOwner sets the owner of the file on disk, if one was set. This is
+// best-effort: failures (e.g., lacking the privileges to change the
+// owner) are logged as warnings instead of failing the run.
+func (f *File) applyOwner(log slogext.Logger) {
+	if f.owner == nil {
+		return
+	}
+
+	if runtime.GOOS == "windows" {
+		log.With("path", f.Name()).Debug("Skipping setting file owner, not supported on this platform")
+		return
+	}
+
+	if err := chown(f.Name(), f.owner.UID, f.owner.GID); err != nil {
+		log.WithError(err).With("path", f.Name(), "uid", f.owner.UID, "gid", f.owner.GID).
+			Warn("Failed to set file owner")
+	}
+}
@@ -1,11 +1,24 @@
 package codegen
 
 import (
+	"bytes"
+	"context"
 	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"golang.org/x/text/encoding/charmap"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
 )
 
 func TestFile_Size(t *testing.T) {
@@ -38,3 +51,181 @@ func TestFileBasic(t *testing.T) {
 	assert.Equal(t, cnts, string(f.contents), "expected SetContents() to set contents")
 	assert.Equal(t, cnts, f.String(), "expected String() to return proper contents")
 }
+
+func TestFileWriteOwnerErrorIsAWarning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("setting file owners is not supported on windows")
+	}
+
+	var calledWith []int
+	chown = func(name string, uid, gid int) error {
+		calledWith = []int{uid, gid}
+		return &os.PathError{Op: "chown", Path: name, Err: os.ErrPermission}
+	}
+	t.Cleanup(func() { chown = os.Chown })
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+
+	f, err := NewFile(filepath.Join(t.TempDir(), "owned.txt"), 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetContents("hello")
+	f.SetOwner(1234, 5678)
+
+	assert.NilError(t, f.Write(log, false), "expected a permission error to not fail the write")
+	assert.DeepEqual(t, calledWith, []int{1234, 5678})
+	assert.Assert(t, strings.Contains(buf.String(), "Failed to set file owner"), buf.String())
+
+	b, err := os.ReadFile(f.Name())
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "hello")
+}
+
+func TestFileWriteWarnsWhenEmpty(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowEmpty bool
+		wantWarn   bool
+	}{
+		{"accidentally empty", false, true},
+		{"explicitly allowed", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slogext.NewWithWriter(&buf)
+
+			f, err := NewFile(filepath.Join(t.TempDir(), "empty.txt"), 0o644, time.Now(), nil)
+			assert.NilError(t, err)
+			if tt.allowEmpty {
+				f.AllowEmpty()
+			}
+
+			assert.NilError(t, f.Write(log, false))
+			assert.Equal(t, strings.Contains(buf.String(), "Generated file is empty"), tt.wantWarn, buf.String())
+		})
+	}
+}
+
+func TestFileWriteIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	log := slogext.NewTestLogger(t)
+
+	f, err := NewFile(filepath.Join(dir, "atomic.txt"), 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetContents("first")
+	assert.NilError(t, f.Write(log, false))
+
+	f.SetContents("second")
+	assert.NilError(t, f.Write(log, false))
+
+	b, err := os.ReadFile(f.Name())
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "second")
+
+	// Only the written file should exist, no temporary files.
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.DeepEqual(t, names, []string{"atomic.txt"})
+}
+
+func TestFileWriteSymlinkUnsupported(t *testing.T) {
+	supportsSymlinks = false
+	t.Cleanup(func() { supportsSymlinks = runtime.GOOS != "windows" })
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+
+	f, err := NewFile(filepath.Join(t.TempDir(), "latest"), 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetSymlink("v2")
+	assert.NilError(t, f.Write(log, false))
+	assert.Assert(t, strings.Contains(buf.String(), "Skipping creating symlink, not supported on this platform"), buf.String())
+
+	_, err = os.Lstat(f.Name())
+	assert.Assert(t, os.IsNotExist(err), "expected no file to be created")
+}
+
+func TestFileWriteEncoding(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+	pth := filepath.Join(t.TempDir(), "legacy.conf")
+
+	// The existing file is Latin-1 encoded, including its block.
+	assert.NilError(t, os.WriteFile(pth, []byte("## <<Stencil::Block(name)>>\nJos\xe9\n## <</Stencil::Block>>\n"), 0o644))
+
+	f, err := NewFile(pth, 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	assert.NilError(t, f.SetEncoding(charmap.ISO8859_1))
+	assert.Equal(t, f.Block("name"), "José")
+
+	// Setting the encoding again doesn't decode the blocks twice.
+	assert.NilError(t, f.SetEncoding(charmap.ISO8859_1))
+	assert.Equal(t, f.Block("name"), "José")
+
+	f.SetContents("café\n" + f.Block("name") + "\n")
+	assert.NilError(t, f.Write(log, false))
+
+	b, err := os.ReadFile(pth)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, b, []byte("caf\xe9\nJos\xe9\n"))
+
+	// Characters that can't be represented in the encoding fail the
+	// write.
+	f.SetContents("snowman: ☃\n")
+	assert.ErrorContains(t, f.Write(log, false), "failed to encode file")
+}
+
+func TestFileSetEncodingAfterBlockRead(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "legacy.conf")
+	assert.NilError(t, os.WriteFile(pth, []byte("## <<Stencil::Block(name)>>\nJos\xe9\n## <</Stencil::Block>>\n"), 0o644))
+
+	f, err := NewFile(pth, 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, f.Block("name"), "Jos\xe9")
+	assert.ErrorContains(t, f.SetEncoding(charmap.ISO8859_1), "must be set before reading its blocks")
+}
+
+func TestFileRenderedBlocksFromMultipleTemplates(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	fs := memfs.New()
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml": "name: testing\n",
+		"templates/_helpers.library.tpl": "{{- define \"block\" }}\n" +
+			"## <<Stencil::Block({{ .Data }})>>\n{{ file.Block .Data }}\n## <</Stencil::Block>>\n" +
+			"{{- end }}",
+		"templates/config.yaml.tpl": "## <<Stencil::Block(inline)>>\n{{ file.Block \"inline\" }}\n## <</Stencil::Block>>\n" +
+			"{{- stencil.Include \"block\" \"included\" }}\n" +
+			"## <<Stencil::Block(static)>>\n## <</Stencil::Block>>\n",
+	})
+	m, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	var f *File
+	for _, tpl := range tpls {
+		for _, tf := range tpl.Files {
+			if tf.Name() == "config.yaml" {
+				f = tf
+			}
+		}
+	}
+	assert.Assert(t, f != nil, "expected config.yaml to be rendered")
+
+	blocks, err := f.RenderedBlocks()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, blocks, map[string]string{
+		"inline":   "testing/config.yaml.tpl",
+		"included": "testing/_helpers.library.tpl",
+		"static":   "testing/config.yaml.tpl",
+	})
+}
@@ -0,0 +1,106 @@
+//go:build unix
+
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+func TestFileWriteSetsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("setting the owner of a file requires root")
+	}
+
+	f, err := NewFile(filepath.Join(t.TempDir(), "owned.txt"), 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetContents("hello")
+	f.SetOwner(1234, 5678)
+	assert.NilError(t, f.Write(slogext.NewTestLogger(t), false))
+
+	info, err := os.Stat(f.Name())
+	assert.NilError(t, err)
+	stat := info.Sys().(*syscall.Stat_t)
+	assert.Equal(t, stat.Uid, uint32(1234))
+	assert.Equal(t, stat.Gid, uint32(5678))
+}
+
+func TestFileWritePreservesMode(t *testing.T) {
+	f, err := NewFile(filepath.Join(t.TempDir(), "script.sh"), 0o755, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetContents("#!/bin/sh")
+	assert.NilError(t, f.Write(slogext.NewTestLogger(t), false))
+
+	info, err := os.Stat(f.Name())
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o755))
+
+	// The mode of an existing file is kept when it's updated.
+	assert.NilError(t, os.Chmod(f.Name(), 0o700))
+	f.SetContents("#!/bin/bash")
+	assert.NilError(t, f.Write(slogext.NewTestLogger(t), false))
+
+	info, err = os.Stat(f.Name())
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o700))
+}
+
+func TestFileWriteSymlink(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, os.Mkdir(filepath.Join(dir, "v2"), 0o755))
+
+	f, err := NewFile(filepath.Join(dir, "latest"), 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetSymlink("v2")
+	assert.NilError(t, f.Write(slogext.NewTestLogger(t), false))
+	assert.Assert(t, f.changed)
+
+	target, err := os.Readlink(f.Name())
+	assert.NilError(t, err)
+	assert.Equal(t, target, "v2")
+
+	// Writing the same symlink again doesn't change it.
+	f, err = NewFile(filepath.Join(dir, "latest"), 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetSymlink("v2")
+	assert.NilError(t, f.Write(slogext.NewTestLogger(t), false))
+	assert.Assert(t, !f.changed)
+}
+
+func TestFileWriteSymlinkReplacesExisting(t *testing.T) {
+	dir := t.TempDir()
+	log := slogext.NewTestLogger(t)
+
+	// An existing symlink is pointed at the new target.
+	link := filepath.Join(dir, "latest")
+	assert.NilError(t, os.Symlink("v1", link))
+
+	f, err := NewFile(link, 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetSymlink("v2")
+	assert.NilError(t, f.Write(log, false))
+	assert.Assert(t, f.changed)
+
+	target, err := os.Readlink(link)
+	assert.NilError(t, err)
+	assert.Equal(t, target, "v2")
+
+	// As is a regular file.
+	file := filepath.Join(dir, "current")
+	assert.NilError(t, os.WriteFile(file, []byte("hello"), 0o644))
+
+	f, err = NewFile(file, 0o644, time.Now(), nil)
+	assert.NilError(t, err)
+	f.SetSymlink("v2")
+	assert.NilError(t, f.Write(log, false))
+
+	target, err = os.Readlink(file)
+	assert.NilError(t, err)
+	assert.Equal(t, target, "v2")
+}
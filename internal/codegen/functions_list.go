@@ -0,0 +1,174 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for listing the functions
+// available to templates.
+
+package codegen
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+)
+
+// funcsDocsURL is the base URL of the documentation for the stencil
+// provided template functions.
+const funcsDocsURL = "https://stencil.rgst.io/funcs/"
+
+// sprigDocsURL is the URL of the documentation for the sprig template
+// functions.
+const sprigDocsURL = "https://masterminds.github.io/sprig/"
+
+// FunctionInfo describes a function that is available to templates.
+type FunctionInfo struct {
+	// Name is the name used to call the function in a template, e.g.,
+	// "stencil.Arg".
+	Name string
+
+	// Signature is the signature of the function, e.g.,
+	// "func(string) (interface {}, error)".
+	Signature string
+
+	// Source is where the function comes from. This is "stencil" for
+	// built-in functions, "sprig" for sprig functions and the name of
+	// the extension for extension functions. Functions set with
+	// [Stencil.SetFuncs] are "custom".
+	Source string
+
+	// Docs is a URL to the documentation of the function, if there is
+	// any.
+	Docs string
+}
+
+// Functions returns all functions that are available to templates,
+// sorted by name. Extension functions are only included for extensions
+// that have been registered, see [Stencil.RegisterExtensions].
+func (s *Stencil) Functions() ([]FunctionInfo, error) {
+	funcs := make([]FunctionInfo, 0)
+
+	for name, fn := range sprig.TxtFuncMap() {
+		funcs = append(funcs, FunctionInfo{
+			Name:      name,
+			Signature: reflect.TypeOf(fn).String(),
+			Source:    "sprig",
+			Docs:      sprigDocsURL,
+		})
+	}
+
+	// Functions from Default override the sprig functions of the same
+	// name, same as when a template is created.
+	for name, fn := range Default {
+		switch name {
+		case "stencil", "file", "extensions", "module", "return":
+			// These are added by NewFuncMap and are either namespaces
+			// (handled below) or only valid in a specific context.
+			continue
+		}
+
+		funcs = slices.DeleteFunc(funcs, func(f FunctionInfo) bool { return f.Name == name })
+		funcs = append(funcs, FunctionInfo{
+			Name:      name,
+			Signature: reflect.TypeOf(fn).String(),
+			Source:    "stencil",
+		})
+	}
+
+	for namespace, typ := range map[string]reflect.Type{
+		"stencil": reflect.TypeOf(&TplStencil{}),
+		"file":    reflect.TypeOf(&TplFile{}),
+		"module":  reflect.TypeOf(&TplModule{}),
+	} {
+		for i := range typ.NumMethod() {
+			m := typ.Method(i)
+			name := namespace + "." + m.Name
+			funcs = append(funcs, FunctionInfo{
+				Name:      name,
+				Signature: methodSignature(m.Type),
+				Source:    "stencil",
+				Docs:      funcsDocsURL + name + ".html",
+			})
+		}
+	}
+
+	for name, fn := range s.funcs {
+		funcs = append(funcs, FunctionInfo{
+			Name:      name,
+			Signature: reflect.TypeOf(fn).String(),
+			Source:    "custom",
+		})
+	}
+
+	if s.ext != nil {
+		extFuncs, err := s.ext.TemplateFunctions()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get extension functions")
+		}
+
+		for extName, fns := range extFuncs {
+			for _, fn := range fns {
+				args := make([]string, fn.NumberOfArguments)
+				for i := range args {
+					args[i] = "interface {}"
+				}
+
+				funcs = append(funcs, FunctionInfo{
+					Name:      fmt.Sprintf("extensions.Call %q", extName+"."+fn.Name),
+					Signature: "func(" + strings.Join(args, ", ") + ") (interface {}, error)",
+					Source:    extName,
+				})
+			}
+		}
+	}
+
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].Name < funcs[j].Name
+	})
+	return funcs, nil
+}
+
+// methodSignature returns the signature of a method without its
+// receiver, e.g., "func(string) (interface {}, error)".
+func methodSignature(typ reflect.Type) string {
+	in := make([]string, 0, typ.NumIn()-1)
+	for i := 1; i < typ.NumIn(); i++ {
+		arg := typ.In(i).String()
+		if typ.IsVariadic() && i == typ.NumIn()-1 {
+			arg = "..." + typ.In(i).Elem().String()
+		}
+		in = append(in, arg)
+	}
+
+	out := make([]string, 0, typ.NumOut())
+	for i := range typ.NumOut() {
+		out = append(out, typ.Out(i).String())
+	}
+
+	sig := "func(" + strings.Join(in, ", ") + ")"
+	switch len(out) {
+	case 0:
+	case 1:
+		sig += " " + out[0]
+	default:
+		sig += " (" + strings.Join(out, ", ") + ")"
+	}
+	return sig
+}
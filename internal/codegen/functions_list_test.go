@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"testing"
+
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/nativeext/apiv1"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+// fakeExtension is an extension that provides a single template
+// function, used for testing.
+type fakeExtension struct{}
+
+func (fakeExtension) GetConfig() (*apiv1.Config, error) {
+	return &apiv1.Config{}, nil
+}
+
+func (fakeExtension) GetTemplateFunctions() ([]*apiv1.TemplateFunction, error) {
+	return []*apiv1.TemplateFunction{{Name: "hello", NumberOfArguments: 1}}, nil
+}
+
+func (fakeExtension) ExecuteTemplateFunction(_ *apiv1.TemplateFunctionExec) (interface{}, error) {
+	return "hello", nil
+}
+
+func TestFunctions(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{}, log, false)
+	defer st.Close()
+	st.RegisterInprocExtensions("fake", fakeExtension{})
+
+	funcs, err := st.Functions()
+	assert.NilError(t, err)
+
+	found := make(map[string]FunctionInfo)
+	for _, f := range funcs {
+		found[f.Name] = f
+	}
+
+	arg, ok := found["stencil.Arg"]
+	assert.Assert(t, ok, "expected stencil.Arg to be listed")
+	assert.Equal(t, arg.Signature, "func(string) (interface {}, error)")
+	assert.Equal(t, arg.Docs, "https://stencil.rgst.io/funcs/stencil.Arg.html")
+
+	ext, ok := found[`extensions.Call "fake.hello"`]
+	assert.Assert(t, ok, "expected extension function to be listed")
+	assert.Equal(t, ext.Signature, "func(interface {}) (interface {}, error)")
+	assert.Equal(t, ext.Source, "fake")
+
+	toYaml, ok := found["toYaml"]
+	assert.Assert(t, ok, "expected toYaml to be listed")
+	assert.Equal(t, toYaml.Source, "stencil")
+
+	_, ok = found["file.Block"]
+	assert.Assert(t, ok, "expected file.Block to be listed")
+	_, ok = found["trimPrefix"]
+	assert.Assert(t, ok, "expected sprig functions to be listed")
+	_, ok = found["return"]
+	assert.Assert(t, !ok, "expected return to not be listed")
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for rendering only library
+// templates, which is used to debug the functions modules export.
+
+package codegen
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// ExportedFunctionInfo describes a function that a module exported
+// through module.Export (or its helpers).
+type ExportedFunctionInfo struct {
+	// Name is the name used to call the function with module.Call,
+	// e.g., "github.com/rgst-io/module-a.HelloWorld".
+	Name string
+
+	// Module is the name of the module that exported the function.
+	Module string
+
+	// Template is the import path of the library template that defines
+	// the function.
+	Template string
+}
+
+// RenderLibraries renders only the library templates of all modules,
+// no file templates are rendered, and returns the functions that were
+// exported by them sorted by name. This is useful for debugging
+// "function was not registered" errors from module.Call.
+func (s *Stencil) RenderLibraries(ctx context.Context, log slogext.Logger) ([]ExportedFunctionInfo, error) {
+	var err error
+	if s.extCaller, err = s.ext.GetExtensionCaller(ctx); err != nil {
+		return nil, err
+	}
+
+	vals := NewValues(ctx, s.m, s.modules)
+	tplfiles, err := s.getTemplates(ctx, log, vals)
+	if err != nil {
+		return nil, err
+	}
+	tplfiles = slices.DeleteFunc(tplfiles, func(t *Template) bool { return !t.Library })
+
+	for _, t := range tplfiles {
+		log.Debugf("Parsing template %s", t.ImportPath())
+		if err := t.Parse(s); err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", t.ImportPath(), err)
+		}
+	}
+	if err := s.registerHelpers(tplfiles); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tplfiles {
+		log.Debugf("Render template %s", t.ImportPath())
+		if err := t.Render(s, vals); err != nil {
+			return nil, fmt.Errorf("failed to render template %q: %w", t.ImportPath(), err)
+		}
+	}
+
+	funcs := make([]ExportedFunctionInfo, 0)
+	for k, ef := range s.sharedState.Functions.Range {
+		module := ef.Template.Module.Name
+		funcs = append(funcs, ExportedFunctionInfo{
+			Name:     module + "." + strings.TrimPrefix(k, module+"/"),
+			Module:   module,
+			Template: ef.Template.ImportPath(),
+		})
+	}
+	slices.SortFunc(funcs, func(a, b ExportedFunctionInfo) int { return cmp.Compare(a.Name, b.Name) })
+	return funcs, nil
+}
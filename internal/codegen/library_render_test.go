@@ -0,0 +1,38 @@
+package codegen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+func TestRenderLibraries(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":                  "name: testing\nhelpers: [Greeting]\n",
+		"templates/names.library.tpl":    `{{- define "Name" }}{{ return "stencil" }}{{ end }}{{ module.Export "Name" }}`,
+		"templates/greeting.library.tpl": `{{- define "Greeting" }}hello{{ end }}`,
+		// File templates must not be rendered, this one would fail.
+		"templates/broken.txt.tpl": `{{ fail "should not be rendered" }}`,
+	})
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	funcs, err := st.RenderLibraries(ctx, log)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, funcs, []ExportedFunctionInfo{
+		{Name: "testing.Greeting", Module: "testing", Template: "testing/greeting.library.tpl"},
+		{Name: "testing.Name", Module: "testing", Template: "testing/names.library.tpl"},
+	})
+}
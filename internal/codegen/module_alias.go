@@ -0,0 +1,74 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for resolving references to
+// modules by their alias or short name.
+
+package codegen
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// resolveModuleName returns the name of the module referenced by the
+// provided reference, as used by "from", stencil.AddToModuleHook and
+// module.Call. A reference is, in order of precedence:
+//
+//   - the name of a module (e.g., github.com/rgst-io/stencil-base)
+//   - the alias of a module set in the project's manifest
+//   - the last path element of a module's name (e.g., stencil-base),
+//     which must match exactly one module
+//
+// References that don't match any module are returned as-is.
+func (s *Stencil) resolveModuleName(ref string) (string, error) {
+	for _, m := range s.modules {
+		if m.Name == ref {
+			return ref, nil
+		}
+	}
+
+	if s.m != nil {
+		aliases, err := s.m.ModuleAliases()
+		if err != nil {
+			return "", err
+		}
+		if name, ok := aliases[ref]; ok {
+			return name, nil
+		}
+	}
+
+	if strings.Contains(ref, "/") {
+		return ref, nil
+	}
+
+	var matches []string
+	for _, m := range s.modules {
+		if path.Base(m.Name) == ref {
+			matches = append(matches, m.Name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ref, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("module %q is ambiguous, it matches %s, use the full name of the module or "+
+			"set an alias for it in stencil.yaml", ref, strings.Join(matches, ", "))
+	}
+}
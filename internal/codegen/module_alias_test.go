@@ -0,0 +1,127 @@
+package codegen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+// newAliasTestModule returns a module with the provided name and
+// templates, whose manifest has the provided extra contents.
+func newAliasTestModule(t *testing.T, name, manifest string, templates map[string]string) *modules.Module {
+	fs := memfs.New()
+	files := map[string]string{"manifest.yaml": "name: " + name + "\n" + manifest}
+	for k, v := range templates {
+		files["templates/"+k] = v
+	}
+	createFiles(t, fs, files)
+
+	m, err := modulestest.NewWithFS(context.Background(), name, fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	return m
+}
+
+func TestResolveModuleName(t *testing.T) {
+	mods := []*modules.Module{
+		newAliasTestModule(t, "github.com/a/base", "", nil),
+		newAliasTestModule(t, "gitlab.com/b/base", "", nil),
+		newAliasTestModule(t, "github.com/a/golang", "", nil),
+	}
+	m := &configuration.Manifest{
+		Name: "test",
+		Modules: []*configuration.TemplateRepository{
+			{Name: "github.com/a/base", Alias: "a-base"},
+			{Name: "gitlab.com/b/base"},
+			{Name: "github.com/a/golang"},
+		},
+	}
+	st := NewStencil(m, nil, mods, slogext.NewTestLogger(t), false)
+
+	for ref, want := range map[string]string{
+		"gitlab.com/b/base": "gitlab.com/b/base",
+		"a-base":            "github.com/a/base",
+		"golang":            "github.com/a/golang",
+		"unknown":           "unknown",
+	} {
+		got, err := st.resolveModuleName(ref)
+		assert.NilError(t, err, ref)
+		assert.Equal(t, got, want, ref)
+	}
+
+	_, err := st.resolveModuleName("base")
+	assert.Error(t, err, `module "base" is ambiguous, it matches github.com/a/base, gitlab.com/b/base, `+
+		"use the full name of the module or set an alias for it in stencil.yaml")
+
+	m.Modules[1].Alias = "a-base"
+	_, err = st.resolveModuleName("a-base")
+	assert.Error(t, err, `alias "a-base" is used by both module "github.com/a/base" and "gitlab.com/b/base"`)
+}
+
+func TestModuleAliasRender(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	libs := map[string]string{
+		"lib.library.tpl": `{{- define "Name" }}{{ return "a" }}{{ end }}{{ module.Export "Name" }}` +
+			`{{- define "Hook" }}{{ return (stencil.GetModuleHook "names" | uniq) }}{{ end }}{{ module.Export "Hook" }}`,
+	}
+	otherLibs := map[string]string{
+		"lib.library.tpl": `{{- define "Name" }}{{ return "b" }}{{ end }}{{ module.Export "Name" }}`,
+	}
+	mods := []*modules.Module{
+		newAliasTestModule(t, "github.com/a/base", "", libs),
+		newAliasTestModule(t, "gitlab.com/b/base", "", otherLibs),
+		newAliasTestModule(t, "testing", "", map[string]string{
+			"out.txt.tpl": `{{- stencil.AddToModuleHook "a-base" "names" "hook" -}}` +
+				`{{ module.Call "a-base.Name" }} {{ module.Call "gitlab.com/b/base.Name" }} {{ module.Call "a-base.Hook" }}`,
+		}),
+	}
+	m := &configuration.Manifest{
+		Name: "test",
+		Modules: []*configuration.TemplateRepository{
+			{Name: "github.com/a/base", Alias: "a-base"},
+			{Name: "gitlab.com/b/base"},
+			{Name: "testing"},
+		},
+	}
+
+	st := NewStencil(m, nil, mods, log, false)
+	files, err := st.RenderToMemory(ctx, log)
+	assert.NilError(t, err)
+	assert.Equal(t, string(files["out.txt"]), "a b [hook]")
+
+	// Without an alias, the short name is ambiguous.
+	mods[2] = newAliasTestModule(t, "testing", "", map[string]string{
+		"out.txt.tpl": `{{ module.Call "base.Name" }}`,
+	})
+	m.Modules[0].Alias = ""
+	st = NewStencil(m, nil, mods, log, false)
+	_, err = st.RenderToMemory(ctx, log)
+	assert.ErrorContains(t, err, `module "base" is ambiguous, it matches github.com/a/base, gitlab.com/b/base`)
+}
+
+func TestArgFromAlias(t *testing.T) {
+	test := fakeTemplateMultipleModules(t,
+		map[string]any{"hello": "world"},
+		// test-0
+		map[string]configuration.Argument{
+			"hello": {From: "dep"},
+		},
+		// test-1
+		map[string]configuration.Argument{
+			"hello": {Schema: map[string]any{"type": "string"}},
+		},
+	)
+	test.s.m.Modules[1].Alias = "dep"
+
+	expl, err := test.s.ExplainArg("test-0", "hello")
+	assert.NilError(t, err)
+	assert.Equal(t, expl.DefinedBy, "test-1")
+	assert.Equal(t, expl.Value, "world")
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for continuing to render when
+// a template fails, see Stencil.SetKeepGoing.
+
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// SetKeepGoing configures if rendering should continue when a template
+// fails to parse or render. When enabled, failed templates are left
+// out of the templates returned by [Stencil.Render], so their files
+// are not written, and their errors are returned by
+// [Stencil.RenderErrors] instead of by Render.
+func (s *Stencil) SetKeepGoing(keepGoing bool) {
+	s.keepGoing = keepGoing
+}
+
+// RenderErrors returns an error listing every template that failed
+// during the last [Stencil.Render] when keep going is enabled, see
+// [Stencil.SetKeepGoing]. nil is returned if no template failed.
+func (s *Stencil) RenderErrors() error {
+	if len(s.failedTemplates) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(s.failedTemplates))
+	for _, name := range slices.Sorted(maps.Keys(s.failedTemplates)) {
+		errs = append(errs, s.failedTemplates[name])
+	}
+	return fmt.Errorf("%d template(s) failed: %w", len(errs), errors.Join(errs...))
+}
+
+// templateFailed handles a template failing to parse or render. If
+// keep going is not enabled, the error is returned as-is. Otherwise,
+// it is recorded and the template is skipped for the rest of the
+// render, see [Stencil.hasFailed].
+func (s *Stencil) templateFailed(t *Template, err error) error {
+	if !s.keepGoing {
+		return err
+	}
+
+	if s.failedTemplates == nil {
+		s.failedTemplates = make(map[string]error)
+	}
+	s.failedTemplates[t.ImportPath()] = err
+	t.Files = nil
+	return nil
+}
+
+// hasFailed returns true if the provided template failed earlier in
+// the current render, see [Stencil.templateFailed].
+func (s *Stencil) hasFailed(t *Template) bool {
+	_, ok := s.failedTemplates[t.ImportPath()]
+	return ok
+}
@@ -15,8 +15,10 @@
 package codegen
 
 import (
+	"cmp"
 	"fmt"
 	"path"
+	"slices"
 	"sort"
 
 	"github.com/mitchellh/hashstructure/v2"
@@ -34,17 +36,55 @@ func hashModuleHookValue(m any) uint64 {
 	return hash
 }
 
+// ModuleHookEntry is a value that was added to a module hook along with
+// where it came from.
+type ModuleHookEntry struct {
+	// Module is the name of the module that added this value
+	Module string
+
+	// Template is the path of the template, relative to the module, that
+	// added this value
+	Template string
+
+	// Value is the underlying value
+	Value any
+}
+
 // moduleHook is a wrapper type for module hook values that
 // contains the values for module hooks
-type moduleHook []any
+type moduleHook []ModuleHookEntry
 
-// Sort sorts the module hook values by their hash
+// Sort sorts the module hook values by their hash, falling back to
+// the module and template that added them when values are equal.
 func (m moduleHook) Sort() {
-	sort.Slice(m, func(i, j int) bool {
-		return hashModuleHookValue(m[i]) < hashModuleHookValue(m[j])
+	slices.SortStableFunc(m, func(a, b ModuleHookEntry) int {
+		return cmp.Or(
+			cmp.Compare(hashModuleHookValue(a.Value), hashModuleHookValue(b.Value)),
+			cmp.Compare(a.Module, b.Module),
+			cmp.Compare(a.Template, b.Template),
+		)
 	})
 }
 
+// Distinct returns a sorted copy of the module hook without duplicate
+// entries, e.g., those added by the same template on every render pass.
+func (m moduleHook) Distinct() moduleHook {
+	d := slices.Clone(m)
+	d.Sort()
+	return slices.CompactFunc(d, func(a, b ModuleHookEntry) bool {
+		return hashModuleHookValue(a) == hashModuleHookValue(b)
+	})
+}
+
+// Values returns the values of the module hook without their source.
+func (m moduleHook) Values() []any {
+	values := make([]any, 0, len(m))
+	for _, e := range m {
+		values = append(values, e.Value)
+	}
+	return values
+}
+
 // global is an explicit type used to define global variables in the sharedData
 // type (specifically the globals struct field) so that we can track not only the
 // value of the global but also the template it came from.
@@ -60,6 +100,10 @@ type global struct {
 // module.Export for later retrieval/usage at module.Call-time.
 type exportedFunction struct {
 	Template *Template
+
+	// Schema is an optional JSON schema that the value returned by the
+	// function must satisfy.
+	Schema map[string]any
 }
 
 // sharedState stores data that is injected by templates and shared
@@ -73,6 +117,10 @@ type sharedState struct {
 	Functions   *xsync.MapOf[string, exportedFunction]
 	Globals     *xsync.MapOf[string, global]
 	ModuleHooks *xsync.MapOf[string, moduleHook]
+
+	// Gitignore contains the .gitignore patterns added by each module
+	// through [TplStencil.AddGitignore], keyed by module name.
+	Gitignore *xsync.MapOf[string, []string]
 }
 
 // newSharedState returns an initialized (empty underlying maps)
@@ -82,18 +130,69 @@ func newSharedState() *sharedState {
 		Functions:   xsync.NewMapOf[string, exportedFunction](),
 		ModuleHooks: xsync.NewMapOf[string, moduleHook](),
 		Globals:     xsync.NewMapOf[string, global](),
+		Gitignore:   xsync.NewMapOf[string, []string](),
 	}
 }
 
-// hash returns a hash of the current sharedState. This is used to
-// determine if the sharedState has changed.
-func (s *sharedState) hash() (uint64, error) {
-	// Ensure that our slices are sorted so that the hash is consistent.
-	for _, v := range s.ModuleHooks.Range {
+// snapshot returns a hash of every value in the sharedState keyed by
+// the kind of value (e.g., globals) and its key. This is used to
+// determine if the sharedState has changed, comparing two snapshots
+// with [diffSnapshots] shows which values changed.
+//
+// Note: The underlying maps can't be hashed directly as all of their
+// fields are unexported, which hashstructure ignores.
+func (s *sharedState) snapshot() (map[string]uint64, error) {
+	snapshot := make(map[string]uint64)
+
+	var err error
+	for k, v := range s.Functions.Range {
+		if snapshot["functions/"+k], err = hashstructure.Hash(v.Template.ImportPath(), hashstructure.FormatV2, nil); err != nil {
+			return nil, fmt.Errorf("failed to hash function %q: %w", k, err)
+		}
+	}
+	for k, v := range s.Globals.Range {
+		if snapshot["globals/"+k], err = hashstructure.Hash(v, hashstructure.FormatV2, nil); err != nil {
+			return nil, fmt.Errorf("failed to hash global %q: %w", k, err)
+		}
+	}
+	for k, v := range s.ModuleHooks.Range {
+		// Ensure that our slices are sorted so that the hash is consistent.
 		v.Sort()
+
+		// Module hooks are appended to on every iteration, so only
+		// compare their distinct entries.
+		if snapshot["moduleHooks/"+k], err = hashstructure.Hash(v.Distinct(), hashstructure.FormatV2, nil); err != nil {
+			return nil, fmt.Errorf("failed to hash module hook %q: %w", k, err)
+		}
+	}
+	for k, v := range s.Gitignore.Range {
+		// Ensure that our slices are sorted so that the hash is consistent.
+		slices.Sort(v)
+		if snapshot["gitignore/"+k], err = hashstructure.Hash(v, hashstructure.FormatV2, nil); err != nil {
+			return nil, fmt.Errorf("failed to hash gitignore patterns %q: %w", k, err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// diffSnapshots returns the sorted keys that were added, removed or
+// changed between two snapshots created by [sharedState.snapshot].
+func diffSnapshots(old, cur map[string]uint64) []string {
+	changed := make([]string, 0)
+	for k, v := range cur {
+		if ov, ok := old[k]; !ok || ov != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range old {
+		if _, ok := cur[k]; !ok {
+			changed = append(changed, k)
+		}
 	}
+	sort.Strings(changed)
 
-	return hashstructure.Hash(s, hashstructure.FormatV2, nil)
+	return changed
 }
 
 // key returns the key name to use for any of the maps on [sharedState].
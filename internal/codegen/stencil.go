@@ -17,14 +17,21 @@
 package codegen
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"math/rand/v2"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/go-git/go-billy/v5/util"
@@ -39,8 +46,18 @@ import (
 	"go.rgst.io/stencil/v2/pkg/stencil"
 )
 
+// defaultPreRenderStageLimit is the number of iterations the pre-render
+// stage is allowed to run for when the project doesn't set
+// [configuration.Manifest.MaxRenderPasses].
+const defaultPreRenderStageLimit = 20
+
 // NewStencil creates a new, fully initialized Stencil renderer function
 func NewStencil(m *configuration.Manifest, lock *stencil.Lockfile, mods []*modules.Module, log slogext.Logger, adopt bool) *Stencil {
+	preRenderStageLimit := defaultPreRenderStageLimit
+	if m != nil && m.MaxRenderPasses > 0 {
+		preRenderStageLimit = m.MaxRenderPasses
+	}
+
 	ext, err := nativeext.NewHost(log)
 	if err != nil {
 		// TODO(jaredallard): We need to change the signature of this
@@ -53,8 +70,11 @@ func NewStencil(m *configuration.Manifest, lock *stencil.Lockfile, mods []*modul
 		ext:                 ext,
 		lock:                lock,
 		modules:             mods,
-		preRenderStageLimit: 20,
+		preRenderStageLimit: preRenderStageLimit,
 		sharedState:         newSharedState(),
+		exportChecks:        make(map[string]struct{}),
+		renderedFiles:       make(map[string]string),
+		argKeyWarnings:      make(map[string]struct{}),
 		adoptMode:           adopt,
 	}
 }
@@ -99,9 +119,174 @@ type Stencil struct {
 	// sharedState is the shared state between all templates.
 	sharedState *sharedState
 
+	// exportChecks contains the functions exported during the final
+	// render stage, used to prevent duplicate exports. Keyed by
+	// <module>.<function>.
+	exportChecks map[string]struct{}
+
+	// renderedFiles contains the contents of the primary file of each
+	// template, keyed by the template's import path. See
+	// [TplStencil.RenderedFile].
+	renderedFiles map[string]string
+
 	// adoptMode denotes if we should use heuristics to detect code that should go
 	// into blocks to assist with first-time adoption of templates
 	adoptMode bool
+
+	// tags and excludeTags filter which templates are rendered during
+	// the final render stage. See [Stencil.SetTagFilter].
+	tags        []string
+	excludeTags []string
+
+	// targetOS and targetArch override the platform returned by
+	// stencil.OS and stencil.Arch. See [Stencil.SetTarget].
+	targetOS   string
+	targetArch string
+
+	// keepGoing denotes if rendering should continue when a template
+	// fails, see [Stencil.SetKeepGoing].
+	keepGoing bool
+
+	// failedTemplates contains the errors of the templates that failed
+	// during the current render when keepGoing is set, keyed by the
+	// template's import path.
+	failedTemplates map[string]error
+
+	// argKeyWarnings contains the arguments that were matched
+	// case-insensitively and have already been warned about, keyed by
+	// <module>:<path>.
+	argKeyWarnings map[string]struct{}
+
+	// funcs are additional functions available to all templates, see
+	// [Stencil.SetFuncs].
+	funcs template.FuncMap
+
+	// requiredWhenActive contains the arguments whose requiredWhen is
+	// currently being rendered, used to detect cycles. Keyed by
+	// <module>:<path>.
+	requiredWhenActive map[string]struct{}
+
+	// globalWriters contains the import path of the template that last
+	// set each global during the current render pass, keyed like
+	// [sharedState.Globals]. See [Stencil.warnGlobalConflict].
+	globalWriters map[string]string
+
+	// globalConflictWarnings contains the conflicting writes to globals
+	// that have already been warned about, keyed by
+	// <global>:<template>:<template>.
+	globalConflictWarnings map[string]struct{}
+}
+
+// warnArgKeyMismatch warns, once per module and argument, that the
+// argument was set in the project's manifest with a key that only
+// matches case-insensitively.
+func (s *Stencil) warnArgKeyMismatch(module, pth, setPth string) {
+	key := module + ":" + pth
+	if _, ok := s.argKeyWarnings[key]; ok {
+		return
+	}
+	s.argKeyWarnings[key] = struct{}{}
+
+	s.log.With("module", module).Warnf(
+		"Argument %q is set as %q in stencil.yaml, update it to match the case declared by the module", pth, setPth,
+	)
+}
+
+// warnGlobalConflict warns, once per global and pair of templates, when
+// the provided template sets a global to a different value than the one
+// set by another template during the same render pass. Globals being
+// updated by the same template between passes (i.e., while the shared
+// state converges) are not conflicts.
+func (s *Stencil) warnGlobalConflict(name string, t *Template, value any, log slogext.Logger) {
+	key := s.sharedState.key(t.Module.Name, name)
+	if s.globalWriters == nil {
+		s.globalWriters = make(map[string]string)
+	}
+	prevTemplate, ok := s.globalWriters[key]
+	s.globalWriters[key] = t.ImportPath()
+	if !ok || prevTemplate == t.ImportPath() {
+		return
+	}
+
+	prev, ok := s.sharedState.Globals.Load(key)
+	if !ok || reflect.DeepEqual(prev.Value, value) {
+		return
+	}
+
+	warnKey := key + ":" + prevTemplate + ":" + t.ImportPath()
+	if _, ok := s.globalConflictWarnings[warnKey]; ok {
+		return
+	}
+	if s.globalConflictWarnings == nil {
+		s.globalConflictWarnings = make(map[string]struct{})
+	}
+	s.globalConflictWarnings[warnKey] = struct{}{}
+
+	log.With("module", t.Module.Name).Warnf(
+		"Global %q set by template %q was overwritten with a different value by template %q",
+		name, prevTemplate, t.ImportPath(),
+	)
+}
+
+// SetFuncs makes the provided functions available to all templates,
+// allowing embedders of this package to provide their own functions
+// without a native extension. Functions can't replace built-in
+// functions (sprig's, stencil's, the stencil, file, module and
+// extensions namespaces, and those of text/template), an error is
+// returned if any of them have the same name as one. Helpers declared
+// by a module take precedence over these functions in its templates.
+// Calling SetFuncs again replaces the functions previously set.
+func (s *Stencil) SetFuncs(funcs template.FuncMap) error {
+	var conflicts []string
+	for name := range funcs {
+		if isBuiltinFunc(name) {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) != 0 {
+		slices.Sort(conflicts)
+		return fmt.Errorf("can't replace built-in function(s): %s", strings.Join(conflicts, ", "))
+	}
+
+	s.funcs = maps.Clone(funcs)
+	return nil
+}
+
+// SetTarget overrides the operating system and architecture returned by
+// stencil.OS and stencil.Arch, which default to those stencil is
+// running on. Empty values are not overridden.
+func (s *Stencil) SetTarget(goos, goarch string) {
+	s.targetOS = goos
+	s.targetArch = goarch
+}
+
+// SetTagFilter limits the templates rendered during the final render
+// stage to those tagged (through templateTags in their module's
+// manifest) with at least one of the provided tags, if any, and none of
+// the excluded tags. The pre-render stage always renders every template
+// so that shared state (e.g., module hooks) is complete. Library
+// templates are always rendered.
+func (s *Stencil) SetTagFilter(tags, excludeTags []string) {
+	s.tags = tags
+	s.excludeTags = excludeTags
+}
+
+// matchesTagFilter returns true if the provided template should be
+// rendered based on the filter set by [Stencil.SetTagFilter].
+func (s *Stencil) matchesTagFilter(t *Template) bool {
+	if t.Library || (len(s.tags) == 0 && len(s.excludeTags) == 0) {
+		return true
+	}
+
+	tags := t.Module.Manifest.TagsForTemplate(t.Path)
+	hasAny := func(want []string) bool {
+		return slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(want, tag) })
+	}
+
+	if hasAny(s.excludeTags) {
+		return false
+	}
+	return len(s.tags) == 0 || hasAny(s.tags)
 }
 
 // RegisterExtensions registers all extensions on the currently loaded
@@ -109,6 +294,11 @@ type Stencil struct {
 func (s *Stencil) RegisterExtensions(ctx context.Context) error {
 	for _, m := range s.modules {
 		if err := m.RegisterExtensions(ctx, s.ext); err != nil {
+			if m.Optional {
+				s.log.WithError(err).With("module", m.Name).
+					Warn("failed to load optional extension, continuing without it")
+				continue
+			}
 			return errors.Wrapf(err, "failed to load extensions from module %q", m.Name)
 		}
 	}
@@ -123,6 +313,41 @@ func (s *Stencil) RegisterInprocExtensions(name string, ext apiv1.Implementation
 	s.ext.RegisterInprocExtension(name, ext)
 }
 
+// GetModuleHook returns the data that has been added to the provided
+// module hook during rendering. This API is used in unit tests to
+// inspect the contributions a template makes to another module's hook.
+// The returned slice is a copy and is safe to modify.
+func (s *Stencil) GetModuleHook(module, name string) []any {
+	v, _ := s.sharedState.ModuleHooks.Load(s.sharedState.key(module, name))
+	return v.Values()
+}
+
+// ExplainArg explains how the value of the argument at the provided
+// path is derived for the provided module, as it would be returned by
+// stencil.Arg in one of the module's templates.
+func (s *Stencil) ExplainArg(module, pth string) (*ArgExplanation, error) {
+	idx := slices.IndexFunc(s.modules, func(m *modules.Module) bool { return m.Name == module })
+	if idx == -1 {
+		return nil, fmt.Errorf("module %q is not used by this project", module)
+	}
+
+	t, err := NewTemplate(s.modules[idx], "explainArg", 0o000, time.Time{}, nil, s.log, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TplStencil{s: s, t: t, log: s.log}
+	expl, err := ts.explainArg(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.validateArg(pth, expl); err != nil {
+		return nil, err
+	}
+	return expl, nil
+}
+
 // GenerateLockfile generates a stencil.Lockfile based
 // on a list of templates.
 func (s *Stencil) GenerateLockfile(tpls []*Template) *stencil.Lockfile {
@@ -132,8 +357,9 @@ func (s *Stencil) GenerateLockfile(tpls []*Template) *stencil.Lockfile {
 
 	for _, tpl := range tpls {
 		for _, f := range tpl.Files {
-			// Don't write files we skipped, or deleted, to the lockfile
-			if f.Skipped || f.Deleted {
+			// Don't write files we skipped, deleted, or that are untracked,
+			// to the lockfile
+			if f.Skipped || f.Deleted || f.untracked {
 				continue
 			}
 
@@ -144,12 +370,19 @@ func (s *Stencil) GenerateLockfile(tpls []*Template) *stencil.Lockfile {
 			})
 		}
 	}
+	l.Blocks = s.lockBlocks(tpls)
 
 	for _, m := range s.modules {
+		digest, err := m.Digest()
+		if err != nil {
+			s.log.WithError(err).Warn("failed to compute module digest, not storing it in the lockfile")
+		}
+
 		l.Modules = append(l.Modules, &stencil.LockfileModuleEntry{
 			Name:    m.Name,
 			URL:     m.URI,
 			Version: m.Version,
+			Digest:  digest,
 		})
 	}
 
@@ -158,67 +391,156 @@ func (s *Stencil) GenerateLockfile(tpls []*Template) *stencil.Lockfile {
 	return l
 }
 
+// lockBlocks returns the blocks of the files generated by the provided
+// templates to be stored in the lockfile, keyed by module and block
+// name. Blocks with the same name but different contents in multiple
+// files of a module are ambiguous, so they're not stored. nil is
+// returned if storing blocks in the lockfile is not enabled.
+func (s *Stencil) lockBlocks(tpls []*Template) []*stencil.LockfileBlockEntry {
+	if s.m == nil || !s.m.LockBlocks {
+		return nil
+	}
+
+	blocks := make(map[[2]string]*stencil.LockfileBlockEntry)
+	ambiguous := make(map[[2]string]bool)
+	for _, tpl := range tpls {
+		if tpl.Binary {
+			continue
+		}
+
+		for _, f := range tpl.Files {
+			if f.Skipped || f.Deleted || f.untracked {
+				continue
+			}
+
+			parsed, err := parseBlocksInner(bytes.NewReader(f.Bytes()), f.Name(), nil, blockCommentPrefixes(tpl))
+			if err != nil {
+				s.log.WithError(err).With("file", f.Name()).Warn("failed to parse blocks, not storing them in the lockfile")
+				continue
+			}
+
+			for name, bi := range parsed {
+				if bi.Contents == "" {
+					continue
+				}
+
+				k := [2]string{tpl.Module.Name, name}
+				if b, ok := blocks[k]; ok && b.Contents != bi.Contents {
+					ambiguous[k] = true
+					continue
+				}
+				blocks[k] = &stencil.LockfileBlockEntry{Module: tpl.Module.Name, Name: name, Contents: bi.Contents}
+			}
+		}
+	}
+
+	var rv []*stencil.LockfileBlockEntry
+	for k, b := range blocks {
+		if ambiguous[k] {
+			s.log.With("module", b.Module, "block", b.Name).
+				Debug("Block has different contents in multiple files, not storing it in the lockfile")
+			continue
+		}
+		rv = append(rv, b)
+	}
+	return rv
+}
+
 // Render renders all templates using the Manifest that was
 // provided to stencil at creation time, returned is the templates
 // that were produced and their associated files.
 func (s *Stencil) Render(ctx context.Context, log slogext.Logger) ([]*Template, error) {
-	tplfiles, err := s.getTemplates(ctx, log)
-	if err != nil {
-		return nil, err
-	}
-
+	var err error
 	if s.extCaller, err = s.ext.GetExtensionCaller(ctx); err != nil {
 		return nil, err
 	}
 
+	s.warnDeprecatedArgs(log)
+
 	log.Debug("Creating values for template")
 	vals := NewValues(ctx, s.m, s.modules)
 	log.Debug("Finished creating values")
 
+	tplfiles, err := s.getTemplates(ctx, log, vals)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add the templates to their modules template to allow them to be able to access
 	// functions declared in the same module
+	s.failedTemplates = nil
 	for _, t := range tplfiles {
 		log.Debugf("Parsing template %s", t.ImportPath())
 		if err := t.Parse(s); err != nil {
-			return nil, errors.Wrapf(err, "failed to parse template %q", t.ImportPath())
+			err = errors.Wrapf(err, "failed to parse template %q", t.ImportPath())
+			if err := s.templateFailed(t, err); err != nil {
+				return nil, err
+			}
 		}
 	}
+	if err := s.registerHelpers(tplfiles); err != nil {
+		return nil, err
+	}
 
 	// Render until we limit or state is stable
-	var lastHash uint64
+	var lastSnapshot map[string]uint64
+	var changed []string
 	var i int
 	for {
 		if i > (s.preRenderStageLimit - 1) {
-			return nil, fmt.Errorf("failed to stabilize shared state within %d iterations", i)
+			return nil, fmt.Errorf("failed to stabilize shared state within %d iterations (see maxRenderPasses in "+
+				"stencil.yaml), still changing between the last two iterations: %s", i, strings.Join(changed, ", "))
 		}
 
 		log.Debug("Render stage", "iteration", i)
+
+		// Gitignore patterns are appended to, so they need to be rebuilt
+		// from scratch on every iteration to not accumulate duplicate data.
+		s.sharedState.Gitignore.Clear()
+		s.globalWriters = make(map[string]string)
+
 		for _, t := range tplfiles {
+			if s.hasFailed(t) {
+				continue
+			}
+
 			log.Debugf("Render template %s", t.ImportPath())
 			if err := t.Render(s, vals); err != nil {
-				return nil, errors.Wrapf(err, "failed to render template %q", t.ImportPath())
+				err = errors.Wrapf(err, "failed to render template %q", t.ImportPath())
+				if err := s.templateFailed(t, err); err != nil {
+					return nil, err
+				}
+				continue
 			}
+			s.recordRenderedFile(t)
 
 			// Don't keep files, we only need the shared state modifications.
 			t.Files = nil
 		}
 
-		// Calculate the hash of the shared state
-		hash, err := s.sharedState.hash()
+		// Determine what changed in the shared state since the last
+		// iteration
+		snapshot, err := s.sharedState.snapshot()
 		if err != nil {
 			return nil, fmt.Errorf("failed to determine a stable hash for shared state: %w", err)
 		}
-		if hash == lastHash {
+		changed = diffSnapshots(lastSnapshot, snapshot)
+		if i > 0 && len(changed) == 0 {
 			log.Debugf("First pass render stable after %d iterations", i)
 			break
 		}
+		if len(changed) != 0 {
+			log.Debug("Shared state changed", "iteration", i, "changed", changed)
+		}
 
-		lastHash = hash
+		lastSnapshot = snapshot
 		i++
 	}
 
 	// We're at the final render stage now.
 	s.renderStage = renderStageFinal
+	s.exportChecks = make(map[string]struct{})
+	s.globalWriters = make(map[string]string)
 
 	if err := s.calcDirReplacements(vals); err != nil {
 		return nil, err
@@ -226,21 +548,196 @@ func (s *Stencil) Render(ctx context.Context, log slogext.Logger) ([]*Template,
 
 	tpls := make([]*Template, 0)
 	for _, t := range tplfiles {
+		if !s.matchesTagFilter(t) {
+			log.Debugf("Skipping template %s, excluded by tag filter", t.ImportPath())
+			continue
+		}
+
+		if s.hasFailed(t) {
+			log.Debugf("Skipping template %s, it failed to render", t.ImportPath())
+			continue
+		}
+
 		log.Debugf("Final render of template %s", t.ImportPath())
 		if err := t.Render(s, vals); err != nil {
-			return nil, errors.Wrapf(err, "failed to render template %q", t.ImportPath())
+			err = errors.Wrapf(err, "failed to render template %q", t.ImportPath())
+			if err := s.templateFailed(t, err); err != nil {
+				return nil, err
+			}
+			continue
 		}
+		s.recordRenderedFile(t)
 
 		// append the rendered template to our list of templates processed
 		tpls = append(tpls, t)
 	}
 
+	if err := s.validateModuleHookItems(); err != nil {
+		return nil, err
+	}
+	s.warnUnusedModuleHooks(log)
+	s.warnOrphanedMigratedBlocks(log, tpls)
+
 	return tpls, nil
 }
 
+// RenderToMemory renders all templates, like [Stencil.Render], and
+// returns the contents of the files they generated keyed by their
+// path instead of the templates. Skipped and deleted files are not
+// included. Nothing is written to disk, which makes this useful for
+// embedding stencil or testing generated content.
+func (s *Stencil) RenderToMemory(ctx context.Context, log slogext.Logger) (map[string][]byte, error) {
+	tpls, err := s.Render(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Skipped || f.Deleted {
+				continue
+			}
+			files[f.Name()] = f.Bytes()
+		}
+	}
+
+	return files, nil
+}
+
+// recordRenderedFile records the contents of the primary file of the
+// provided template, if it rendered one, for [TplStencil.RenderedFile].
+func (s *Stencil) recordRenderedFile(t *Template) {
+	if len(t.Files) == 0 || t.Files[0].Skipped || t.Files[0].Deleted {
+		delete(s.renderedFiles, t.ImportPath())
+		return
+	}
+	s.renderedFiles[t.ImportPath()] = t.Files[0].String()
+}
+
+// validateModuleHookItems ensures that the number of distinct items
+// added to each module hook is within the minItems and maxItems
+// declared in the owning module's manifest.
+func (s *Stencil) validateModuleHookItems() error {
+	for _, m := range s.modules {
+		if m.Manifest == nil {
+			continue
+		}
+
+		names := slices.Sorted(maps.Keys(m.Manifest.ModuleHooks))
+		for _, name := range names {
+			cfg := m.Manifest.ModuleHooks[name]
+			v, _ := s.sharedState.ModuleHooks.Load(s.sharedState.key(m.Name, name))
+			v = v.Distinct()
+
+			var constraint string
+			switch {
+			case len(v) < cfg.MinItems:
+				constraint = fmt.Sprintf("at least %d", cfg.MinItems)
+			case cfg.MaxItems > 0 && len(v) > cfg.MaxItems:
+				constraint = fmt.Sprintf("at most %d", cfg.MaxItems)
+			default:
+				continue
+			}
+
+			err := fmt.Errorf("module hook %q of module %q expects %s item(s), got %d", name, m.Name, constraint, len(v))
+			if len(v) == 0 {
+				return err
+			}
+
+			contributors := make([]string, 0, len(v))
+			for _, e := range v {
+				contributors = append(contributors, path.Join(e.Module, e.Template))
+			}
+			slices.Sort(contributors)
+
+			return fmt.Errorf("%w (contributed by: %s)", err, strings.Join(contributors, ", "))
+		}
+	}
+
+	return nil
+}
+
+// warnUnusedModuleHooks warns about module hooks that were declared in
+// a module's manifest but that no template added any data to during
+// this render. This helps module authors find dead extension points.
+func (s *Stencil) warnUnusedModuleHooks(log slogext.Logger) {
+	for _, m := range s.modules {
+		if m.Manifest == nil {
+			continue
+		}
+
+		names := slices.Sorted(maps.Keys(m.Manifest.ModuleHooks))
+		for _, name := range names {
+			if v, _ := s.sharedState.ModuleHooks.Load(s.sharedState.key(m.Name, name)); len(v) != 0 {
+				continue
+			}
+
+			log.With("module", m.Name, "hook", name).
+				Warn("Module hook is declared but no module added any data to it")
+		}
+	}
+}
+
+// warnOrphanedMigratedBlocks warns about blocks with contents in files
+// migrated with file.MigrateTo that the template rendering the new path
+// does not declare. These blocks would otherwise be silently dropped,
+// losing the user's code.
+func (s *Stencil) warnOrphanedMigratedBlocks(log slogext.Logger, tpls []*Template) {
+	files := make(map[string]*File)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Skipped || f.Deleted {
+				continue
+			}
+			files[f.path] = f
+		}
+	}
+
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.migratedTo == "" {
+				continue
+			}
+
+			// If nothing renders the new path, the migrated file is left
+			// as-is and no blocks are lost.
+			dest, ok := files[f.migratedTo]
+			if !ok {
+				continue
+			}
+
+			declared, err := parseBlocksInner(bytes.NewReader(dest.contents), dest.path, dest.sourceTemplate,
+				blockCommentPrefixes(dest.sourceTemplate))
+			if err != nil {
+				log.With("path", dest.path).WithError(err).
+					Warn("Failed to parse blocks of migrated file, unable to check for lost blocks")
+				continue
+			}
+
+			lost := make([]string, 0)
+			for _, name := range slices.Sorted(maps.Keys(f.blocks)) {
+				contents := f.blocks[name].Contents
+				if _, ok := declared[name]; ok || strings.TrimSpace(contents) == "" {
+					continue
+				}
+				lost = append(lost, fmt.Sprintf("%s (%d bytes)", name, len(contents)))
+			}
+			if len(lost) == 0 {
+				continue
+			}
+
+			log.With("from", f.path, "to", dest.path, "blocks", strings.Join(lost, ", ")).
+				Warn("Migrated file contains blocks that are not declared at its new path, their contents will be lost")
+		}
+	}
+}
+
 // calcDirReplacements calculates all of the final rendered paths for dirReplacements for each module
 // It needs to be in stencil because it uses rendering, which needs the Values object from codegen,
 // so we poke the rendered replacements into the module object for applying later in various ways.
+// Replacements that render to an empty (or whitespace only) string are skipped, which allows them
+// to be conditional.
 func (s *Stencil) calcDirReplacements(vals *Values) error {
 	for _, m := range s.modules {
 		reps := map[string]string{}
@@ -250,6 +747,10 @@ func (s *Stencil) calcDirReplacements(vals *Values) error {
 			if err != nil {
 				return err
 			}
+			if strings.TrimSpace(nn) == "" {
+				s.log.With("module", m.Name, "dir", dsrc).Debug("Skipping directory replacement, rendered to an empty string")
+				continue
+			}
 			reps[dsrc] = nn
 		}
 		m.StoreDirReplacements(reps)
@@ -257,6 +758,31 @@ func (s *Stencil) calcDirReplacements(vals *Values) error {
 	return nil
 }
 
+// calcTemplateDirConditions renders the templateDirConditions of the
+// provided module, returning whether or not each directory should be
+// included.
+func (s *Stencil) calcTemplateDirConditions(m *modules.Module, vals *Values) (map[string]bool, error) {
+	conditions := make(map[string]bool, len(m.Manifest.TemplateDirConditions))
+	for dir, cond := range m.Manifest.TemplateDirConditions {
+		rt, err := NewTemplate(m, "templateDirCondition", 0o000, time.Time{}, []byte(cond), s.log, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rt.Render(s, vals); err != nil {
+			return nil, errors.Wrapf(err, "failed to render templateDirConditions for %q in module %q", dir, m.Name)
+		}
+
+		include, err := strconv.ParseBool(strings.TrimSpace(rt.Files[0].String()))
+		if err != nil {
+			return nil, fmt.Errorf("templateDirConditions for %q in module %q must render to true or false, got %q",
+				dir, m.Name, rt.Files[0].String())
+		}
+		conditions[path.Clean(filepath.ToSlash(dir))] = include
+	}
+	return conditions, nil
+}
+
 // renderDirReplacement breaks out the actual rendering for calcDirReplacements to make it unit testable
 func (s *Stencil) renderDirReplacement(template string, m *modules.Module, vals *Values) (string, error) {
 	rt, err := NewTemplate(m, "dirReplace", 0o000, time.Time{}, []byte(template), s.log, nil)
@@ -276,9 +802,16 @@ func (s *Stencil) renderDirReplacement(template string, m *modules.Module, vals
 	return nn, nil
 }
 
+// ChangedFilesEnvVar is the environment variable that contains the
+// newline separated list of files that were created or had their
+// contents changed, passed to post-run commands.
+const ChangedFilesEnvVar = "STENCIL_CHANGED_FILES"
+
 // PostRun runs all post run commands specified in the modules that
-// this project depends on
-func (s *Stencil) PostRun(ctx context.Context, log slogext.Logger) error {
+// this project depends on. The files created or updated when writing
+// the provided templates are passed to the commands through
+// [ChangedFilesEnvVar].
+func (s *Stencil) PostRun(ctx context.Context, log slogext.Logger, tpls []*Template) error {
 	log.Info("Running post-run command(s)")
 
 	type postRunCommand struct {
@@ -288,27 +821,13 @@ func (s *Stencil) PostRun(ctx context.Context, log slogext.Logger) error {
 
 	postRunCommands := []*postRunCommand{}
 
-	// Check if the project has a '.mise.toml' and that 'mise' is
-	// installed. If so, automatically trust the config.
-	//
-	// Note: This is to fix a special case with mise being used across
-	// various language's modules. If anything else needs to be added
-	// here, consider adding it to the post run commands array for a
-	// specific module first. Otherwise, consider changing this system to
-	// prevent more cases from being added to the code.
-	if _, err := os.Stat(".mise.toml"); err == nil {
-		// Check if 'mise' is in path to ensure this is less likely to fail.
-		//
-		//nolint:errcheck // Why: Checking path only
-		if misePath, _ := exec.LookPath("mise"); misePath != "" {
-			postRunCommands = append(postRunCommands, &postRunCommand{
-				Module: "stencil",
-				Spec: &configuration.PostRunCommandSpec{
-					Name:    "mise: trust config",
-					Command: "mise trust --quiet",
-				},
-			})
-		}
+	// mise is used across various language's modules, so its config is
+	// trusted by stencil itself rather than every module doing so.
+	if !s.m.SkipMiseTrust {
+		postRunCommands = append(postRunCommands, &postRunCommand{
+			Module: "stencil",
+			Spec:   miseTrustPostRunCommand,
+		})
 	}
 
 	// Get all post run commands from all modules
@@ -321,9 +840,24 @@ func (s *Stencil) PostRun(ctx context.Context, log slogext.Logger) error {
 		}
 	}
 
+	// Commands from the project run last, after every module's.
+	for _, prc := range s.m.PostRunCommand {
+		postRunCommands = append(postRunCommands, &postRunCommand{
+			Module: "stencil.yaml",
+			Spec:   prc,
+		})
+	}
+
+	environ := append(os.Environ(), ChangedFilesEnvVar+"="+strings.Join(changedFiles(tpls), "\n"))
 	for _, prc := range postRunCommands {
+		if !postRunConditionMet(prc.Spec.If) {
+			log.Debugf(" - Skipping %s (source: %s), conditions not met", prc.Spec.Name, prc.Module)
+			continue
+		}
+
 		log.Infof(" - %s (source: %s)", prc.Spec.Name, prc.Module)
 		cmd := cmdexec.CommandContext(ctx, "/usr/bin/env", "bash", "-c", prc.Spec.Command)
+		cmd.SetEnviron(environ)
 		cmd.UseOSStreams(true)
 		if err := cmd.Run(); err != nil {
 			return errors.Wrapf(err, "failed to run post run command for module %q", prc.Module)
@@ -333,10 +867,62 @@ func (s *Stencil) PostRun(ctx context.Context, log slogext.Logger) error {
 	return nil
 }
 
+// miseTrustPostRunCommand trusts the project's .mise.toml, if it has
+// one and mise is installed. See [configuration.Manifest.SkipMiseTrust].
+var miseTrustPostRunCommand = &configuration.PostRunCommandSpec{
+	Name:    "mise: trust config",
+	Command: "mise trust --quiet",
+	If: &configuration.PostRunCondition{
+		FileExists:    ".mise.toml",
+		CommandExists: "mise",
+	},
+}
+
+// postRunConditionMet returns true if all of the provided conditions
+// for running a post-run command are met.
+func postRunConditionMet(cond *configuration.PostRunCondition) bool {
+	if cond == nil {
+		return true
+	}
+
+	if cond.FileExists != "" {
+		if _, err := os.Stat(cond.FileExists); err != nil {
+			return false
+		}
+	}
+
+	if cond.CommandExists != "" {
+		if _, err := exec.LookPath(cond.CommandExists); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// changedFiles returns the sorted paths of the files that were created
+// or updated when the provided templates were written.
+func changedFiles(tpls []*Template) []string {
+	files := make([]string, 0)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.changed {
+				files = append(files, f.Name())
+			}
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files)
+}
+
 // getTemplates takes all modules attached to this stencil
 // struct and returns all templates exposed by it.
-func (s *Stencil) getTemplates(ctx context.Context, log slogext.Logger) ([]*Template, error) {
+func (s *Stencil) getTemplates(ctx context.Context, log slogext.Logger, vals *Values) ([]*Template, error) {
 	tpls := make([]*Template, 0)
+
+	// excludedDirs contains the import paths of the directories skipped
+	// because of templateDirConditions.
+	excludedDirs := make(map[string]bool)
 	for _, m := range s.modules {
 		log.Debugf("Fetching module %q", m.Name)
 		fs, err := m.GetFS(ctx)
@@ -353,21 +939,41 @@ func (s *Stencil) getTemplates(ctx context.Context, log slogext.Logger) ([]*Temp
 
 		log.Debugf("Discovering templates from module %q", m.Name)
 
+		binTpls, err := s.binarySourceTemplates(m, fs, vals, log)
+		if err != nil {
+			return nil, err
+		}
+		tpls = append(tpls, binTpls...)
+
 		// Only find templates in the templates/ directory
 		fs, err = fs.Chroot("templates")
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to chroot module filesystem to templates/ (does it exist?)")
 		}
 
+		dirConditions, err := s.calcTemplateDirConditions(m, vals)
+		if err != nil {
+			return nil, err
+		}
+
 		err = util.Walk(fs, "", func(path string, inf os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
 
+			if inf.IsDir() {
+				if include, ok := dirConditions[filepath.ToSlash(path)]; ok && !include {
+					log.Debugf("Skipping template directory %q, templateDirConditions evaluated to false", path)
+					excludedDirs[m.Name+"/"+filepath.ToSlash(path)] = true
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
 			// add binary check here
 
-			// Skip files without a .tpl extension
-			isTemplate := filepath.Ext(path) == ".tpl"
+			// Skip files without a template (e.g., .tpl) extension
+			_, isTemplate := m.Manifest.TemplateExtension(path)
 			isBinary := filepath.Ext(path) == ".nontpl"
 			if !isTemplate && !isBinary {
 				return nil
@@ -410,7 +1016,7 @@ func (s *Stencil) getTemplates(ctx context.Context, log slogext.Logger) ([]*Temp
 		tpls[i], tpls[j] = tpls[j], tpls[i]
 	})
 
-	return tpls, nil
+	return orderTemplates(tpls, excludedDirs)
 }
 
 // Close closes all resources that should be closed when done
@@ -1,13 +1,21 @@
 package codegen
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"os/exec"
 	"path"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+	"text/template"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/jaredallard/vcs/resolver"
 	"go.rgst.io/stencil/v2/internal/modules"
@@ -18,6 +26,7 @@ import (
 	"go.rgst.io/stencil/v2/pkg/stencil"
 	"gopkg.in/yaml.v3"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
 )
 
 func TestBasicE2ERender(t *testing.T) {
@@ -49,6 +58,9 @@ func TestBasicE2ERender(t *testing.T) {
 	assert.Equal(t, len(tpls[0].Files), 1, "expected Render() template to return a single file")
 	assert.Equal(t, tpls[0].Files[0].String(), "test", "expected Render() to return correct output")
 
+	digest, err := tp.Digest()
+	assert.NilError(t, err, "failed to compute module digest")
+
 	lock := st.GenerateLockfile(tpls)
 	assert.DeepEqual(t, lock, &stencil.Lockfile{
 		Version: version.Version.GitVersion,
@@ -57,6 +69,7 @@ func TestBasicE2ERender(t *testing.T) {
 				Name:    "testing",
 				URL:     "vfs://testing",
 				Version: &resolver.Version{Virtual: "vfs"},
+				Digest:  digest,
 			},
 		},
 		Files: []*stencil.LockfileFileEntry{
@@ -105,6 +118,33 @@ func TestModuleHookRender(t *testing.T) {
 	assert.Equal(t, strings.TrimSpace(tpls[1].Files[0].String()), "b", "expected Render() m2 to return correct output")
 }
 
+func TestGitignoreRender(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	m1, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing1",
+	}, "testdata/gitignore/m1.tpl")
+	assert.NilError(t, err, "failed to create module 1")
+	m2, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing2",
+	}, "testdata/gitignore/gitignore.tpl")
+	assert.NilError(t, err, "failed to create module 2")
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m1, m2}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "expected Render() to not fail")
+
+	var gitignore *File
+	for _, tpl := range tpls {
+		if tpl.Module.Name == "testing2" {
+			gitignore = tpl.Files[0]
+		}
+	}
+	assert.Assert(t, gitignore != nil, "expected gitignore template to be rendered")
+	assert.Equal(t, strings.TrimSpace(gitignore.String()), ".cache/\ndist/\nnode_modules/")
+}
+
 func TestDirReplacementRendering(t *testing.T) {
 	log := slogext.NewTestLogger(t)
 	sm := &configuration.Manifest{Name: "testing", Arguments: map[string]any{"x": "d"}}
@@ -128,6 +168,401 @@ func TestDirReplacementRendering(t *testing.T) {
 	assert.Equal(t, tps[0].Files[0].path, "bob/d/m1")
 }
 
+func TestConditionalDirReplacementRendering(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		want string
+	}{
+		{"applies when condition holds", "kotlin", "testdata/proj/m1"},
+		{"skipped when rendering empty", "java", "testdata/replacement/m1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slogext.NewTestLogger(t)
+			sm := &configuration.Manifest{Name: "testing", Arguments: map[string]any{"lang": tt.lang}}
+			m1man := &configuration.TemplateRepositoryManifest{
+				Name: "testing1",
+				DirReplacements: map[string]string{
+					"testdata/replacement": `{{ if eq (stencil.Arg "lang") "kotlin" }}proj{{ end }}`,
+				},
+				Arguments: map[string]configuration.Argument{"lang": {Schema: map[string]any{"type": "string"}}},
+			}
+			m1, err := modulestest.NewModuleFromTemplates(m1man, "testdata/replacement/m1.tpl")
+			assert.NilError(t, err, "failed to NewModuleFromTemplates")
+
+			st := NewStencil(sm, nil, []*modules.Module{m1}, log, false)
+			tps, err := st.Render(context.Background(), log)
+			assert.NilError(t, err, "failed to render template")
+			assert.Equal(t, len(tps), 1)
+			assert.Equal(t, len(tps[0].Files), 1)
+			assert.Equal(t, tps[0].Files[0].path, tt.want)
+		})
+	}
+}
+
+func TestOutputPrefixRendering(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+	sm := &configuration.Manifest{Name: "testing"}
+	m1man := &configuration.TemplateRepositoryManifest{
+		Name:            "testing1",
+		OutputPrefix:    "docs",
+		DirReplacements: map[string]string{"testdata": "replaced"},
+	}
+	m1, err := modulestest.NewModuleFromTemplates(m1man,
+		"testdata/output-prefix/a.tpl", "testdata/output-prefix/b.tpl", "testdata/output-prefix/c.tpl")
+	assert.NilError(t, err, "failed to NewModuleFromTemplates")
+
+	st := NewStencil(sm, nil, []*modules.Module{m1}, log, false)
+	tpls, err := st.Render(context.Background(), log)
+	assert.NilError(t, err, "failed to render template")
+
+	paths := make([]string, 0)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			paths = append(paths, f.path)
+		}
+	}
+	slices.Sort(paths)
+
+	// Absolute paths are relative to the project root and not prefixed.
+	assert.DeepEqual(t, paths, []string{"README.md", "docs/nested/c.txt", "docs/replaced/output-prefix/a"})
+}
+
+func TestInvalidOutputPrefix(t *testing.T) {
+	for _, prefix := range []string{"/docs", "../docs"} {
+		_, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+			Name:         "testing1",
+			OutputPrefix: prefix,
+		})
+		assert.ErrorContains(t, err, "must be a relative path inside of the project")
+	}
+}
+
+func TestInvalidAllowedEnv(t *testing.T) {
+	for _, name := range []string{"", "CI-REGISTRY", "$CI_REGISTRY", "1CI"} {
+		_, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+			Name:       "testing1",
+			AllowedEnv: []string{name},
+		})
+		assert.ErrorContains(t, err, "which is not a valid environment variable name")
+	}
+}
+
+// createFiles creates the provided files, keyed by their path, with
+// their contents in fs.
+func createFiles(t *testing.T, fs billy.Filesystem, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		f, err := fs.Create(name)
+		assert.NilError(t, err)
+		_, err = f.Write([]byte(contents))
+		assert.NilError(t, err)
+		assert.NilError(t, f.Close())
+	}
+}
+
+func TestCustomTemplateExtensions(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	files := map[string]string{
+		"manifest.yaml":                     "name: testing\ntemplateExtensions: [\".gotmpl\"]\n",
+		"templates/config.yaml.gotmpl":      `name: {{ module.Call "testing.Name" }}`,
+		"templates/helpers.library.gotmpl":  `{{- define "Name" }}{{ return "stencil" }}{{ end }}{{ module.Export "Name" }}`,
+		"templates/not-a-template.txt.tpl":  "ignored",
+		"templates/nested/README.md.gotmpl": "hello",
+	}
+	createFiles(t, fs, files)
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render")
+
+	got := make(map[string]string)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			got[f.path] = f.String()
+		}
+	}
+	assert.DeepEqual(t, got, map[string]string{
+		"config.yaml":      "name: stencil",
+		"nested/README.md": "hello",
+	})
+}
+
+func TestTemplateDirConditions(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	files := map[string]string{
+		"manifest.yaml": `name: testing
+arguments:
+  kind:
+    schema:
+      type: string
+templateDirConditions:
+  service: '{{ eq (stencil.Arg "kind") "service" }}'
+  library: '{{ eq (stencil.Arg "kind") "library" }}'
+`,
+		"templates/README.md.tpl":         "readme",
+		"templates/service/main.go.tpl":   "service",
+		"templates/library/lib.go.tpl":    "library",
+		"templates/library/nested/x.tpl":  "nested",
+		"templates/libraryish/README.tpl": "not gated",
+	}
+	createFiles(t, fs, files)
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{
+		Name:      "test",
+		Arguments: map[string]any{"kind": "service"},
+	}, nil, []*modules.Module{tp}, log, false)
+
+	got, err := st.RenderToMemory(ctx, log)
+	assert.NilError(t, err, "failed to render")
+	assert.DeepEqual(t, got, map[string][]byte{
+		"README.md":         []byte("readme"),
+		"service/main.go":   []byte("service"),
+		"libraryish/README": []byte("not gated"),
+	})
+}
+
+func TestSetFuncs(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":        "name: testing\n",
+		"templates/README.tpl": `{{ shout "hello" }}`,
+	})
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+	assert.NilError(t, st.SetFuncs(template.FuncMap{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+	}))
+
+	got, err := st.RenderToMemory(ctx, log)
+	assert.NilError(t, err, "failed to render")
+	assert.DeepEqual(t, got, map[string][]byte{"README": []byte("HELLO!")})
+}
+
+func TestSetFuncsCantReplaceBuiltins(t *testing.T) {
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, nil, slogext.NewTestLogger(t), false)
+	err := st.SetFuncs(template.FuncMap{
+		"upper":   strings.ToUpper,
+		"stencil": func() string { return "" },
+		"printf":  fmt.Sprintf,
+		"custom":  func() string { return "" },
+	})
+	assert.Error(t, err, "can't replace built-in function(s): printf, stencil, upper")
+}
+
+func TestTemplateDirConditionsMustBeBool(t *testing.T) {
+	tp, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name:                  "testing",
+		TemplateDirConditions: map[string]string{"service": "yes"},
+	})
+	assert.NilError(t, err)
+
+	log := slogext.NewTestLogger(t)
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+	_, err = st.Render(context.Background(), log)
+	assert.ErrorContains(t, err, `templateDirConditions for "service" in module "testing" must render to true or false, got "yes"`)
+}
+
+func TestTagFilter(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	files := map[string]string{
+		"manifest.yaml": `name: testing
+templateTags:
+  docs: [docs]
+  docs/api.md.tpl: [api]
+  ci.yaml.tpl: [ci]
+`,
+		"templates/README.md.tpl":     "readme",
+		"templates/docs/guide.md.tpl": "guide",
+		"templates/docs/api.md.tpl":   "api",
+		"templates/ci.yaml.tpl":       "ci",
+	}
+	createFiles(t, fs, files)
+
+	tests := []struct {
+		name        string
+		tags        []string
+		excludeTags []string
+		want        []string
+	}{
+		{
+			name: "should render everything without a filter",
+			want: []string{"README.md", "ci.yaml", "docs/api.md", "docs/guide.md"},
+		},
+		{
+			name: "should only render templates with a tag",
+			tags: []string{"docs"},
+			want: []string{"docs/api.md", "docs/guide.md"},
+		},
+		{
+			name: "should render templates matching any tag",
+			tags: []string{"api", "ci"},
+			want: []string{"ci.yaml", "docs/api.md"},
+		},
+		{
+			name:        "should not render templates with an excluded tag",
+			excludeTags: []string{"docs"},
+			want:        []string{"README.md", "ci.yaml"},
+		},
+		{
+			name:        "should support combining tags and excluded tags",
+			tags:        []string{"docs"},
+			excludeTags: []string{"api"},
+			want:        []string{"docs/guide.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+			assert.NilError(t, err, "failed to NewWithFS")
+
+			st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+			st.SetTagFilter(tt.tags, tt.excludeTags)
+
+			got, err := st.RenderToMemory(ctx, log)
+			assert.NilError(t, err, "failed to render")
+			assert.DeepEqual(t, slices.Sorted(maps.Keys(got)), tt.want)
+		})
+	}
+}
+
+func TestPostRunReceivesChangedFiles(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	files := map[string]string{
+		"manifest.yaml": `name: testing
+postRunCommand:
+  - name: record changed files
+    command: printf '%s' "$STENCIL_CHANGED_FILES" > changed.txt
+`,
+		"templates/b.txt.tpl":     "b",
+		"templates/a/a.txt.tpl":   "a",
+		"templates/skipped.tpl":   `{{ file.Skip "not needed" }}`,
+		"templates/deleted.tpl":   `{{ file.Delete }}`,
+		"templates/existing.tpl":  "existing",
+		"templates/unchanged.tpl": "unchanged",
+	}
+	createFiles(t, fs, files)
+	assert.NilError(t, os.WriteFile("existing", []byte("old"), 0o644))
+
+	// Files that already exist with the same contents are not changed.
+	assert.NilError(t, os.WriteFile("unchanged", []byte("unchanged"), 0o644))
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render")
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			assert.NilError(t, f.Write(log, false))
+		}
+	}
+
+	assert.NilError(t, st.PostRun(ctx, log, tpls))
+
+	got, err := os.ReadFile("changed.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(got), "a/a.txt\nb.txt\nexisting")
+}
+
+func TestPostRunProjectCommandRunsLast(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	mods := make([]*modules.Module, 0, 2)
+	for _, name := range []string{"module-a", "module-b"} {
+		m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+			Name: name,
+			PostRunCommand: []*configuration.PostRunCommandSpec{{
+				Name:    "record",
+				Command: "echo " + name + " >> order.txt",
+			}},
+		})
+		assert.NilError(t, err)
+		mods = append(mods, m)
+	}
+
+	st := NewStencil(&configuration.Manifest{
+		Name:          "test",
+		SkipMiseTrust: true,
+		PostRunCommand: []*configuration.PostRunCommandSpec{{
+			Name:    "record",
+			Command: "echo project >> order.txt",
+		}},
+	}, nil, mods, log, false)
+	assert.NilError(t, st.PostRun(ctx, log, nil))
+
+	got, err := os.ReadFile("order.txt")
+	assert.NilError(t, err)
+	assert.Equal(t, string(got), "module-a\nmodule-b\nproject\n")
+}
+
+func TestPostRunMiseTrust(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skipMiseTrust=%v", skip), func(t *testing.T) {
+			env.ChangeWorkingDir(t, t.TempDir())
+			ctx := context.Background()
+			log := slogext.NewTestLogger(t)
+
+			// Fake mise that records that it was ran.
+			binDir := t.TempDir()
+			assert.NilError(t, os.WriteFile(filepath.Join(binDir, "mise"),
+				[]byte("#!/usr/bin/env bash\necho \"$@\" > mise-ran.txt\n"), 0o755))
+			t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+			assert.NilError(t, os.WriteFile(".mise.toml", []byte(""), 0o644))
+
+			st := NewStencil(&configuration.Manifest{Name: "test", SkipMiseTrust: skip}, nil, nil, log, false)
+			assert.NilError(t, st.PostRun(ctx, log, nil))
+
+			got, err := os.ReadFile("mise-ran.txt")
+			if skip {
+				assert.Assert(t, errors.Is(err, os.ErrNotExist), "expected mise to not be ran")
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, string(got), "trust --quiet\n")
+		})
+	}
+}
+
+func TestPostRunConditions(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.WriteFile("exists.txt", []byte(""), 0o644))
+
+	assert.Assert(t, postRunConditionMet(nil))
+	assert.Assert(t, postRunConditionMet(&configuration.PostRunCondition{FileExists: "exists.txt", CommandExists: "bash"}))
+	assert.Assert(t, !postRunConditionMet(&configuration.PostRunCondition{FileExists: "missing.txt"}))
+	assert.Assert(t, !postRunConditionMet(&configuration.PostRunCondition{CommandExists: "not-a-real-command"}))
+}
+
 func TestBinaryRender(t *testing.T) {
 	log := slogext.NewTestLogger(t)
 	sm := &configuration.Manifest{Name: "testing", Arguments: map[string]any{"x": "d"}}
@@ -162,6 +597,64 @@ func TestBinaryRender(t *testing.T) {
 	assert.DeepEqual(t, cont, cont2)
 }
 
+func TestBinarySources(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	files := map[string]string{
+		"manifest.yaml": `name: testing
+arguments:
+  platform:
+    schema:
+      type: string
+binarySources:
+  bin/tool: 'assets/tool-{{ stencil.Arg "platform" }}'
+  bin/extra: '{{ if eq (stencil.Arg "platform") "darwin" }}assets/extra{{ end }}'
+`,
+		"assets/tool-linux":       "\x7fELF",
+		"assets/tool-darwin":      "\xcf\xfa\xed\xfe",
+		"assets/extra":            "extra",
+		"templates/README.md.tpl": "readme",
+	}
+	createFiles(t, fs, files)
+
+	for platform, want := range map[string]map[string][]byte{
+		"linux": {"README.md": []byte("readme"), "bin/tool": []byte("\x7fELF")},
+		"darwin": {
+			"README.md": []byte("readme"),
+			"bin/tool":  []byte("\xcf\xfa\xed\xfe"),
+			"bin/extra": []byte("extra"),
+		},
+	} {
+		t.Run(platform, func(t *testing.T) {
+			tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+			assert.NilError(t, err, "failed to NewWithFS")
+			st := NewStencil(&configuration.Manifest{
+				Name:      "test",
+				Arguments: map[string]any{"platform": platform},
+			}, nil, []*modules.Module{tp}, log, false)
+
+			got, err := st.RenderToMemory(ctx, log)
+			assert.NilError(t, err, "failed to render")
+			assert.DeepEqual(t, got, want)
+		})
+	}
+}
+
+func TestBinarySourcesMustBeInModule(t *testing.T) {
+	tp, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name:          "testing",
+		BinarySources: map[string]string{"bin/tool": "../tool"},
+	})
+	assert.NilError(t, err)
+
+	log := slogext.NewTestLogger(t)
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+	_, err = st.Render(context.Background(), log)
+	assert.ErrorContains(t, err, `binarySources for "bin/tool" in module "testing" selected "../tool", which is not inside of the module`)
+}
+
 func TestBadDirReplacement(t *testing.T) {
 	log := slogext.NewTestLogger(t)
 	sm := &configuration.Manifest{Name: "testing"}
@@ -215,6 +708,9 @@ z: {{ stencil.GetGlobal "z" }}`))
 	assert.NilError(t, yaml.Unmarshal(tpls[0].Files[0].contents, &resp), "failed to unmarshal response")
 	assert.DeepEqual(t, resp, map[string]int{"x": 1, "y": 2, "z": 3})
 
+	digest, err := tp.Digest()
+	assert.NilError(t, err, "failed to compute module digest")
+
 	lock := st.GenerateLockfile(tpls)
 	assert.DeepEqual(t, lock, &stencil.Lockfile{
 		Version: version.Version.GitVersion,
@@ -223,6 +719,7 @@ z: {{ stencil.GetGlobal "z" }}`))
 				Name:    "testing",
 				URL:     "vfs://testing",
 				Version: &resolver.Version{Virtual: "vfs"},
+				Digest:  digest,
 			},
 		},
 		Files: []*stencil.LockfileFileEntry{
@@ -234,3 +731,544 @@ z: {{ stencil.GetGlobal "z" }}`))
 		},
 	})
 }
+
+// TestNonStabilizingStateLogsChangedKeys ensures that when shared
+// state never stabilizes the keys that keep changing are logged.
+func TestNonStabilizingStateLogsChangedKeys(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+	log.SetLevel(slogext.DebugLevel)
+
+	f, _ := fs.Create("manifest.yaml")
+	f.Write([]byte("name: testing"))
+	f.Close()
+
+	// x flips between true and false on every iteration, y is stable.
+	f, err := fs.Create("templates/test-template.tpl")
+	assert.NilError(t, err, "failed to create stub template")
+	f.Write([]byte(`{{- stencil.SetGlobal "x" (not (stencil.GetGlobal "x")) }}
+{{- stencil.SetGlobal "y" true }}`))
+	assert.NilError(t, f.Close(), "failed to close stub template")
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	_, err = st.Render(ctx, log)
+	assert.ErrorContains(t, err, "failed to stabilize shared state within 20 iterations")
+
+	// The first iteration adds both globals, after that only x changes.
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, "globals/testing/x"), out)
+	assert.Assert(t, strings.Contains(out, "globals/testing/y"), out)
+	assert.Equal(t, strings.Count(out, "globals/testing/y"), 1, out)
+}
+
+// TestConflictingGlobalsWarn ensures that templates setting the same
+// global to different values are warned about, once.
+func TestConflictingGlobalsWarn(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+
+	f, _ := fs.Create("manifest.yaml")
+	f.Write([]byte("name: testing"))
+	f.Close()
+
+	for name, contents := range map[string]string{
+		"templates/a.tpl": `{{- stencil.SetGlobal "x" "a" }}{{- stencil.SetGlobal "y" true }}`,
+		"templates/b.tpl": `{{- stencil.SetGlobal "x" "b" }}{{- stencil.SetGlobal "y" true }}`,
+	} {
+		f, err := fs.Create(name)
+		assert.NilError(t, err, "failed to create stub template")
+		f.Write([]byte(contents))
+		assert.NilError(t, f.Close(), "failed to close stub template")
+	}
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	_, err = st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	out := buf.String()
+	assert.Equal(t, strings.Count(out, "overwritten with a different value"), 1, out)
+	assert.Assert(t, strings.Contains(out, `Global "x" set by template`), out)
+	assert.Assert(t, strings.Contains(out, `"testing/a.tpl"`), out)
+	assert.Assert(t, strings.Contains(out, `"testing/b.tpl"`), out)
+}
+
+// TestMaxRenderPasses ensures that the number of iterations can be
+// changed in the manifest and that the error lists what was still
+// changing.
+func TestMaxRenderPasses(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	f, _ := fs.Create("manifest.yaml")
+	f.Write([]byte("name: testing"))
+	f.Close()
+
+	// x flips between true and false on every iteration, y is stable.
+	f, err := fs.Create("templates/test-template.tpl")
+	assert.NilError(t, err, "failed to create stub template")
+	f.Write([]byte(`{{- stencil.SetGlobal "x" (not (stencil.GetGlobal "x")) }}
+{{- stencil.SetGlobal "y" true }}`))
+	assert.NilError(t, f.Close(), "failed to close stub template")
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test", MaxRenderPasses: 5}, nil, []*modules.Module{tp}, log, false)
+
+	_, err = st.Render(ctx, log)
+	assert.ErrorContains(t, err, "failed to stabilize shared state within 5 iterations")
+	assert.ErrorContains(t, err, "still changing between the last two iterations: globals/testing/x")
+	assert.Assert(t, !strings.Contains(err.Error(), "globals/testing/y"), err.Error())
+}
+
+// newOptionalExtTestStencil returns a [Stencil] with a template module
+// that renders whether or not the optional extension "test-ext" was
+// loaded. The extension is loaded from extDir, which should contain a
+// bin/plugin for the extension to load successfully.
+func newOptionalExtTestStencil(t *testing.T, extDir string, optional bool) *Stencil {
+	log := slogext.NewTestLogger(t)
+
+	m1, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing1",
+	}, "testdata/optional-ext/m1.tpl")
+	assert.NilError(t, err, "failed to create template module")
+
+	ext, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "test-ext",
+		Type: configuration.TemplateRepositoryTypes{configuration.TemplateRepositoryTypeExt},
+	})
+	assert.NilError(t, err, "failed to create extension module")
+
+	ext.URI = extDir
+	ext.Version = &resolver.Version{Virtual: "local"}
+	ext.Optional = optional
+
+	return NewStencil(&configuration.Manifest{Name: "testing"}, nil, []*modules.Module{m1, ext}, log, false)
+}
+
+func TestOptionalExtensionAbsent(t *testing.T) {
+	ctx := context.Background()
+
+	// Point the extension at a directory without an extension binary so
+	// that loading it fails.
+	st := newOptionalExtTestStencil(t, t.TempDir(), true)
+	defer st.Close()
+
+	assert.NilError(t, st.RegisterExtensions(ctx), "expected optional extension failure to be ignored")
+	assert.Assert(t, !st.ext.HasExtension("test-ext"), "expected extension to not be loaded")
+
+	tpls, err := st.Render(ctx, st.log)
+	assert.NilError(t, err, "failed to render templates")
+	assert.Equal(t, len(tpls), 1)
+	assert.Equal(t, strings.TrimSpace(tpls[0].Files[0].String()), "absent")
+}
+
+func TestOptionalExtensionPresent(t *testing.T) {
+	ctx := context.Background()
+
+	extDir := t.TempDir()
+	cmd := exec.Command("go", "build", "-o", filepath.Join(extDir, "bin", "plugin"), "./testdata/optional-ext/plugin")
+	out, err := cmd.CombinedOutput()
+	assert.NilError(t, err, "failed to build test extension: %s", string(out))
+
+	st := newOptionalExtTestStencil(t, extDir, true)
+	defer st.Close()
+
+	assert.NilError(t, st.RegisterExtensions(ctx), "failed to load optional extension")
+	assert.Assert(t, st.ext.HasExtension("test-ext"), "expected extension to be loaded")
+
+	tpls, err := st.Render(ctx, st.log)
+	assert.NilError(t, err, "failed to render templates")
+	assert.Equal(t, len(tpls), 1)
+	assert.Equal(t, strings.TrimSpace(tpls[0].Files[0].String()), "present")
+}
+
+func TestRequiredExtensionFailsToLoad(t *testing.T) {
+	st := newOptionalExtTestStencil(t, t.TempDir(), false)
+	defer st.Close()
+
+	assert.ErrorContains(t, st.RegisterExtensions(context.Background()), `failed to load extensions from module "test-ext"`)
+}
+
+// TestLockBlocksRecoversRenamedFile ensures that blocks stored in the
+// lockfile are used when the file they came from was renamed.
+func TestLockBlocksRecoversRenamedFile(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+	sm := &configuration.Manifest{Name: "testing", LockBlocks: true}
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+	}, "testdata/lock-blocks/m1.tpl")
+	assert.NilError(t, err, "failed to create module")
+
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.MkdirAll("testdata/lock-blocks", 0o755))
+	assert.NilError(t, os.WriteFile("testdata/lock-blocks/m1",
+		[]byte("generated\n// <<Stencil::Block(custom)>>\nmy content\n// <</Stencil::Block>>\n"), 0o644))
+
+	st := NewStencil(sm, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render templates")
+	lock := st.GenerateLockfile(tpls)
+	assert.DeepEqual(t, lock.Blocks, []*stencil.LockfileBlockEntry{
+		{Module: "testing", Name: "custom", Contents: "my content"},
+	})
+
+	// Simulate the file being renamed outside of stencil.
+	assert.NilError(t, os.Rename("testdata/lock-blocks/m1", "testdata/lock-blocks/renamed"))
+
+	st = NewStencil(sm, lock, []*modules.Module{m}, log, false)
+	tpls, err = st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render templates")
+	assert.Equal(t, tpls[0].Files[0].String(), "generated\n// <<Stencil::Block(custom)>>\nmy content\n// <</Stencil::Block>>\n")
+}
+
+// TestLockBlocksDisabledByDefault ensures that blocks are not stored in
+// the lockfile unless enabled.
+func TestLockBlocksDisabledByDefault(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+	}, "testdata/lock-blocks/m1.tpl")
+	assert.NilError(t, err, "failed to create module")
+
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.MkdirAll("testdata/lock-blocks", 0o755))
+	assert.NilError(t, os.WriteFile("testdata/lock-blocks/m1",
+		[]byte("generated\n// <<Stencil::Block(custom)>>\nmy content\n// <</Stencil::Block>>\n"), 0o644))
+
+	st := NewStencil(&configuration.Manifest{Name: "testing"}, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render templates")
+	assert.Assert(t, st.GenerateLockfile(tpls).Blocks == nil)
+}
+
+// TestLockBlocksRecoversRenamedTemplate ensures that blocks stored in
+// the lockfile are used when the template generating the file they
+// came from was renamed.
+func TestLockBlocksRecoversRenamedTemplate(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+	sm := &configuration.Manifest{Name: "testing", LockBlocks: true}
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+	}, "testdata/lock-blocks/m1.tpl")
+	assert.NilError(t, err, "failed to create module")
+
+	// A new version of the module, where the template was renamed and
+	// generates the file at a new path.
+	renamed, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+	}, "testdata/lock-blocks/renamed.tpl")
+	assert.NilError(t, err, "failed to create module")
+
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.MkdirAll("testdata/lock-blocks", 0o755))
+	assert.NilError(t, os.WriteFile("testdata/lock-blocks/m1",
+		[]byte("generated\n// <<Stencil::Block(custom)>>\nmy content\n// <</Stencil::Block>>\n"), 0o644))
+
+	st := NewStencil(sm, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render templates")
+	lock := st.GenerateLockfile(tpls)
+
+	assert.NilError(t, os.Remove("testdata/lock-blocks/m1"))
+	st = NewStencil(sm, lock, []*modules.Module{renamed}, log, false)
+	tpls, err = st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render templates")
+	assert.Equal(t, tpls[0].Files[0].Name(), "testdata/lock-blocks/renamed")
+	assert.Equal(t, tpls[0].Files[0].String(), "generated\n// <<Stencil::Block(custom)>>\nmy content\n// <</Stencil::Block>>\n")
+}
+
+// TestLockBlocksSkipsAmbiguousBlocks ensures that blocks with the same
+// name but different contents in multiple files of a module are not
+// stored in the lockfile.
+func TestLockBlocksSkipsAmbiguousBlocks(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+	sm := &configuration.Manifest{Name: "testing", LockBlocks: true}
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+	}, "testdata/lock-blocks/m1.tpl", "testdata/lock-blocks/renamed.tpl")
+	assert.NilError(t, err, "failed to create module")
+
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.MkdirAll("testdata/lock-blocks", 0o755))
+	for _, name := range []string{"m1", "renamed"} {
+		assert.NilError(t, os.WriteFile("testdata/lock-blocks/"+name,
+			[]byte("generated\n// <<Stencil::Block(custom)>>\n"+name+"\n// <</Stencil::Block>>\n"), 0o644))
+	}
+
+	st := NewStencil(sm, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render templates")
+	assert.Assert(t, st.GenerateLockfile(tpls).Blocks == nil)
+}
+
+func TestWarnsOnUnusedModuleHooks(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+
+	f, _ := fs.Create("manifest.yaml")
+	f.Write([]byte("name: testing\nmoduleHooks:\n  used: {}\n  unused: {}\n"))
+	f.Close()
+
+	f, err := fs.Create("templates/test-template.tpl")
+	assert.NilError(t, err, "failed to create stub template")
+	f.Write([]byte(`{{- stencil.AddToModuleHook "testing" "used" "data" }}`))
+	assert.NilError(t, f.Close(), "failed to close stub template")
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	_, err = st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	assert.Assert(t, strings.Contains(buf.String(), "module=testing hook=unused"), buf.String())
+	assert.Assert(t, !strings.Contains(buf.String(), "hook=used"), buf.String())
+}
+
+func TestWarnsOnOrphanedMigratedBlocks(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	fs := memfs.New()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	log := slogext.NewWithWriter(&buf)
+
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":         "name: testing\n",
+		"templates/old.txt.tpl": `{{- file.MigrateTo "new.txt" }}`,
+		"templates/new.txt.tpl": "## <<Stencil::Block(kept)>>\n{{ file.Block \"kept\" }}\n## <</Stencil::Block>>\n",
+	})
+	assert.NilError(t, os.WriteFile("old.txt", []byte(
+		"## <<Stencil::Block(kept)>>\nkept\n## <</Stencil::Block>>\n"+
+			"## <<Stencil::Block(lost)>>\nlost code\n## <</Stencil::Block>>\n"+
+			"## <<Stencil::Block(empty)>>\n## <</Stencil::Block>>\n",
+	), 0o644))
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	_, err = st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	assert.Assert(t, strings.Contains(buf.String(), "are not declared at its new path"), buf.String())
+	assert.Assert(t, strings.Contains(buf.String(), `blocks="lost (9 bytes)"`), buf.String())
+}
+
+func TestRenderedFile(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":              "name: testing\n",
+		"templates/app.txt.tpl":      `{{- stencil.RenderedFile "testing/config.yaml.tpl" }}`,
+		"templates/config.yaml.tpl":  "name: {{ .Config.Name }}\n",
+		"templates/README.md.tpl":    "```yaml\n{{ stencil.RenderedFile \"testing/config.yaml.tpl\" }}```\n",
+		"templates/skipped.txt.tpl":  `{{- file.Skip "not needed" }}`,
+		"templates/uses-skipped.tpl": `{{- stencil.RenderedFile "testing/skipped.txt.tpl" }}`,
+	})
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+	_, err = st.RenderToMemory(ctx, log)
+	assert.ErrorContains(t, err, `template "testing/skipped.txt.tpl" does not exist or did not render a file`)
+
+	assert.NilError(t, fs.Remove("templates/uses-skipped.tpl"))
+	tp, err = modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st = NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+	files, err := st.RenderToMemory(ctx, log)
+	assert.NilError(t, err)
+	assert.Equal(t, string(files["README.md"]), "```yaml\nname: test\n```\n")
+
+	// The contents must not depend on whether a template is rendered
+	// before or after config.yaml during the final render.
+	assert.Equal(t, string(files["app.txt"]), "name: test\n")
+}
+
+func TestFileModes(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":                  "name: testing\nfileModes:\n  scripts/*.sh: \"0755\"\n",
+		"templates/scripts/build.sh.tpl": "#!/usr/bin/env bash",
+		"templates/scripts/lib.sh.tpl":   "{{- file.SetMode 0o644 }}",
+		"templates/README.md.tpl":        "# README",
+	})
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	modes := make(map[string]os.FileMode)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			modes[f.Name()] = f.Mode()
+		}
+	}
+	assert.Equal(t, modes["scripts/build.sh"], os.FileMode(0o755))
+	assert.Equal(t, modes["scripts/lib.sh"], os.FileMode(0o644))
+	assert.Assert(t, modes["README.md"] != os.FileMode(0o755), "README.md should keep the mode of its template")
+}
+
+// newModuleHookItemsTestStencil returns a [Stencil] with a module
+// declaring a module hook that accepts at most one item and a module
+// per contributor that adds an item to it.
+func newModuleHookItemsTestStencil(t *testing.T, contributors ...string) *Stencil {
+	owner, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "owner",
+		ModuleHooks: map[string]configuration.ModuleHook{
+			"entrypoint": {MinItems: 1, MaxItems: 1},
+		},
+	})
+	assert.NilError(t, err)
+
+	mods := []*modules.Module{owner}
+	for _, c := range contributors {
+		fs := memfs.New()
+		f, err := fs.Create("templates/contribute.tpl")
+		assert.NilError(t, err)
+		_, err = f.Write([]byte(`{{- stencil.AddToModuleHook "owner" "entrypoint" "` + c + `" }}`))
+		assert.NilError(t, err)
+		assert.NilError(t, f.Close())
+
+		f, err = fs.Create("manifest.yaml")
+		assert.NilError(t, err)
+		_, err = f.Write([]byte("name: " + c))
+		assert.NilError(t, err)
+		assert.NilError(t, f.Close())
+
+		m, err := modulestest.NewWithFS(context.Background(), c, fs)
+		assert.NilError(t, err)
+		mods = append(mods, m)
+	}
+
+	return NewStencil(&configuration.Manifest{Name: "test"}, nil, mods, slogext.NewTestLogger(t), false)
+}
+
+func TestModuleHookItemsWithinLimit(t *testing.T) {
+	st := newModuleHookItemsTestStencil(t, "a")
+	_, err := st.Render(context.Background(), slogext.NewTestLogger(t))
+	assert.NilError(t, err)
+}
+
+func TestModuleHookItemsOverLimit(t *testing.T) {
+	st := newModuleHookItemsTestStencil(t, "a", "b")
+	_, err := st.Render(context.Background(), slogext.NewTestLogger(t))
+	assert.Error(t, err, `module hook "entrypoint" of module "owner" expects at most 1 item(s), `+
+		"got 2 (contributed by: a/contribute.tpl, b/contribute.tpl)")
+}
+
+func TestModuleHookItemsUnderLimit(t *testing.T) {
+	st := newModuleHookItemsTestStencil(t)
+	_, err := st.Render(context.Background(), slogext.NewTestLogger(t))
+	assert.Error(t, err, `module hook "entrypoint" of module "owner" expects at least 1 item(s), got 0`)
+}
+
+func TestRenderToMemory(t *testing.T) {
+	fs := memfs.New()
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	files := map[string]string{
+		"manifest.yaml":           "name: testing",
+		"templates/a.txt.tpl":     "{{ .Config.Name }}",
+		"templates/dir/b.txt.tpl": "b",
+		"templates/skipped.tpl":   `{{ file.Skip "not needed" }}`,
+	}
+	createFiles(t, fs, files)
+
+	tp, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{tp}, log, false)
+
+	// Render from an empty directory to ensure nothing is written to
+	// disk.
+	dir := t.TempDir()
+	env.ChangeWorkingDir(t, dir)
+
+	got, err := st.RenderToMemory(ctx, log)
+	assert.NilError(t, err, "expected RenderToMemory() to not fail")
+	assert.DeepEqual(t, got, map[string][]byte{
+		"a.txt":     []byte("test"),
+		"dir/b.txt": []byte("b"),
+	})
+
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0, "expected no files to be written to disk")
+}
+
+func TestProjectValuesSharedAcrossModules(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	newModule := func(name, tpl string) *modules.Module {
+		fs := memfs.New()
+		createFiles(t, fs, map[string]string{
+			"manifest.yaml":              "name: " + name + "\n",
+			"templates/" + name + ".tpl": tpl,
+		})
+		m, err := modulestest.NewWithFS(ctx, name, fs)
+		assert.NilError(t, err, "failed to NewWithFS")
+		return m
+	}
+
+	a := newModule("a", `{{ stencil.Project "org" }}`)
+	b := newModule("b", `{{ stencil.Project "license.name" }} {{ stencil.Project "unset" | default "fallback" }}`)
+
+	st := NewStencil(&configuration.Manifest{
+		Name: "test",
+		Project: map[string]any{
+			"org":     "rgst-io",
+			"license": map[string]any{"name": "Apache-2.0"},
+		},
+	}, nil, []*modules.Module{a, b}, log, false)
+
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err, "failed to render")
+
+	got := make(map[string]string)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			got[f.path] = f.String()
+		}
+	}
+	assert.DeepEqual(t, got, map[string]string{
+		"a": "rgst-io",
+		"b": "Apache-2.0 fallback",
+	})
+}
@@ -19,6 +19,7 @@ package codegen
 
 import (
 	"bytes"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
@@ -73,6 +74,16 @@ type Template struct {
 	// Library denotes if a template is a library template or not. Library
 	// templates cannot generate files.
 	Library bool
+
+	// ext is the template extension (e.g., .tpl) that is stripped from
+	// Path to determine the path of the generated file.
+	ext string
+
+	// includes is the stack of the import paths of the templates that
+	// define the named templates being executed through stencil.Include,
+	// used to attribute the blocks they render (see
+	// [File.RenderedBlocks]).
+	includes []string
 }
 
 type NewTemplateOpts struct {
@@ -84,12 +95,20 @@ type NewTemplateOpts struct {
 }
 
 // NewTemplate creates a new Template with the current file being the same name
-// with the extension .tpl being removed. If the provided template has
-// the extension .library.tpl, then the Library field is set to true.
+// with the template extension (.tpl, unless configured otherwise by the
+// module's manifest) being removed. If the provided template has the
+// extension .library.tpl, then the Library field is set to true.
 func NewTemplate(m *modules.Module, fpath string, mode os.FileMode,
 	modTime time.Time, contents []byte, log slogext.Logger, opts *NewTemplateOpts) (*Template, error) {
+	ext := ".tpl"
+	if m != nil {
+		if e, ok := m.Manifest.TemplateExtension(fpath); ok {
+			ext = e
+		}
+	}
+
 	var library bool
-	if filepath.Ext(strings.TrimSuffix(fpath, ".tpl")) == ".library" {
+	if filepath.Ext(strings.TrimSuffix(fpath, ext)) == ".library" {
 		library = true
 	}
 
@@ -101,6 +120,7 @@ func NewTemplate(m *modules.Module, fpath string, mode os.FileMode,
 		Path:     fpath,
 		Contents: contents,
 		Library:  library,
+		ext:      ext,
 	}
 
 	if opts != nil {
@@ -119,13 +139,19 @@ func (t *Template) ImportPath() string {
 
 // Parse parses the provided template and makes it available to be Rendered
 // in the context of the current module.
-func (t *Template) Parse(_ *Stencil) error {
+func (t *Template) Parse(st *Stencil) error {
 	if !t.Binary {
+		// Functions set by embedders must be known at parse time.
+		funcs := NewFuncMap(nil, nil, t.log)
+		if st != nil {
+			maps.Copy(funcs, st.funcs)
+		}
+
 		// Add the current template to the template object on the module that we're
 		// attached to. This enables us to call functions in other templates within our
 		// 'module context'.
-		if _, err := t.Module.GetTemplate().New(t.ImportPath()).Funcs(NewFuncMap(nil, nil, t.log)).
-			Parse(string(t.Contents)); err != nil {
+		if _, err := t.Module.GetTemplate().New(t.ImportPath()).Funcs(funcs).
+			Funcs(helperFuncs(t, nil)).Parse(string(t.Contents)); err != nil {
 			return err
 		}
 	}
@@ -143,13 +169,24 @@ func (t *Template) Render(st *Stencil, vals *Values) error {
 		if t.Binary {
 			p = strings.TrimSuffix(t.Path, ".nontpl")
 		} else {
-			p = strings.TrimSuffix(t.Path, ".tpl")
+			p = strings.TrimSuffix(t.Path, t.ext)
 		}
-		p = t.Module.ApplyDirReplacements(p)
-		f, err := NewFile(p, t.mode, t.modTime, t)
+
+		// Modes declared in the manifest take precedence over the mode of
+		// the template itself.
+		mode := t.mode
+		if m, ok, err := t.Module.Manifest.FileMode(p); err != nil {
+			return err
+		} else if ok {
+			mode = m
+		}
+
+		p = t.Module.ApplyOutputPrefix(t.Module.ApplyDirReplacements(p))
+		f, err := NewFile(p, mode, t.modTime, t)
 		if err != nil {
 			return err
 		}
+		f.recoverBlocksFromLockfile(st.lock)
 		t.Files = []*File{f}
 	}
 
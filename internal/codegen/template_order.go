@@ -0,0 +1,103 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for ordering templates based on
+// the dependencies declared between them.
+
+package codegen
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// orderTemplates returns the provided templates ordered so that every
+// template comes after the templates it depends on, as declared in the
+// templateDependsOn of its module's manifest. Otherwise, the order of
+// the provided templates is kept. Dependencies in one of the provided
+// excluded directories (import paths of directories skipped by
+// templateDirConditions) are treated as satisfied. An error is returned
+// if a dependency does not exist or the dependencies contain a cycle.
+func orderTemplates(tpls []*Template, excludedDirs map[string]bool) ([]*Template, error) {
+	byImportPath := make(map[string]*Template, len(tpls))
+	for _, t := range tpls {
+		byImportPath[t.ImportPath()] = t
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[*Template]int, len(tpls))
+	ordered := make([]*Template, 0, len(tpls))
+
+	// stack contains the import paths of the templates being visited,
+	// used to report cycles.
+	var stack []string
+	var visit func(t *Template) error
+	visit = func(t *Template) error {
+		switch state[t] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("templateDependsOn contains a cycle: %s -> %s",
+				strings.Join(stack, " -> "), t.ImportPath())
+		}
+
+		state[t] = visiting
+		stack = append(stack, t.ImportPath())
+		for _, dep := range t.Module.Manifest.TemplateDependsOn[t.Path] {
+			depPath := path.Join(t.Module.Name, dep)
+			depTpl, ok := byImportPath[depPath]
+			if !ok && inExcludedDir(depPath, t.Module.Name, excludedDirs) {
+				continue
+			}
+			if !ok {
+				return fmt.Errorf("template %q depends on %q, which is not a template of module %q that is rendered",
+					t.ImportPath(), dep, t.Module.Name)
+			}
+
+			if err := visit(depTpl); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[t] = visited
+
+		ordered = append(ordered, t)
+		return nil
+	}
+
+	for _, t := range tpls {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// inExcludedDir returns true if the template with the provided import
+// path is in one of the excluded directories of its module.
+func inExcludedDir(importPath, moduleName string, excludedDirs map[string]bool) bool {
+	for dir := path.Dir(importPath); dir != moduleName && dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if excludedDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package codegen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+// renderWithDependsOn renders a module with the templates a, b, c and d
+// and the provided templateDependsOn, returning the import paths of the
+// templates in the order they were rendered.
+func renderWithDependsOn(t *testing.T, dependsOn string) ([]string, error) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	fs := memfs.New()
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":   "name: testing\ntemplateDependsOn:\n" + dependsOn,
+		"templates/a.tpl": "a",
+		"templates/b.tpl": "b",
+		"templates/c.tpl": "c",
+		"templates/d.tpl": "d",
+	})
+	m, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]string, 0, len(tpls))
+	for _, tpl := range tpls {
+		order = append(order, tpl.ImportPath())
+	}
+	return order, nil
+}
+
+func TestTemplateDependsOnOrdering(t *testing.T) {
+	// Templates are shuffled, so render a few times to make sure the
+	// order isn't a coincidence.
+	for range 10 {
+		order, err := renderWithDependsOn(t, "  c.tpl: [b.tpl]\n  b.tpl: [a.tpl, d.tpl]\n")
+		assert.NilError(t, err)
+
+		index := make(map[string]int)
+		for i, p := range order {
+			index[p] = i
+		}
+		assert.Assert(t, index["testing/a.tpl"] < index["testing/b.tpl"], "a must render before b: %v", order)
+		assert.Assert(t, index["testing/d.tpl"] < index["testing/b.tpl"], "d must render before b: %v", order)
+		assert.Assert(t, index["testing/b.tpl"] < index["testing/c.tpl"], "b must render before c: %v", order)
+	}
+}
+
+func TestTemplateDependsOnCycle(t *testing.T) {
+	_, err := renderWithDependsOn(t, "  a.tpl: [b.tpl]\n  b.tpl: [c.tpl]\n  c.tpl: [a.tpl]\n")
+	assert.ErrorContains(t, err, "templateDependsOn contains a cycle")
+}
+
+func TestTemplateDependsOnMissing(t *testing.T) {
+	_, err := renderWithDependsOn(t, "  a.tpl: [missing.tpl]\n")
+	assert.ErrorContains(t, err, `template "testing/a.tpl" depends on "missing.tpl"`)
+}
+
+func TestTemplateDependsOnExcludedDirectory(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	fs := memfs.New()
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml": "name: testing\n" +
+			"templateDirConditions:\n  optional: 'false'\n" +
+			"templateDependsOn:\n  a.tpl: [optional/b.tpl]\n",
+		"templates/a.tpl":          "a",
+		"templates/optional/b.tpl": "b",
+	})
+	m, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	order := make([]string, 0, len(tpls))
+	for _, tpl := range tpls {
+		order = append(order, tpl.ImportPath())
+	}
+	assert.DeepEqual(t, order, []string{"testing/a.tpl"})
+}
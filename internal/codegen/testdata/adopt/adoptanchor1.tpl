@@ -0,0 +1,20 @@
+global:
+  deploymentEnvironment: prod
+	version: xyz
+  otherField: 1
+local:
+  deploymentEnvironment: prod
+{{- /* <<Stencil::AdoptAfter(version)>> "  deploymentEnvironmentx: prod" */}}
+{{- /* <<Stencil::AdoptBefore(version)>> "  otherField: 2" */}}
+## <<Stencil::Block(version)>>
+{{- if empty (trim (file.Block "version")) }}
+  version: latest
+{{- else }}
+{{ file.Block "version"}}
+{{- end }}
+## <</Stencil::Block>>
+  otherField: 2
+somechart:
+  somestuff: 4
+somechart:
+  somestuff: 4
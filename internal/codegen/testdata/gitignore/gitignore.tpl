@@ -0,0 +1,4 @@
+{{- stencil.AddGitignore "dist/" ".cache/" }}
+{{- range stencil.GetGitignore }}
+{{ . }}
+{{- end }}
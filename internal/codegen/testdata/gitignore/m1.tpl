@@ -0,0 +1,2 @@
+{{- file.Skip "only adds gitignore patterns" }}
+{{- stencil.AddGitignore "node_modules/" "dist/" }}
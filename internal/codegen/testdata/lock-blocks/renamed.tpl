@@ -0,0 +1,4 @@
+generated
+// <<Stencil::Block(custom)>>
+{{ file.Block "custom" }}
+// <</Stencil::Block>>
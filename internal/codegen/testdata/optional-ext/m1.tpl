@@ -0,0 +1 @@
+{{- if stencil.HasExtension "test-ext" }}present{{ else }}absent{{ end }}
@@ -0,0 +1,40 @@
+// Copyright (C) 2024 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Description: A native extension that implements no template
+// functions, used to test loading extensions.
+
+package main
+
+import (
+	"go.rgst.io/stencil/v2/pkg/extensions/apiv1"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+)
+
+// noopExtension is an extension that implements no template functions.
+type noopExtension struct{}
+
+func (noopExtension) GetConfig() (*apiv1.Config, error) { return &apiv1.Config{}, nil }
+
+func (noopExtension) GetTemplateFunctions() ([]*apiv1.TemplateFunction, error) { return nil, nil }
+
+func (noopExtension) ExecuteTemplateFunction(*apiv1.TemplateFunctionExec) (any, error) {
+	return nil, nil
+}
+
+func main() {
+	if err := apiv1.NewExtensionImplementation(noopExtension{}, slogext.New()); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,2 @@
+{{- file.SetPath "/README.md" }}
+b
@@ -0,0 +1,2 @@
+{{- file.SetPath "nested/c.txt" }}
+c
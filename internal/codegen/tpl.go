@@ -20,8 +20,11 @@ package codegen
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"text/template"
 
+	"github.com/Masterminds/sprig/v3"
 	"go.rgst.io/stencil/v2/internal/modules/nativeext"
 	"go.rgst.io/stencil/v2/pkg/slogext"
 )
@@ -45,7 +48,10 @@ func NewFuncMap(st *Stencil, t *Template, log slogext.Logger) template.FuncMap {
 	}
 
 	// build the function map
-	funcs := Default
+	funcs := maps.Clone(Default)
+	if st != nil {
+		maps.Copy(funcs, st.funcs)
+	}
 	funcs["stencil"] = func() *TplStencil { return tplst }
 	funcs["file"] = func() *TplFile {
 		if tplf == nil {
@@ -62,5 +68,45 @@ func NewFuncMap(st *Stencil, t *Template, log slogext.Logger) template.FuncMap {
 		return "", fmt.Errorf("'return' can only be called during a module.Call")
 	}
 
+	if t != nil {
+		maps.Copy(funcs, helperFuncs(t, tplm))
+	}
+
+	return funcs
+}
+
+// textTemplateBuiltins are the functions built into text/template.
+var textTemplateBuiltins = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or", "print", "printf",
+	"println", "urlquery", "eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// isBuiltinFunc returns true if the provided name is the name of a
+// function that is available to every template, regardless of the
+// functions set with [Stencil.SetFuncs].
+func isBuiltinFunc(name string) bool {
+	switch name {
+	case "stencil", "file", "extensions", "module", "return":
+		return true
+	}
+	if _, ok := Default[name]; ok {
+		return true
+	}
+	if _, ok := sprig.TxtFuncMap()[name]; ok {
+		return true
+	}
+	return slices.Contains(textTemplateBuiltins, name)
+}
+
+// helperFuncs returns the helpers declared by the module of the
+// provided template, which are callable directly by name through
+// tplm. See [configuration.TemplateRepositoryManifest.Helpers].
+func helperFuncs(t *Template, tplm *TplModule) template.FuncMap {
+	funcs := make(template.FuncMap, len(t.Module.Manifest.Helpers))
+	for _, name := range t.Module.Manifest.Helpers {
+		funcs[name] = func(args ...any) (any, error) {
+			return tplm.Call(t.Module.Name+"."+name, args...)
+		}
+	}
 	return funcs
 }
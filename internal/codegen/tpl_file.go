@@ -6,13 +6,18 @@
 package codegen
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/go-git/go-billy/v5/osfs"
 	"go.rgst.io/stencil/v2/pkg/slogext"
 	"go.rgst.io/stencil/v2/pkg/stencil"
+	"golang.org/x/text/encoding/ianaindex"
 )
 
 // TplFile is the current file we're writing output to in a
@@ -52,16 +57,65 @@ type TplFile struct {
 //	{{ file.Block "name" }}
 //	## <</Stencil::Block>>
 func (f *TplFile) Block(name string) string {
+	f.recordBlockTemplate(name)
 	return f.f.Block(name)
 }
 
+// recordBlockTemplate records the template currently being executed as
+// the one that rendered the provided block of the current file.
+func (f *TplFile) recordBlockTemplate(name string) {
+	switch {
+	case f.t == nil:
+		return
+	case len(f.t.includes) > 0:
+		f.f.setBlockTemplate(name, f.t.includes[len(f.t.includes)-1])
+	default:
+		f.f.setBlockTemplate(name, f.t.ImportPath())
+	}
+}
+
+// AppendBlock returns the contents of a given block with the provided
+// content appended to it on a new line. This is useful for keeping the
+// existing contents of a block while enforcing that it contains some
+// lines. If the block doesn't exist yet, only the provided content is
+// returned. Like file.Block, the returned string never ends with a
+// newline.
+//
+// Because the block on disk will contain the appended content after
+// the first run, the content is not appended again if the block
+// already ends with it.
+//
+//	## <<Stencil::Block(name)>>
+//	{{ file.AppendBlock "name" "enforced: true" }}
+//	## <</Stencil::Block>>
+func (f *TplFile) AppendBlock(name, content string) string {
+	f.recordBlockTemplate(name)
+	existing := f.f.Block(name)
+	content = strings.TrimRight(content, "\n")
+	switch {
+	case existing == "":
+		return content
+	case content == "", strings.HasSuffix("\n"+existing, "\n"+content):
+		return existing
+	default:
+		return existing + "\n" + content
+	}
+}
+
 // SetPath changes the path of the current file being rendered
 //
+// If the module has an outputPrefix, it is prepended to the path unless
+// the path is absolute, in which case it is relative to the root of the
+// project.
+//
 //	{{- file.SetPath "new/path/to/file.txt" }}
 func (f *TplFile) SetPath(path string) (out string, err error) {
-	path = f.t.Module.ApplyDirReplacements(path)
-	err = f.f.SetPath(path)
-	return "", err
+	path = f.t.Module.ApplyOutputPrefix(f.t.Module.ApplyDirReplacements(path))
+	if err := f.f.SetPath(path); err != nil {
+		return "", err
+	}
+	f.f.recoverBlocksFromLockfile(f.lock)
+	return "", nil
 }
 
 // SetContents sets the contents of file being rendered to the value
@@ -74,6 +128,107 @@ func (f *TplFile) SetContents(contents string) error {
 	return nil
 }
 
+// AppendDocument appends a YAML document to the contents of the file
+// being rendered, separating it from the previous document with "---".
+// The first document appended is not prefixed with a separator. Like
+// file.SetContents, the output of the template is not used for the
+// file once a document has been appended.
+//
+//	{{- range $name := list "a" "b" }}
+//	{{- file.AppendDocument (toYaml (dict "kind" "ConfigMap" "name" $name)) }}
+//	{{- end }}
+func (f *TplFile) AppendDocument(doc string) string {
+	doc = strings.TrimRight(strings.TrimPrefix(doc, "---\n"), "\n") + "\n"
+
+	contents := f.f.String()
+	if contents == "" {
+		f.f.SetContents(doc)
+		return ""
+	}
+
+	if !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	f.f.SetContents(contents + "---\n" + doc)
+	return ""
+}
+
+// SetMode sets the mode (permissions) of the current file, overriding
+// the mode of the template and any default from fileModes in the
+// module's manifest.
+//
+//	{{- if stencil.Arg "executable" }}
+//	{{- file.SetMode 0o755 }}
+//	{{- end }}
+func (f *TplFile) SetMode(mode int) (string, error) {
+	if mode < 0 || mode > 0o7777 {
+		return "", fmt.Errorf("invalid file mode %o", mode)
+	}
+	f.f.SetMode(os.FileMode(mode))
+	return "", nil
+}
+
+// SetOwner sets the owner (uid) and group (gid) of the current file.
+// This is best-effort, if stencil lacks the privileges to change the
+// owner of the file (e.g., isn't ran as root) a warning is logged
+// instead. Not supported on Windows. A value of -1 leaves the
+// respective id unchanged.
+//
+//	{{- file.SetOwner 1000 1000 }}
+func (f *TplFile) SetOwner(uid, gid int) (string, error) {
+	f.f.SetOwner(uid, gid)
+	return "", nil
+}
+
+// Symlink makes the current file a symlink to the provided target,
+// which must be a relative path and is resolved relative to the
+// directory of the file. Any existing file at its path is replaced
+// and the contents rendered by the template are ignored. On platforms
+// without symlink support (e.g., Windows) a warning is logged and the
+// file is not created.
+//
+//	{{- file.Symlink "v2" }}
+func (f *TplFile) Symlink(target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("symlink target must not be empty")
+	}
+	if filepath.IsAbs(target) {
+		return "", fmt.Errorf("symlink target %q must be a relative path", target)
+	}
+	f.f.SetSymlink(target)
+	return "", nil
+}
+
+// SetEncoding sets the encoding of the current file, its rendered
+// (UTF-8) contents are transcoded to it when the file is written. The
+// encoding is looked up by its IANA name (e.g., "iso-8859-1" or
+// "windows-1252"). Characters that can't be represented in the
+// encoding cause an error. Binary files are always written as-is. The
+// blocks of the existing file are decoded from the encoding, so it must
+// be set before any block is read (e.g., with file.Block).
+//
+//	{{- file.SetEncoding "iso-8859-1" }}
+func (f *TplFile) SetEncoding(name string) (string, error) {
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return "", fmt.Errorf("unsupported encoding %q", name)
+	}
+	if err := f.f.SetEncoding(enc); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// AllowEmpty allows the current file to be empty. By default, a
+// warning is logged when a generated file is empty, as that's usually
+// caused by a bug in the template.
+//
+//	{{- file.AllowEmpty }}
+func (f *TplFile) AllowEmpty() string {
+	f.f.AllowEmpty()
+	return ""
+}
+
 // Skip skips the current file being rendered
 //
 //	{{- file.Skip "A reason to skip this file" }}
@@ -83,6 +238,19 @@ func (f *TplFile) Skip(reason string) (output string, err error) {
 	return "", nil
 }
 
+// SkipIf skips the current file being rendered if the provided
+// condition is truthy, following the same rules as the "if" action
+// (e.g., false, 0, nil and empty strings, lists and maps are not
+// truthy).
+//
+//	{{- file.SkipIf (not (stencil.Arg "docs")) "Docs are disabled" }}
+func (f *TplFile) SkipIf(cond any, reason string) (output string, err error) {
+	if truth, _ := template.IsTrue(cond); !truth {
+		return "", nil
+	}
+	return f.Skip(reason)
+}
+
 // Delete deletes the current file being rendered
 //
 //	{{- file.Delete }}
@@ -140,6 +308,21 @@ func (f *TplFile) Once() (out string, err error) {
 	return "", nil
 }
 
+// Untrack hands the current file over to the user: it is only
+// generated if it doesn't already exist, like file.Static, and is never
+// stored in the stencil.lock file. Files that were previously stored in
+// the lockfile are removed from it, so they are no longer considered
+// owned by stencil (e.g., by file.Once or when pruning the lockfile).
+//
+//	{{- file.Untrack }}
+func (f *TplFile) Untrack() (out string, err error) {
+	if f.lock != nil {
+		f.lock.Files = slices.DeleteFunc(f.lock.Files, func(ff *stencil.LockfileFileEntry) bool { return ff.Name == f.f.path })
+	}
+	f.f.untracked = true
+	return f.Static()
+}
+
 // Path returns the current path of the file we're writing to
 //
 //	{{ file.Path }}
@@ -171,10 +354,11 @@ func (f *TplFile) Path() string {
 //	{{- stencil.Include "command" | file.SetContents }}
 //	{{- end }}
 func (f *TplFile) Create(path string, mode os.FileMode, modTime time.Time) (out string, err error) {
-	f.f, err = NewFile(path, mode, modTime, f.t)
+	f.f, err = NewFile(f.t.Module.ApplyOutputPrefix(path), mode, modTime, f.t)
 	if err != nil {
 		return "", err
 	}
+	f.f.recoverBlocksFromLockfile(f.lock)
 
 	f.t.Files = append(f.t.Files, f.f)
 	return "", nil
@@ -195,6 +379,10 @@ func (f *TplFile) RemoveAll(path string) (out string, err error) {
 // path (via a create and write, then delete of the old path, not a filesystem move).  If
 // the MigrateTo target file already exists, it is overwritten.
 //
+// Blocks of the old file that are not declared by the template that
+// renders the new path would be lost, so a warning listing them is
+// logged after rendering.
+//
 //	{{- file.MigrateTo "new/path/to/file.txt" }}
 func (f *TplFile) MigrateTo(path string) (out string, err error) {
 	if _, err := osfs.Default.Stat(f.f.path); err != nil {
@@ -203,6 +391,9 @@ func (f *TplFile) MigrateTo(path string) (out string, err error) {
 		return f.Skip("MigrateTo file input doesn't exist")
 	}
 
+	if f.t != nil {
+		path = f.t.Module.ApplyOutputPrefix(path)
+	}
 	f.log.With("path", f.f.path).With("to", path).
 		Debug("Migrating file to new path")
 	contents, err := os.ReadFile(f.f.path)
@@ -223,6 +414,7 @@ func (f *TplFile) MigrateTo(path string) (out string, err error) {
 	f.log.With("path", f.f.path).
 		Debug("Deleting original file after migration")
 	f.f.Deleted = true
+	f.f.migratedTo = path
 
 	return "", nil
 }
@@ -1,13 +1,21 @@
 package codegen
 
 import (
+	"context"
 	"os"
 	"path"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
 	"go.rgst.io/stencil/v2/pkg/slogext"
 	"go.rgst.io/stencil/v2/pkg/stencil"
+	"golang.org/x/text/encoding/charmap"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
 )
 
 // TestTplFile_DeleteNoLockfile tests the file.Delete command when there's no lockfile history at all
@@ -91,6 +99,126 @@ func TestTplFile_OnceFileAlreadyExists(t *testing.T) {
 	assert.Equal(t, true, tplf.f.Skipped)
 }
 
+func TestTplFile_SkipIf(t *testing.T) {
+	for _, cond := range []any{true, "yes", 1, []string{"a"}} {
+		tplf := TplFile{f: &File{path: "test.go"}}
+
+		out, err := tplf.SkipIf(cond, "reason")
+		assert.NilError(t, err)
+		assert.Equal(t, "", out)
+		assert.Equal(t, true, tplf.f.Skipped, "expected %v to skip", cond)
+		assert.Equal(t, "reason", tplf.f.SkippedReason)
+	}
+
+	for _, cond := range []any{false, "", 0, nil, []string{}} {
+		tplf := TplFile{f: &File{path: "test.go"}}
+
+		out, err := tplf.SkipIf(cond, "reason")
+		assert.NilError(t, err)
+		assert.Equal(t, "", out)
+		assert.Equal(t, false, tplf.f.Skipped, "expected %v to not skip", cond)
+	}
+}
+
+func TestTplFile_AppendDocument(t *testing.T) {
+	tplf := TplFile{f: &File{path: "manifests.yaml"}}
+
+	assert.Equal(t, tplf.AppendDocument("kind: ConfigMap\nname: a\n"), "")
+	assert.Equal(t, tplf.f.String(), "kind: ConfigMap\nname: a\n", "expected no separator for the first document")
+
+	tplf.AppendDocument("kind: ConfigMap\nname: b")
+	tplf.AppendDocument("---\nkind: Secret\nname: c\n\n")
+	assert.Equal(t, tplf.f.String(), `kind: ConfigMap
+name: a
+---
+kind: ConfigMap
+name: b
+---
+kind: Secret
+name: c
+`)
+}
+
+func TestTplFile_Symlink(t *testing.T) {
+	tplf := TplFile{f: &File{path: "latest"}}
+
+	out, err := tplf.Symlink("v2")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "")
+	assert.Equal(t, tplf.f.symlinkTarget, "v2")
+
+	_, err = tplf.Symlink("/usr/bin/v2")
+	assert.ErrorContains(t, err, `symlink target "/usr/bin/v2" must be a relative path`)
+}
+
+func TestTplFile_SetEncoding(t *testing.T) {
+	tplf := TplFile{f: &File{path: "legacy.conf"}}
+
+	out, err := tplf.SetEncoding("iso-8859-1")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "")
+	assert.Equal(t, tplf.f.encoding, charmap.ISO8859_1)
+
+	_, err = tplf.SetEncoding("not-an-encoding")
+	assert.ErrorContains(t, err, `unsupported encoding "not-an-encoding"`)
+}
+
+// TestTplFile_Untrack tests that file.Untrack leaves the file out of the lockfile
+func TestTplFile_Untrack(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	fs := memfs.New()
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml":               "name: testing\n",
+		"templates/untracked.txt.tpl": "{{- file.Untrack }}hello",
+		"templates/tracked.txt.tpl":   "tracked",
+	})
+	m, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	// untracked.txt was tracked by a previous run.
+	lock := &stencil.Lockfile{
+		Files: []*stencil.LockfileFileEntry{
+			{Name: "untracked.txt", Template: "untracked.txt.tpl", Module: "testing"},
+		},
+	}
+	st := NewStencil(&configuration.Manifest{Name: "test"}, lock, []*modules.Module{m}, log, false)
+	tpls, err := st.Render(ctx, log)
+	assert.NilError(t, err)
+
+	contents := make(map[string]string)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			assert.Assert(t, !f.Skipped, "expected %s to be generated", f.Name())
+			contents[f.Name()] = f.String()
+		}
+	}
+	assert.DeepEqual(t, contents, map[string]string{
+		"untracked.txt": "hello",
+		"tracked.txt":   "tracked",
+	})
+
+	l := st.GenerateLockfile(tpls)
+	l.MergeMissingInfoFromOlderLockfile(lock)
+	names := make([]string, 0, len(l.Files))
+	for _, f := range l.Files {
+		names = append(names, f.Name)
+	}
+	assert.DeepEqual(t, names, []string{"tracked.txt"})
+
+	// Once generated, the file is owned by the user.
+	assert.NilError(t, os.WriteFile("untracked.txt", []byte("changed"), 0o644))
+	tpls, err = NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m}, log, false).Render(ctx, log)
+	assert.NilError(t, err)
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			assert.Equal(t, f.Skipped, f.Name() == "untracked.txt", f.Name())
+		}
+	}
+}
+
 // TestTplFile_OnceNoLockfile tests the file.Once command when there's no lockfile history at all
 func TestTplFile_OnceNoLockfile(t *testing.T) {
 	tplf := TplFile{
@@ -269,3 +397,38 @@ func TestTplFile_RemoveAll(t *testing.T) {
 	_, err = os.Stat("test/test2.go")
 	assert.ErrorContains(t, err, "no such file")
 }
+
+func TestTplFile_AppendBlockExistingBlock(t *testing.T) {
+	tplf := TplFile{f: &File{blocks: map[string]*blockInfo{
+		"name": {Name: "name", Contents: "a: 1\nb: 2"},
+	}}}
+
+	assert.Equal(t, tplf.AppendBlock("name", "c: 3\n"), "a: 1\nb: 2\nc: 3")
+}
+
+func TestTplFile_AppendBlockNoBlock(t *testing.T) {
+	tplf := TplFile{f: &File{blocks: map[string]*blockInfo{}}}
+
+	assert.Equal(t, tplf.AppendBlock("name", "c: 3\n"), "c: 3")
+}
+
+func TestTplFile_AppendBlockAlreadyAppended(t *testing.T) {
+	tplf := TplFile{f: &File{blocks: map[string]*blockInfo{
+		"name": {Name: "name", Contents: "a: 1\nc: 3"},
+	}}}
+
+	assert.Equal(t, tplf.AppendBlock("name", "c: 3"), "a: 1\nc: 3")
+	assert.Equal(t, tplf.AppendBlock("name", "3"), "a: 1\nc: 3\n3", "expected only whole lines to match")
+	assert.Equal(t, tplf.AppendBlock("name", ""), "a: 1\nc: 3")
+}
+
+func TestTplFile_AppendBlockFromDisk(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.WriteFile("test.yaml", []byte("## <<Stencil::Block(name)>>\na: 1\n## <</Stencil::Block>>\n"), 0o644))
+
+	f, err := NewFile("test.yaml", 0o644, time.Time{}, nil)
+	assert.NilError(t, err)
+	tplf := TplFile{f: f}
+
+	assert.Equal(t, tplf.AppendBlock("name", "b: 2"), "a: 1\nb: 2")
+}
@@ -39,10 +39,6 @@ type TplModule struct {
 	log slogext.Logger
 }
 
-// exportChecks is a map of already exported functions to prevent
-// duplicate exports.
-var exportChecks = make(map[string]struct{})
-
 // Export registers a function to allow it to be called by other
 // templates.
 //
@@ -51,6 +47,10 @@ var exportChecks = make(map[string]struct{})
 // also only eligible to be exported once, if a function is exported
 // twice the second call will be a runtime error.
 //
+// An optional JSON schema may be provided to declare the output of the
+// function. When set, the value returned by the function is validated
+// against it by [TplModule.Call] before being handed to the caller.
+//
 // Example:
 //
 //	{{- define "HelloWorld" }}
@@ -58,15 +58,22 @@ var exportChecks = make(map[string]struct{})
 //	{{- end }}
 //
 //	{{ module.Export "HelloWorld" }}
-func (tm *TplModule) Export(name string) (string, error) {
+//
+//	// With an output schema
+//	{{ module.Export "HelloWorld" (dict "type" "string") }}
+func (tm *TplModule) Export(name string, schema ...map[string]any) (string, error) {
+	if len(schema) > 1 {
+		return "", fmt.Errorf("Export() only takes max two arguments, name and schema")
+	}
+
 	// We only allow functions to be exported before the final pass.
 	if tm.s.renderStage == renderStageFinal {
 		// In the final pass, though, check to make sure there's not dupes
 		checkName := fmt.Sprintf("%s.%s", tm.t.Module.Name, name)
-		if _, ok := exportChecks[checkName]; ok {
+		if _, ok := tm.s.exportChecks[checkName]; ok {
 			return "", fmt.Errorf("function %q in module %q was already exported", name, tm.t.Module.Name)
 		}
-		exportChecks[checkName] = struct{}{}
+		tm.s.exportChecks[checkName] = struct{}{}
 
 		return "", nil
 	}
@@ -84,12 +91,49 @@ func (tm *TplModule) Export(name string) (string, error) {
 	ef := exportedFunction{
 		Template: tm.t,
 	}
+	if len(schema) > 0 {
+		ef.Schema = schema[0]
+	}
 	tm.s.sharedState.Functions.Store(key, ef)
 	tm.log.Debug("Exported function", "module.name", moduleName, "function.name", name)
 
 	return "", nil
 }
 
+// registerHelpers exports the helpers declared by each module, see
+// [configuration.TemplateRepositoryManifest.Helpers], from the library
+// template that defines them. This must be called after the provided
+// templates have been parsed.
+func (s *Stencil) registerHelpers(tpls []*Template) error {
+	for _, m := range s.modules {
+		for _, name := range m.Manifest.Helpers {
+			if name == "" || !strings.HasPrefix(name, strings.ToUpper(name[:1])) {
+				return fmt.Errorf("helper %q of module %q must start with a capital letter", name, m.Name)
+			}
+			if _, ok := Default[name]; ok {
+				return fmt.Errorf("helper %q of module %q conflicts with a built-in function", name, m.Name)
+			}
+
+			var owner *Template
+			if def := m.GetTemplate().Lookup(name); def != nil && def.Tree != nil {
+				for _, t := range tpls {
+					if t.Module == m && t.Library && def.Tree.ParseName == t.ImportPath() {
+						owner = t
+						break
+					}
+				}
+			}
+			if owner == nil {
+				return fmt.Errorf("helper %q of module %q is not defined in any of its library templates", name, m.Name)
+			}
+
+			s.sharedState.Functions.Store(s.sharedState.key(m.Name, name), exportedFunction{Template: owner})
+		}
+	}
+
+	return nil
+}
+
 // Call executes a template function by name with the provided
 // arguments. The function must have been exported by the module that
 // provides it through the [Export] function.
@@ -107,6 +151,14 @@ func (tm *TplModule) Export(name string) (string, error) {
 // [TplStencil.Include] (`stencil.Include`). Meaning, it points to
 // [Values]. The caller passed data is accessible on `.Data`.
 //
+// If the function was exported with an output schema, the returned
+// value is validated against it and an error is returned if it does
+// not match.
+//
+// The module may also be referenced by the alias set for it in the
+// project's stencil.yaml or, if it's unambiguous, by the last element
+// of its name (e.g., "module-a.HelloWorld").
+//
 // Example:
 //
 //	// module-a
@@ -137,6 +189,10 @@ func (tm *TplModule) Call(name string, args ...any) (any, error) {
 		return nil, fmt.Errorf("expected format module.function, got %q", name)
 	}
 	moduleName, functionName := name[:lastPeriodIdx], name[lastPeriodIdx+1:]
+	moduleName, err := tm.s.resolveModuleName(moduleName)
+	if err != nil {
+		return nil, err
+	}
 
 	key := tm.s.sharedState.key(moduleName, functionName)
 	ef, ok := tm.s.sharedState.Functions.Load(key)
@@ -202,6 +258,16 @@ func (tm *TplModule) Call(name string, args ...any) (any, error) {
 	if err := tmpTpl.ExecuteTemplate(io.Discard, functionName, d); err != nil && !errors.Is(err, ErrStopProcessingTemplate) {
 		return nil, err
 	}
+	if errVal.err != nil {
+		return nil, errVal.err
+	}
+
+	if ef.Schema != nil {
+		if err := validateJSONSchema(moduleName+"/functions/"+functionName, ef.Schema, returnVal); err != nil {
+			return nil, fmt.Errorf("function %q in module %q returned a value that does not match its output schema: %w",
+				functionName, moduleName, err)
+		}
+	}
 
-	return returnVal, errVal.err
+	return returnVal, nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/google/go-cmp/cmp"
 	"go.rgst.io/stencil/v2/internal/modules"
 	"go.rgst.io/stencil/v2/internal/modules/modulestest"
@@ -91,6 +92,24 @@ func TestTplModule_Tpl(t *testing.T) {
 			callingTemplate:     ``,
 			wantFuncErrContains: "already exported",
 		},
+		{
+			name: "should return value matching output schema",
+			functionTemplate: `{{- define "HelloWorld" -}}
+		{{ return (fromYaml "hello: world") }}
+		{{- end -}}
+		{{- module.Export "HelloWorld" (fromYaml "type: object\nrequired: [hello]\nproperties: {hello: {type: string}}") -}}`,
+			callingTemplate: `{{ (module.Call "function.HelloWorld").hello }}`,
+			want:            "world",
+		},
+		{
+			name: "should error on value not matching output schema",
+			functionTemplate: `{{- define "HelloWorld" -}}
+		{{ return "Hello, world!" }}
+		{{- end -}}
+		{{- module.Export "HelloWorld" (dict "type" "object") -}}`,
+			callingTemplate: `{{ module.Call "function.HelloWorld" }}`,
+			wantErrContains: `function "HelloWorld" in module "function" returned a value that does not match its output schema`,
+		},
 		{
 			name: "use context from module being called",
 			functionTemplate: `{{- stencil.SetGlobal "a" "func" -}}
@@ -108,7 +127,7 @@ func TestTplModule_Tpl(t *testing.T) {
 			log := slogext.NewTestLogger(t)
 
 			// create stencil
-			st := &Stencil{sharedState: newSharedState(), log: log}
+			st := &Stencil{sharedState: newSharedState(), exportChecks: make(map[string]struct{}), log: log}
 
 			// create calling module
 			callerModule, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
@@ -190,3 +209,49 @@ func TestTplModule_Tpl(t *testing.T) {
 		})
 	}
 }
+
+func TestTplModule_Helpers(t *testing.T) {
+	tests := []struct {
+		name            string
+		manifest        string
+		want            string
+		wantErrContains string
+	}{
+		{
+			name:     "should call helper without exporting it",
+			manifest: "name: testing\nhelpers: [Greet]\n",
+			want:     "Hello, world!",
+		},
+		{
+			name:            "should error on undefined helper",
+			manifest:        "name: testing\nhelpers: [Greet, Missing]\n",
+			wantErrContains: `helper "Missing" of module "testing" is not defined in any of its library templates`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := slogext.NewTestLogger(t)
+
+			fs := memfs.New()
+			createFiles(t, fs, map[string]string{
+				"manifest.yaml": tt.manifest,
+				"templates/helpers.library.tpl": `{{- define "Greet" -}}
+{{ return (printf "Hello, %s!" .Data) }}
+{{- end -}}`,
+				"templates/hello.txt.tpl": `{{ Greet "world" }}`,
+			})
+			m, err := modulestest.NewWithFS(ctx, "testing", fs)
+			assert.NilError(t, err, "failed to NewWithFS")
+
+			st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m}, log, false)
+			files, err := st.RenderToMemory(ctx, log)
+			if tt.wantErrContains != "" {
+				assert.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			assert.NilError(t, err, "failed to render")
+			assert.Equal(t, string(files["hello.txt"]), tt.want)
+		})
+	}
+}
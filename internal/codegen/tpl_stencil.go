@@ -20,13 +20,26 @@ package codegen
 
 import (
 	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/jaredallard/vcs/resolver"
+	"go.rgst.io/stencil/v2/internal/ci"
+	"go.rgst.io/stencil/v2/internal/dotnotation"
 	"go.rgst.io/stencil/v2/pkg/configuration"
 	"go.rgst.io/stencil/v2/pkg/slogext"
 )
@@ -66,7 +79,37 @@ func (s *TplStencil) GetModuleHook(name string) []any {
 	s.log.With("template", s.t.ImportPath(), "path", k, "data", spew.Sdump(v)).
 		Debug("getting module hook")
 
-	return v
+	return v.Values()
+}
+
+// GetModuleHookWithSource returns the same data as stencil.GetModuleHook
+// but with each value annotated with the module and template that added
+// it. This is useful when debugging where data in a module hook came
+// from.
+//
+//	{{- range stencil.GetModuleHookWithSource "myModuleHook" }}
+//	# Added by {{ .Module }} ({{ .Template }})
+//	{{ .Value }}
+//	{{- end }}
+func (s *TplStencil) GetModuleHookWithSource(name string) []ModuleHookEntry {
+	k := s.s.sharedState.key(s.t.Module.Name, name)
+	v, _ := s.s.sharedState.ModuleHooks.Load(k)
+
+	s.log.With("template", s.t.ImportPath(), "path", k, "data", spew.Sdump(v)).
+		Debug("getting module hook with source")
+
+	return append([]ModuleHookEntry{}, v...)
+}
+
+// ModuleHookCount returns the number of values added to a module hook
+// of this module, without copying them like stencil.GetModuleHook does.
+// Every value passed to stencil.AddToModuleHook is counted.
+//
+//	# {{ stencil.ModuleHookCount "myModuleHook" }} dependencies
+func (s *TplStencil) ModuleHookCount(name string) int {
+	k := s.s.sharedState.key(s.t.Module.Name, name)
+	v, _ := s.s.sharedState.ModuleHooks.Load(k)
+	return len(v)
 }
 
 // SetGlobal sets a global to be used in the context of the current
@@ -82,6 +125,9 @@ func (s *TplStencil) GetModuleHook(name string) []any {
 // averse to panics that could  occur if you're using the data it
 // returns in the wrong way.
 //
+// Only the last value set for a global is kept, so a warning is logged
+// when two templates set the same global to different values.
+//
 //	{{- /* This writes a global into the current context of the template module repository */}}
 //	{{- stencil.SetGlobal "IsGeorgeCool" true -}}
 func (s *TplStencil) SetGlobal(name string, data any) string {
@@ -89,6 +135,7 @@ func (s *TplStencil) SetGlobal(name string, data any) string {
 	s.log.With("template", s.t.ImportPath(), "path", k, "data", spew.Sdump(data)).
 		Debug("adding to global store")
 
+	s.s.warnGlobalConflict(name, s.t, data, s.log)
 	s.s.sharedState.Globals.Store(k, global{
 		Template: s.t.Path,
 		Value:    data,
@@ -133,26 +180,31 @@ func (s *TplStencil) GetGlobal(name string) any {
 // it to operate on. These are not strongly typed so it's best practice
 // to look at how the owning module uses it for now.
 //
+// If the owning module declares a schema for the module hook, the data
+// is validated against it. Extensible module hooks also accept data
+// matching any of the schemas provided for them by other modules
+// through moduleHookSchemas in their manifest.
+//
+// The module may also be referenced by the alias set for it in the
+// project's stencil.yaml or, if it's unambiguous, by the last element
+// of its name.
+//
 //	{{- /* This writes to a module hook */}}
 //	{{- stencil.AddToModuleHook "github.com/myorg/repo" "myModuleHook" "myData" }}
 func (s *TplStencil) AddToModuleHook(module, name string, data ...any) (out string, err error) {
-	// Attempt to read the destined module's manifest for extra features.
-	var mcfg *configuration.TemplateRepositoryManifest
-	for _, m := range s.s.modules {
-		if m.Name == module {
-			mcfg = m.Manifest
-			break
-		}
+	module, err = s.s.resolveModuleName(module)
+	if err != nil {
+		return "", err
 	}
-	if mcfg != nil {
-		// Check if we have a schema. If we do, use it to validate our module
-		// hook data.
-		mhcfg, ok := mcfg.ModuleHooks[name]
-		if ok && mhcfg.Schema != nil {
-			for _, d := range data {
-				if err := validateJSONSchema(module+"/moduleHooks/"+name, mhcfg.Schema, d); err != nil {
-					return "", err
-				}
+
+	schema, err := s.s.moduleHookSchema(module, name)
+	if err != nil {
+		return "", err
+	}
+	if schema != nil {
+		for _, d := range data {
+			if err := validateJSONSchema(module+"/moduleHooks/"+name, schema, d); err != nil {
+				return "", err
 			}
 		}
 	}
@@ -162,28 +214,198 @@ func (s *TplStencil) AddToModuleHook(module, name string, data ...any) (out stri
 		Debug("adding to module hook")
 
 	s.s.sharedState.ModuleHooks.Compute(k, func(old moduleHook, _ bool) (moduleHook, bool) {
-		return append(old, data...), false
+		for _, d := range data {
+			old = append(old, ModuleHookEntry{
+				Module:   s.t.Module.Name,
+				Template: s.t.Path,
+				Value:    d,
+			})
+		}
+		return old, false
 	})
 
 	return "", nil
 }
 
+// moduleHookSchema returns the JSON schema that data added to the
+// provided module hook must match, or nil if there is none. For
+// extensible module hooks, the schema of the owning module and the
+// schemas provided by other modules are combined with anyOf.
+func (s *Stencil) moduleHookSchema(module, name string) (map[string]any, error) {
+	// Attempt to read the destined module's manifest for extra features.
+	var mcfg *configuration.TemplateRepositoryManifest
+	for _, m := range s.modules {
+		if m.Name == module {
+			mcfg = m.Manifest
+			break
+		}
+	}
+	if mcfg == nil {
+		return nil, nil
+	}
+	mhcfg := mcfg.ModuleHooks[name]
+
+	schemas := make([]any, 0)
+	if mhcfg.Schema != nil {
+		schemas = append(schemas, mhcfg.Schema)
+	}
+	for _, m := range s.modules {
+		if m.Manifest == nil {
+			continue
+		}
+
+		schema, ok := m.Manifest.ModuleHookSchemas[module][name]
+		if !ok {
+			continue
+		}
+		if !mhcfg.Extensible {
+			return nil, fmt.Errorf("module %q provides a schema for module hook %q of module %q, which is not extensible",
+				m.Name, name, module)
+		}
+		schemas = append(schemas, schema)
+	}
+
+	switch len(schemas) {
+	case 0:
+		return nil, nil
+	case 1:
+		return schemas[0].(map[string]any), nil
+	}
+	return map[string]any{"anyOf": schemas}, nil
+}
+
+// RenderedFile returns the rendered contents of the primary (first)
+// file of another template in the current run, e.g., to embed a
+// generated config file into documentation. The template is referenced
+// by its import path, the import path of its module joined with its
+// path relative to the module's templates directory (e.g.,
+// "github.com/myorg/repo/config.yaml.tpl").
+//
+// The contents are only available during the final render, before
+// that an empty string is returned. Templates that have not been
+// rendered yet during the final render return the contents from the
+// last pre-render iteration, which are equal unless that template also
+// depends on stencil.RenderedFile. An error is returned if the template
+// does not exist or did not render a file (e.g., it was skipped).
+//
+//	{{- stencil.RenderedFile "github.com/myorg/repo/config.yaml.tpl" }}
+func (s *TplStencil) RenderedFile(importPath string) (string, error) {
+	if s.s.renderStage != renderStageFinal {
+		s.log.With("template", s.t.ImportPath(), "path", importPath).
+			Debug("rendered file is not available on pre-final stage")
+		return "", nil
+	}
+
+	contents, ok := s.s.renderedFiles[importPath]
+	if !ok {
+		return "", fmt.Errorf("template %q does not exist or did not render a file", importPath)
+	}
+	return contents, nil
+}
+
+// AddGitignore adds one or more patterns to the project's .gitignore.
+//
+// Patterns are accumulated across all modules and can be retrieved,
+// sorted and deduplicated, with stencil.GetGitignore by the template
+// that owns the project's .gitignore. This allows modules to ignore
+// the artifacts they generate (e.g., node_modules) without needing to
+// own the .gitignore or a block in it.
+//
+//	{{- stencil.AddGitignore "node_modules/" "dist/" }}
+func (s *TplStencil) AddGitignore(patterns ...string) string {
+	// Patterns are fully collected by the pre-render stage, so adding
+	// them during the final render would only duplicate data.
+	if s.s.renderStage == renderStageFinal {
+		return ""
+	}
+
+	s.log.With("template", s.t.ImportPath(), "patterns", patterns).
+		Debug("adding to gitignore")
+
+	s.s.sharedState.Gitignore.Compute(s.t.Module.Name, func(old []string, _ bool) ([]string, bool) {
+		return append(old, patterns...), false
+	})
+
+	return ""
+}
+
+// GetGitignore returns all of the patterns added to the project's
+// .gitignore by stencil.AddGitignore across all modules. The returned
+// patterns are sorted and deduplicated.
+//
+//	{{- range stencil.GetGitignore }}
+//	{{ . }}
+//	{{- end }}
+func (s *TplStencil) GetGitignore() []string {
+	patterns := make([]string, 0)
+	for _, v := range s.s.sharedState.Gitignore.Range {
+		patterns = append(patterns, v...)
+	}
+	slices.Sort(patterns)
+
+	return slices.Compact(patterns)
+}
+
 // ReadFile reads a file from the current directory and returns it's
 // contents
 //
 //	{{ stencil.ReadFile "myfile.txt" }}
 func (s *TplStencil) ReadFile(name string) (string, error) {
-	f, err := s.exists(name)
+	b, err := s.readFile(name)
 	if err != nil {
 		return "", err
 	}
 
-	b, err := io.ReadAll(f)
+	return string(b), nil
+}
+
+// ReadJSON reads a JSON file from the current directory and returns
+// its decoded contents. Objects are decoded into a map[string]any and
+// arrays into a []any.
+//
+//	{{- $pkg := stencil.ReadJSON "package.json" }}
+//	{{ $pkg.name }}
+func (s *TplStencil) ReadJSON(name string) (any, error) {
+	b, err := s.readFile(name)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(b), nil
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode %q as JSON: %w", name, err)
+	}
+	return v, nil
+}
+
+// ReadTOML reads a TOML file from the current directory and returns its
+// decoded contents as a map[string]any.
+//
+//	{{- $cfg := stencil.ReadTOML "Cargo.toml" }}
+//	{{ $cfg.package.name }}
+func (s *TplStencil) ReadTOML(name string) (map[string]any, error) {
+	b, err := s.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	v := make(map[string]any)
+	if err := toml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode %q as TOML: %w", name, err)
+	}
+	return v, nil
+}
+
+// readFile reads a file from the current directory, returning an
+// error if the file does not exist or is outside of it.
+func (s *TplStencil) readFile(name string) ([]byte, error) {
+	f, err := s.exists(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
 }
 
 // ReadDirEntry is a partial of [os.DirEntry] returned by
@@ -221,6 +443,106 @@ func (s *TplStencil) ReadDir(name string) ([]ReadDirEntry, error) {
 	return rv, nil
 }
 
+// Glob returns the paths of the files and directories in the project
+// that match the provided pattern, relative to the root of the project
+// and sorted. Patterns use the syntax of [path.Match] with the addition
+// of "**", which matches any number of directories (e.g., "**/*.proto"
+// matches every .proto file in the project). The .git directory is
+// never matched.
+//
+//	{{- range $path := stencil.Glob "api/**/*.proto" }}
+//	  {{- $path }}
+//	{{- end }}
+func (s *TplStencil) Glob(pattern string) ([]string, error) {
+	if !fs.ValidPath(pattern) {
+		return nil, fmt.Errorf("invalid pattern %q, must be relative to the root of the project", pattern)
+	}
+
+	patParts := strings.Split(pattern, "/")
+	for _, part := range patParts {
+		if _, err := path.Match(part, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Only walk the directory that the pattern can match in, i.e., its
+	// leading parts that don't contain any special characters.
+	root := "."
+	for i, part := range patParts[:len(patParts)-1] {
+		if strings.ContainsAny(part, `*?[\`) {
+			break
+		}
+		root = path.Join(patParts[:i+1]...)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]string, 0)
+	err = fs.WalkDir(os.DirFS(cwd), root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && fpath == root {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+
+		if fpath != "." && matchGlobParts(patParts, strings.Split(fpath, "/")) {
+			matches = append(matches, fpath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(matches)
+	return matches, nil
+}
+
+// matchGlobParts returns true if the provided path parts match the
+// provided pattern parts. See [TplStencil.Glob] for the syntax.
+func matchGlobParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], parts[0])
+	return err == nil && ok && matchGlobParts(pattern[1:], parts[1:])
+}
+
+// Env returns the value of the provided environment variable, or an
+// empty string if it's not set. Only environment variables listed in
+// allowedEnv of the module's manifest can be read, reading any other
+// variable is an error. This is useful for values provided by the
+// environment (e.g., CI) that aren't arguments.
+//
+//	{{- $registry := stencil.Env "CI_REGISTRY" }}
+func (s *TplStencil) Env(name string) (string, error) {
+	if !slices.Contains(s.t.Module.Manifest.AllowedEnv, name) {
+		return "", fmt.Errorf("module %q is not allowed to read environment variable %q, add it to allowedEnv in "+
+			"its manifest.yaml", s.t.Module.Name, name)
+	}
+	return os.Getenv(name), nil
+}
+
 // Exists returns true if the file exists in the current directory
 //
 //	{{- if stencil.Exists "myfile.txt" }}
@@ -312,6 +634,13 @@ func (s *TplStencil) Include(name string, dataSli ...any) (string, error) {
 		d.Data = dataSli[0]
 	}
 
+	// Blocks rendered by the named template belong to the template that
+	// defines it, which is parsed with its import path as the name.
+	if nt := s.t.Module.GetTemplate().Lookup(name); nt != nil && nt.Tree != nil {
+		s.t.includes = append(s.t.includes, nt.Tree.ParseName)
+		defer func() { s.t.includes = s.t.includes[:len(s.t.includes)-1] }()
+	}
+
 	var buf bytes.Buffer
 	if err := s.t.Module.GetTemplate().ExecuteTemplate(&buf, name, d); err != nil {
 		return "", err
@@ -367,6 +696,119 @@ func (s *TplStencil) ReadBlocks(fpath string) (map[string]string, error) {
 	return rv, nil
 }
 
+// ReadBlocksWithPrefix returns the blocks of a file whose names start
+// with the provided prefix, as key/value pairs sorted by the name of
+// the block. This is useful when a module owns a family of blocks
+// (e.g., route-1, route-2). See stencil.ReadBlocks for how blocks are
+// read.
+//
+//	{{- range $block := stencil.ReadBlocksWithPrefix "myfile.txt" "route-" }}
+//	  {{- $block.Key }}
+//	  {{- $block.Value }}
+//	{{- end }}
+func (s *TplStencil) ReadBlocksWithPrefix(fpath, prefix string) ([]SortedItem, error) {
+	blocks, err := s.ReadBlocks(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]SortedItem, 0)
+	for _, name := range slices.Sorted(maps.Keys(blocks)) {
+		if strings.HasPrefix(name, prefix) {
+			rv = append(rv, SortedItem{Key: name, Value: blocks[name]})
+		}
+	}
+	return rv, nil
+}
+
+// BlockHasContent returns true if the block with the provided name in
+// the file at the provided path exists and has content other than
+// whitespace. This is useful for generating a file only when a user has
+// filled in a block in another file. If the file does not exist, false
+// is returned.
+//
+// **NOTE**: Blocks are read from the file on disk, so, like
+// stencil.ReadBlocks, this reflects the contents of the file
+// before stencil was ran. The other file must already exist on disk,
+// its contents are not those being rendered in the current run.
+//
+//	{{- if not (stencil.BlockHasContent "myfile.txt" "imports") }}
+//	{{- file.Skip "No custom imports" }}
+//	{{- end }}
+func (s *TplStencil) BlockHasContent(fpath, name string) (bool, error) {
+	blocks, err := s.ReadBlocks(fpath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return strings.TrimSpace(blocks[name]) != "", nil
+}
+
+// ExistingBlockNames returns the names of all blocks in the files on
+// disk that match the provided glob (see [path.Match]), relative to
+// the root of the project, sorted and without duplicates. This allows
+// a template to generate exactly the files, or sections, that a user
+// had in a previous run (e.g., one file per block).
+//
+// **NOTE**: Like stencil.ReadBlocks, this reads the files as they were
+// before stencil was ran, files rendered in the current run are not
+// taken into account. If no files match, an empty list is returned.
+// Symlinks are not followed.
+//
+//	{{- range $name := stencil.ExistingBlockNames "services/*.yaml" }}
+//	  {{- $name }}
+//	{{- end }}
+func (s *TplStencil) ExistingBlockNames(glob string) ([]string, error) {
+	if !fs.ValidPath(glob) {
+		return nil, fmt.Errorf("invalid glob %q, must be relative to the root of the project", glob)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := fs.Glob(os.DirFS(cwd), glob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+
+	bfs := osfs.New(cwd)
+	names := make([]string, 0)
+	for _, match := range matches {
+		// Only read regular files, symlinks could point outside of the
+		// project.
+		if inf, err := bfs.Lstat(match); err != nil || !inf.Mode().IsRegular() {
+			continue
+		}
+
+		if err := func() error {
+			f, err := s.exists(match)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			blocks, err := parseBlocksInner(f, match, nil, blockCommentPrefixes(s.t))
+			if err != nil {
+				return err
+			}
+			for name := range blocks {
+				names = append(names, name)
+			}
+			return nil
+		}(); err != nil {
+			return nil, fmt.Errorf("failed to read blocks from %q: %w", match, err)
+		}
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
 // Debug logs the provided arguments under the DEBUG log level (must run
 // stencil with --debug).
 //
@@ -376,3 +818,134 @@ func (s *TplStencil) Debug(args ...interface{}) string {
 
 	return ""
 }
+
+// HasExtension returns true if the native extension with the provided
+// name (the import path of the module providing it) has been loaded.
+// This is useful for modules that optionally use an extension (see the
+// `optional` field of a module dependency).
+//
+//	{{- if stencil.HasExtension "github.com/myorg/myext" }}
+//	{{ extensions.Call "github.com/myorg/myext.MyFunction" }}
+//	{{- end }}
+func (s *TplStencil) HasExtension(name string) bool {
+	return s.s.ext.HasExtension(name)
+}
+
+// IsCI returns true if stencil is being ran inside of a CI environment
+// (e.g., the CI or GITHUB_ACTIONS environment variables are set).
+//
+//	{{- if stencil.IsCI }}
+//	{{- file.Skip "Not generated in CI" }}
+//	{{- end }}
+func (s *TplStencil) IsCI() bool {
+	return ci.IsCI()
+}
+
+// Project returns the value of a key in the `project` section of the
+// project's manifest (stencil.yaml). Unlike arguments, project values
+// are shared by all modules and don't need to be declared in a
+// module's manifest. Nested values can be accessed using dot notation.
+// If the key is not set, nil is returned.
+//
+//	{{- stencil.Project "org" }}
+//	{{- stencil.Project "license.name" | default "Apache-2.0" }}
+func (s *TplStencil) Project(pth string) (any, error) {
+	if pth == "" {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+
+	v, err := dotnotation.Get(s.s.m.Project, pth)
+	if err != nil {
+		//nolint:nilerr // Why: unset keys are returned as nil
+		return nil, nil
+	}
+	return v, nil
+}
+
+// OS returns the operating system being generated for, in the same
+// format as GOOS (e.g., "linux", "darwin" or "windows"). This defaults
+// to the operating system stencil is running on and can be overridden
+// with the --target-os flag.
+//
+//	{{- if eq stencil.OS "windows" }}
+//	{{- file.Skip "Not supported on Windows" }}
+//	{{- end }}
+func (s *TplStencil) OS() string {
+	if s.s.targetOS != "" {
+		return s.s.targetOS
+	}
+	return runtime.GOOS
+}
+
+// Arch returns the architecture being generated for, in the same format
+// as GOARCH (e.g., "amd64" or "arm64"). This defaults to the
+// architecture stencil is running on and can be overridden with the
+// --target-arch flag.
+//
+//	{{- stencil.Arch }}
+func (s *TplStencil) Arch() string {
+	if s.s.targetArch != "" {
+		return s.s.targetArch
+	}
+	return runtime.GOARCH
+}
+
+// ModuleInfo describes a module used by the project, as returned by
+// [TplStencil.Modules].
+type ModuleInfo struct {
+	// Name is the name (import path) of the module
+	Name string
+
+	// Version is the version of the module formatted for display, e.g.,
+	// "v1.2.3", "main@0123456" or "local" for local replacements
+	Version string
+
+	// URL is the URL (or path) the module was fetched from
+	URL string
+}
+
+// Modules returns all modules used by the project, sorted by name,
+// with their versions formatted for display.
+//
+//	{{- range stencil.Modules }}
+//	- {{ .Name }} ({{ .Version }})
+//	{{- end }}
+func (s *TplStencil) Modules() []ModuleInfo {
+	mods := make([]ModuleInfo, 0, len(s.s.modules))
+	for _, m := range s.s.modules {
+		mods = append(mods, ModuleInfo{
+			Name:    m.Name,
+			Version: formatModuleVersion(m.Version),
+			URL:     m.URI,
+		})
+	}
+	slices.SortFunc(mods, func(a, b ModuleInfo) int { return cmp.Compare(a.Name, b.Name) })
+	return mods
+}
+
+// formatModuleVersion returns the provided version formatted for
+// display: the tag if there is one, otherwise the branch and short
+// commit or just the commit.
+func formatModuleVersion(v *resolver.Version) string {
+	if v == nil {
+		return ""
+	}
+
+	commit := v.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+
+	switch {
+	case v.Virtual != "":
+		return v.Virtual
+	case v.Tag != "":
+		return v.Tag
+	case v.Branch != "" && commit != "":
+		return v.Branch + "@" + commit
+	case v.Branch != "":
+		return v.Branch
+	default:
+		return commit
+	}
+}
@@ -20,16 +20,124 @@ package codegen
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.rgst.io/stencil/v2/internal/dotnotation"
 	"go.rgst.io/stencil/v2/pkg/configuration"
 )
 
+// ArgSource denotes where the value of an argument came from
+type ArgSource string
+
+const (
+	// ArgSourceProject denotes that the value was set in the project's
+	// manifest (stencil.yaml)
+	ArgSourceProject ArgSource = "project"
+
+	// ArgSourceDefault denotes that the value is the default declared in
+	// the argument's definition
+	ArgSourceDefault ArgSource = "default"
+
+	// ArgSourceZeroValue denotes that the value is the zero value for the
+	// type declared in the argument's schema, because it was not set and
+	// has no default
+	ArgSourceZeroValue ArgSource = "zero value"
+)
+
+// ArgExplanation explains how the value of an argument was derived
+type ArgExplanation struct {
+	// Module is the module the argument was requested for
+	Module string
+
+	// Path is the path of the argument
+	Path string
+
+	// DefinedBy is the module whose argument definition was used. This
+	// differs from Module when the argument uses "from" to alias an
+	// argument of another module.
+	DefinedBy string
+
+	// Source is where the value came from
+	Source ArgSource
+
+	// Value is the resolved value of the argument
+	Value any
+
+	// Schema is the JSON schema the value was validated against, if any
+	Schema map[string]any
+
+	// Validation is the additional validation the value was checked
+	// against, if any
+	Validation *configuration.ArgumentValidation
+
+	// Secret denotes if the value of the argument is sensitive
+	Secret bool
+
+	// Examples are the example values declared for the argument, if any
+	Examples []any
+}
+
 // Arg returns the value of an argument in the project's manifest
 //
+// Values nested inside of an argument can be accessed using dot
+// notation, where numeric path segments are used as indexes into lists
+// (e.g., "servers.0.host" for the declared argument "servers").
+//
 //	{{- stencil.Arg "name" }}
 func (s *TplStencil) Arg(pth string) (interface{}, error) {
+	argPth, subPth := s.splitArgPath(pth)
+	expl, err := s.explainArg(argPth)
+	if err != nil {
+		return "", err
+	}
+
+	// validate the data
+	if err := s.validateArg(argPth, expl); err != nil {
+		return nil, err
+	}
+
+	if subPth == "" {
+		return expl.Value, nil
+	}
+
+	v, err := dotnotation.Get(expl.Value, subPth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %q from argument %q: %w", subPth, argPth, err)
+	}
+	return v, nil
+}
+
+// splitArgPath splits the provided path into the path of the argument
+// declared in the module's manifest and the path inside of its value.
+// If the full path is declared, or no prefix of it is, the path is
+// returned as-is with an empty sub-path.
+func (s *TplStencil) splitArgPath(pth string) (argPth, subPth string) {
+	args := s.t.Module.Manifest.Arguments
+	if _, ok := args[pth]; ok {
+		return pth, ""
+	}
+
+	// Use the longest declared prefix of the path.
+	spl := strings.Split(pth, ".")
+	for i := len(spl) - 1; i > 0; i-- {
+		prefix := strings.Join(spl[:i], ".")
+		if _, ok := args[prefix]; ok {
+			return prefix, strings.Join(spl[i:], ".")
+		}
+	}
+
+	return pth, ""
+}
+
+// explainArg resolves the value of an argument, returning how it was
+// derived alongside it. The value is not validated against the
+// argument's schema.
+func (s *TplStencil) explainArg(pth string) (*ArgExplanation, error) {
 	if pth == "" {
 		return nil, fmt.Errorf("path cannot be empty")
 	}
@@ -40,17 +148,31 @@ func (s *TplStencil) Arg(pth string) (interface{}, error) {
 
 	mf := s.t.Module.Manifest
 	if _, ok := mf.Arguments[pth]; !ok {
-		return "", fmt.Errorf("module %q doesn't list argument %q as an argument in its manifest", s.t.Module.Name, pth)
+		return nil, fmt.Errorf("module %q doesn't list argument %q as an argument in its manifest", s.t.Module.Name, pth)
 	}
 	arg := mf.Arguments[pth]
 
+	expl := &ArgExplanation{
+		Module:    s.t.Module.Name,
+		Path:      pth,
+		DefinedBy: s.t.Module.Name,
+	}
+
 	// If there's a "from" we should handle that now before anything else,
 	// so that its definition is used.
 	if arg.From != "" {
+		from, err := s.s.resolveModuleName(arg.From)
+		if err != nil {
+			return nil, err
+		}
+		arg.From = from
+
 		fromArg, err := s.resolveFrom(ctx, pth, &arg)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+		expl.DefinedBy = arg.From
+
 		// Guaranteed to not be nil
 		arg = *fromArg
 	}
@@ -62,31 +184,113 @@ func (s *TplStencil) Arg(pth string) (interface{}, error) {
 
 	// if not set then we return a default value based on the denoted type
 	v, err := dotnotation.Get(mapInf, pth)
+	if err != nil {
+		// Keys in stencil.yaml are easily written with a different case
+		// than the module declares (e.g., "Name" vs "name"), so fall back
+		// to matching them case-insensitively.
+		if setPth, ok := foldArgPath(s.s.m.Arguments, pth); ok {
+			s.s.warnArgKeyMismatch(s.t.Module.Name, pth, setPth)
+			v, err = dotnotation.Get(mapInf, setPth)
+		}
+	}
+	expl.Source = ArgSourceProject
 	if err != nil {
 		v, err = s.resolveDefault(pth, &arg)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+
+		expl.Source = ArgSourceZeroValue
+		if arg.Default != nil {
+			expl.Source = ArgSourceDefault
 		}
 	}
 
-	// validate the data
-	if arg.Schema != nil {
-		if err := s.validateArg(pth, &arg, v); err != nil {
-			return nil, err
+	expl.Value = v
+	expl.Schema = arg.Schema
+	expl.Validation = arg.Validation
+	expl.Secret = arg.Secret
+	expl.Examples = arg.Examples
+	return expl, nil
+}
+
+// foldArgPath returns the path of the value in the provided arguments
+// that matches the provided path when map keys are compared
+// case-insensitively. Exact matches are preferred and ambiguous keys
+// (e.g., both "Name" and "NAME" are set) are not matched. false is
+// returned if the path does not match, or matches exactly.
+func foldArgPath(args map[string]any, pth string) (string, bool) {
+	var cur any = args
+	spl := strings.Split(pth, ".")
+	for i, seg := range spl {
+		var keys []string
+		switch m := cur.(type) {
+		case map[string]any:
+			for k := range m {
+				keys = append(keys, k)
+			}
+		case map[any]any:
+			for k := range m {
+				if ks, ok := k.(string); ok {
+					keys = append(keys, ks)
+				}
+			}
+		default:
+			// Not a map (e.g., a list indexed by the segment), leave the
+			// rest of the path to dotnotation.
+			return strings.Join(spl, "."), strings.Join(spl, ".") != pth
+		}
+
+		match := ""
+		for _, k := range keys {
+			if k == seg {
+				match = k
+				break
+			}
+			if strings.EqualFold(k, seg) {
+				if match != "" {
+					// Ambiguous, don't guess which one was meant.
+					return "", false
+				}
+				match = k
+			}
+		}
+		if match == "" {
+			return "", false
+		}
+		spl[i] = match
+
+		switch m := cur.(type) {
+		case map[string]any:
+			cur = m[match]
+		case map[any]any:
+			cur = m[match]
 		}
 	}
 
-	return v, nil
+	setPth := strings.Join(spl, ".")
+	return setPth, setPth != pth
 }
 
 // resolveDefault resolves the default value of an argument from the manifest
 func (s *TplStencil) resolveDefault(pth string, arg *configuration.Argument) (interface{}, error) {
+	if arg.RequiredWhen != "" {
+		required, err := s.requiredWhen(pth, arg.RequiredWhen)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, withArgExamples(fmt.Errorf("module %q requires argument %q but is not set (requiredWhen: %s)",
+				s.t.Module.Name, pth, strings.TrimSpace(arg.RequiredWhen)), arg.Examples)
+		}
+	}
+
 	if arg.Default != nil {
 		return arg.Default, nil
 	}
 
 	if arg.Required {
-		return nil, fmt.Errorf("module %q requires argument %q but is not set", s.t.Module.Name, pth)
+		return nil, withArgExamples(fmt.Errorf("module %q requires argument %q but is not set", s.t.Module.Name, pth), arg.Examples)
 	}
 
 	// json schema convention is to define "type" as the top level key.
@@ -119,6 +323,43 @@ func (s *TplStencil) resolveDefault(pth string, arg *configuration.Argument) (in
 	return v, nil
 }
 
+// requiredWhen renders the requiredWhen template of the argument at the
+// provided path, see [configuration.Argument.RequiredWhen], returning
+// if the argument is required.
+func (s *TplStencil) requiredWhen(pth, cond string) (bool, error) {
+	// Conditions can reference other arguments, which may in turn
+	// reference this one.
+	key := s.t.Module.Name + ":" + pth
+	if _, ok := s.s.requiredWhenActive[key]; ok {
+		return false, fmt.Errorf("requiredWhen of argument %q of module %q depends on itself", pth, s.t.Module.Name)
+	}
+	if s.s.requiredWhenActive == nil {
+		s.s.requiredWhenActive = make(map[string]struct{})
+	}
+	s.s.requiredWhenActive[key] = struct{}{}
+	defer delete(s.s.requiredWhenActive, key)
+
+	vals := s.t.args
+	if vals == nil {
+		vals = NewValues(context.TODO(), s.s.m, s.s.modules)
+	}
+
+	rt, err := NewTemplate(s.t.Module, "requiredWhen", 0o000, time.Time{}, []byte(cond), s.log, nil)
+	if err != nil {
+		return false, err
+	}
+	if err := rt.Render(s.s, vals); err != nil {
+		return false, fmt.Errorf("failed to render requiredWhen of argument %q of module %q: %w", pth, s.t.Module.Name, err)
+	}
+
+	required, err := strconv.ParseBool(strings.TrimSpace(rt.Files[0].String()))
+	if err != nil {
+		return false, fmt.Errorf("requiredWhen of argument %q of module %q must render to true or false, got %q",
+			pth, s.t.Module.Name, rt.Files[0].String())
+	}
+	return required, nil
+}
+
 // resolveFrom resoles the "from" field of an argument
 func (s *TplStencil) resolveFrom(_ context.Context, pth string, arg *configuration.Argument) (*configuration.Argument, error) {
 	foundModuleInDeps := false
@@ -163,7 +404,64 @@ func (s *TplStencil) resolveFrom(_ context.Context, pth string, arg *configurati
 	return &fromArg, nil
 }
 
-// validateArg validates an argument against the schema
-func (s *TplStencil) validateArg(pth string, arg *configuration.Argument, v interface{}) error {
-	return validateJSONSchema(s.t.Module.Name+"/arguments/"+pth, arg.Schema, v)
+// validateArg validates the value of an argument against its schema
+// and, after that, its additional validation. The examples of the
+// argument, if any, are included in the returned error.
+func (s *TplStencil) validateArg(pth string, expl *ArgExplanation) error {
+	return withArgExamples(s.validateArgValue(pth, expl), expl.Examples)
+}
+
+// validateArgValue implements validateArg, without adding examples to
+// the returned error.
+func (s *TplStencil) validateArgValue(pth string, expl *ArgExplanation) error {
+	if expl.Schema != nil {
+		if err := validateJSONSchema(s.t.Module.Name+"/arguments/"+pth, expl.Schema, expl.Value); err != nil {
+			return err
+		}
+	}
+
+	if expl.Validation == nil || expl.Validation.Pattern == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(expl.Validation.Pattern)
+	if err != nil {
+		return fmt.Errorf("module %q has an invalid validation pattern for argument %q: %w", s.t.Module.Name, pth, err)
+	}
+
+	v, ok := expl.Value.(string)
+	if !ok {
+		return fmt.Errorf("argument %q of module %q must be a string to be validated against a pattern, got %T",
+			pth, s.t.Module.Name, expl.Value)
+	}
+
+	if !re.MatchString(v) {
+		msg := expl.Validation.Message
+		if msg == "" {
+			msg = fmt.Sprintf("value %q does not match pattern %q", v, expl.Validation.Pattern)
+		}
+		return fmt.Errorf("argument %q of module %q is invalid: %s", pth, s.t.Module.Name, msg)
+	}
+
+	return nil
+}
+
+// withArgExamples returns the provided error with the provided example
+// values of an argument appended to its message, if there are any. nil
+// is returned if err is nil.
+func withArgExamples(err error, examples []any) error {
+	if err == nil || len(examples) == 0 {
+		return err
+	}
+
+	strs := make([]string, 0, len(examples))
+	for _, ex := range examples {
+		b, jerr := json.Marshal(jsonCompatible(ex))
+		if jerr != nil {
+			strs = append(strs, fmt.Sprint(ex))
+			continue
+		}
+		strs = append(strs, string(b))
+	}
+	return fmt.Errorf("%w (examples: %s)", err, strings.Join(strs, ", "))
 }
@@ -1,15 +1,18 @@
 package codegen
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"go.rgst.io/stencil/v2/internal/modules"
 	"go.rgst.io/stencil/v2/internal/modules/modulestest"
 	"go.rgst.io/stencil/v2/pkg/configuration"
 	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
 )
 
 type testTpl struct {
@@ -56,7 +59,7 @@ func fakeTemplate(t *testing.T, args map[string]interface{},
 	// which we've created earlier after loading the module in the
 	// NewModuleFromTemplates call. This won't be used, but it's
 	// enough to set up the correct environment for running template test functions.
-	tpls, err := test.s.getTemplates(context.Background(), log)
+	tpls, err := test.s.getTemplates(context.Background(), log, NewValues(context.Background(), test.s.m, test.s.modules))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -124,7 +127,7 @@ func fakeTemplateMultipleModules(t *testing.T, manifestArgs map[string]interface
 	// which we've created earlier after loading the module in the
 	// NewModuleFromTemplates call. This won't be used, but it's
 	// enough to set up the correct environment for running template test functions.
-	tpls, err := test.s.getTemplates(context.Background(), log)
+	tpls, err := test.s.getTemplates(context.Background(), log, NewValues(context.Background(), test.s.m, test.s.modules))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -234,6 +237,41 @@ func TestTplStencil_Arg(t *testing.T) {
 			want:    map[string]interface{}{"world": map[string]interface{}{"abc": []interface{}{"def"}}},
 			wantErr: false,
 		},
+		{
+			name: "should support indexing into a list argument",
+			fields: fakeTemplate(t, map[string]interface{}{
+				"servers": []interface{}{
+					map[string]interface{}{"host": "a.example.com"},
+					map[string]interface{}{"host": "b.example.com"},
+				},
+			}, map[string]configuration.Argument{
+				"servers": {
+					Schema: map[string]interface{}{
+						"type": "array",
+					},
+				},
+			}),
+			args: args{
+				pth: "servers.1.host",
+			},
+			want:    "b.example.com",
+			wantErr: false,
+		},
+		{
+			name: "should fail when indexing out of range of a list argument",
+			fields: fakeTemplate(t, map[string]interface{}{
+				"servers": []interface{}{
+					map[string]interface{}{"host": "a.example.com"},
+				},
+			}, map[string]configuration.Argument{
+				"servers": {},
+			}),
+			args: args{
+				pth: "servers.1.host",
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "should return default type when arg is not provided",
 			fields: fakeTemplate(t, map[string]interface{}{},
@@ -323,3 +361,168 @@ func TestTplStencil_Arg(t *testing.T) {
 		})
 	}
 }
+
+func TestTplStencil_ArgValidation(t *testing.T) {
+	validation := &configuration.ArgumentValidation{
+		Pattern: "^[a-z][a-z0-9-]*$",
+		Message: "must be a lowercase DNS label, e.g. my-service",
+	}
+
+	test := fakeTemplate(t, map[string]any{
+		"valid":   "my-service",
+		"invalid": "My_Service",
+	}, map[string]configuration.Argument{
+		"valid":   {Schema: map[string]any{"type": "string"}, Validation: validation},
+		"invalid": {Schema: map[string]any{"type": "string"}, Validation: validation},
+	})
+	s := &TplStencil{s: test.s, t: test.t, log: test.log}
+
+	got, err := s.Arg("valid")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "my-service")
+
+	_, err = s.Arg("invalid")
+	assert.Error(t, err, `argument "invalid" of module "test" is invalid: must be a lowercase DNS label, e.g. my-service`)
+}
+
+func TestTplStencil_ArgExamples(t *testing.T) {
+	test := fakeTemplate(t, map[string]any{
+		"port": "http",
+	}, map[string]configuration.Argument{
+		"port":    {Schema: map[string]any{"type": "integer"}, Examples: []any{8080, 443}},
+		"name":    {Required: true, Examples: []any{"my-service"}},
+		"version": {Required: true},
+	})
+	s := &TplStencil{s: test.s, t: test.t, log: test.log}
+
+	_, err := s.Arg("port")
+	assert.ErrorContains(t, err, "got string, want integer (examples: 8080, 443)")
+
+	_, err = s.Arg("name")
+	assert.Error(t, err, `module "test" requires argument "name" but is not set (examples: "my-service")`)
+
+	// Arguments without examples are unchanged.
+	_, err = s.Arg("version")
+	assert.Error(t, err, `module "test" requires argument "version" but is not set`)
+}
+
+func TestTplStencil_ArgRequiredWhen(t *testing.T) {
+	argDefs := map[string]configuration.Argument{
+		"tls": {Schema: map[string]any{"type": "boolean"}},
+		"tlsCert": {
+			Schema:       map[string]any{"type": "string"},
+			RequiredWhen: `{{ stencil.Arg "tls" }}`,
+			Default:      "none",
+		},
+	}
+
+	// The condition is met, so the default isn't used.
+	test := fakeTemplate(t, map[string]any{"tls": true}, argDefs)
+	s := &TplStencil{s: test.s, t: test.t, log: test.log}
+	_, err := s.Arg("tlsCert")
+	assert.Error(t, err, `module "test" requires argument "tlsCert" but is not set (requiredWhen: {{ stencil.Arg "tls" }})`)
+
+	// Setting it satisfies the condition.
+	test = fakeTemplate(t, map[string]any{"tls": true, "tlsCert": "cert.pem"}, argDefs)
+	s = &TplStencil{s: test.s, t: test.t, log: test.log}
+	got, err := s.Arg("tlsCert")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "cert.pem")
+
+	// The condition isn't met, so the default is used.
+	test = fakeTemplate(t, map[string]any{"tls": false}, argDefs)
+	s = &TplStencil{s: test.s, t: test.t, log: test.log}
+	got, err = s.Arg("tlsCert")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "none")
+}
+
+func TestTplStencil_ArgRequiredWhenErrors(t *testing.T) {
+	test := fakeTemplate(t, map[string]any{}, map[string]configuration.Argument{
+		"a":       {RequiredWhen: `{{ stencil.Arg "b" }}`},
+		"b":       {RequiredWhen: `{{ stencil.Arg "a" }}`},
+		"invalid": {RequiredWhen: "yes"},
+	})
+	s := &TplStencil{s: test.s, t: test.t, log: test.log}
+
+	_, err := s.Arg("a")
+	assert.ErrorContains(t, err, `requiredWhen of argument "a" of module "test" depends on itself`)
+
+	_, err = s.Arg("invalid")
+	assert.Error(t, err, `requiredWhen of argument "invalid" of module "test" must render to true or false, got "yes"`)
+}
+
+func TestTplStencil_ArgCaseInsensitiveKeys(t *testing.T) {
+	test := fakeTemplate(t, map[string]any{
+		"Name":   "my-service",
+		"Server": map[string]any{"Port": 8080},
+		"dup":    "lower",
+		"DUP":    "upper",
+	}, map[string]configuration.Argument{
+		"name":        {Schema: map[string]any{"type": "string"}},
+		"server.port": {Schema: map[string]any{"type": "integer"}},
+		"Dup":         {Schema: map[string]any{"type": "string"}},
+	})
+
+	var buf bytes.Buffer
+	test.s.log = slogext.NewWithWriter(&buf)
+	s := &TplStencil{s: test.s, t: test.t, log: test.log}
+
+	for range 2 {
+		got, err := s.Arg("name")
+		assert.NilError(t, err)
+		assert.Equal(t, got, "my-service")
+	}
+	assert.Equal(t, strings.Count(buf.String(), `Argument "name" is set as "Name" in stencil.yaml`), 1,
+		"expected exactly one warning, got: %s", buf.String())
+
+	got, err := s.Arg("server.port")
+	assert.NilError(t, err)
+	assert.Equal(t, got, 8080)
+
+	// Ambiguous keys are not matched.
+	got, err = s.Arg("Dup")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "")
+}
+
+func TestStencil_ExplainArg(t *testing.T) {
+	test := fakeTemplateMultipleModules(t,
+		map[string]any{"hello": "world"},
+		// test-0
+		map[string]configuration.Argument{
+			"hello":   {From: "test-1"},
+			"enabled": {Default: true},
+			"count":   {Schema: map[string]any{"type": "integer"}},
+		},
+		// test-1
+		map[string]configuration.Argument{
+			"hello": {Schema: map[string]any{"type": "string"}},
+		},
+	)
+
+	expl, err := test.s.ExplainArg("test-0", "hello")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, expl, &ArgExplanation{
+		Module:    "test-0",
+		Path:      "hello",
+		DefinedBy: "test-1",
+		Source:    ArgSourceProject,
+		Value:     "world",
+		Schema:    map[string]any{"type": "string"},
+	})
+
+	expl, err = test.s.ExplainArg("test-0", "enabled")
+	assert.NilError(t, err)
+	assert.Equal(t, expl.Source, ArgSourceDefault)
+	assert.Equal(t, expl.DefinedBy, "test-0")
+	assert.Equal(t, expl.Value, true)
+
+	expl, err = test.s.ExplainArg("test-0", "count")
+	assert.NilError(t, err)
+	assert.Equal(t, expl.Source, ArgSourceZeroValue)
+	assert.Equal(t, expl.Value, 0)
+
+	_, err = test.s.ExplainArg("not-a-module", "hello")
+	assert.ErrorContains(t, err, `module "not-a-module" is not used by this project`)
+}
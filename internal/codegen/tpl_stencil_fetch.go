@@ -0,0 +1,162 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains the stencil.FetchURL template
+// function for embedding remote files into generated files.
+
+package codegen
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.rgst.io/stencil/v2/internal/modules/nativeext"
+)
+
+// fetchURLTimeout is the maximum amount of time stencil.FetchURL waits
+// for a download to finish.
+var fetchURLTimeout = 30 * time.Second
+
+// fetchURLMaxSize is the maximum size, in bytes, of a response that
+// stencil.FetchURL accepts.
+var fetchURLMaxSize int64 = 10 * 1024 * 1024
+
+// fetchURLCacheTTL is how long the responses of stencil.FetchURL are
+// cached for before they are downloaded again.
+var fetchURLCacheTTL = 24 * time.Hour
+
+// FetchURL downloads the contents of the provided URL and returns them
+// as a string. This is useful for embedding canonical external files
+// (e.g., a LICENSE) into generated files.
+//
+// Only hosts listed in fetchURLHosts of the project's stencil.yaml can
+// be downloaded from, including when following redirects. Responses
+// are limited to 10MiB and are cached for 24 hours in stencil's cache
+// directory.
+//
+//	{{- stencil.FetchURL "https://www.apache.org/licenses/LICENSE-2.0.txt" }}
+func (s *TplStencil) FetchURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %q: %w", rawURL, err)
+	}
+	if err := checkFetchURL(s.s.m.FetchURLHosts, u); err != nil {
+		return "", err
+	}
+
+	cacheDir, err := nativeext.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	cachePath := filepath.Join(cacheDir, "fetchurl", fmt.Sprintf("%x", sha256.Sum256([]byte(rawURL))))
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < fetchURLCacheTTL {
+		if b, err := os.ReadFile(cachePath); err == nil {
+			s.log.Debug("Using cached response", "url", rawURL)
+			return string(b), nil
+		}
+	}
+
+	s.log.Debug("Fetching URL", "url", rawURL)
+	b, err := fetchURL(s.s.m.FetchURLHosts, rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeFetchURLCache(cachePath, b); err != nil {
+		s.log.WithError(err).Warn("Failed to cache response", "url", rawURL)
+	}
+	return string(b), nil
+}
+
+// checkFetchURL returns an error if the provided URL isn't an HTTP(S)
+// URL of one of the provided hosts.
+func checkFetchURL(hosts []string, u *url.URL) error {
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("unsupported URL %q, only http and https URLs can be fetched", u)
+	}
+
+	if !slices.ContainsFunc(hosts, func(h string) bool { return strings.EqualFold(h, u.Hostname()) }) {
+		return fmt.Errorf("host %q is not allowed to be fetched from, add it to fetchURLHosts in stencil.yaml to fetch %q",
+			u.Hostname(), u)
+	}
+	return nil
+}
+
+// fetchURL downloads the contents of the provided URL. Redirects are
+// only followed to the provided hosts.
+func fetchURL(hosts []string, rawURL string) ([]byte, error) {
+	client := &http.Client{
+		Timeout: fetchURLTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Matches the default policy of [http.Client].
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return checkFetchURL(hosts, req.URL)
+		},
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %q: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch %q: unexpected status %s", rawURL, resp.Status)
+	}
+
+	// Read one byte past the limit to tell if the response is too large.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, fetchURLMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %q: %w", rawURL, err)
+	}
+	if int64(len(b)) > fetchURLMaxSize {
+		return nil, fmt.Errorf("failed to fetch %q: response is larger than %d bytes", rawURL, fetchURLMaxSize)
+	}
+	return b, nil
+}
+
+// writeFetchURLCache atomically writes the provided contents to the
+// cache at the provided path.
+func writeFetchURLCache(path string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
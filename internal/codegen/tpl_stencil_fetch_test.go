@@ -0,0 +1,135 @@
+package codegen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+)
+
+func TestTplStencil_FetchURLCachesResponses(t *testing.T) {
+	env.Patch(t, "XDG_CACHE_HOME", t.TempDir())
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Write([]byte("Licensed under the Apache License"))
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &TplStencil{
+		s:   &Stencil{m: &configuration.Manifest{FetchURLHosts: []string{"127.0.0.1"}}},
+		log: slogext.NewTestLogger(t),
+	}
+
+	for range 2 {
+		got, err := s.FetchURL(srv.URL + "/LICENSE")
+		assert.NilError(t, err)
+		assert.Equal(t, got, "Licensed under the Apache License")
+	}
+	assert.Equal(t, requests, 1, "expected the second fetch to be served from the cache")
+}
+
+func TestTplStencil_FetchURLErrorsOnDisallowedHost(t *testing.T) {
+	env.Patch(t, "XDG_CACHE_HOME", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("expected no request to be made")
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &TplStencil{
+		s:   &Stencil{m: &configuration.Manifest{FetchURLHosts: []string{"example.com"}}},
+		log: slogext.NewTestLogger(t),
+	}
+
+	_, err := s.FetchURL(srv.URL + "/LICENSE")
+	assert.ErrorContains(t, err, `host "127.0.0.1" is not allowed to be fetched from`)
+}
+
+func TestTplStencil_FetchURLErrorsOnBadStatus(t *testing.T) {
+	env.Patch(t, "XDG_CACHE_HOME", t.TempDir())
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	s := &TplStencil{
+		s:   &Stencil{m: &configuration.Manifest{FetchURLHosts: []string{"127.0.0.1"}}},
+		log: slogext.NewTestLogger(t),
+	}
+
+	_, err := s.FetchURL(srv.URL + "/LICENSE")
+	assert.ErrorContains(t, err, "unexpected status 404 Not Found")
+}
+
+func TestTplStencil_FetchURLErrorsOnRedirectToDisallowedHost(t *testing.T) {
+	env.Patch(t, "XDG_CACHE_HOME", t.TempDir())
+
+	target := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("expected the redirect to not be followed")
+	}))
+	t.Cleanup(target.Close)
+
+	// localhost resolves to the target server, but isn't allowed.
+	targetURL, err := url.Parse(target.URL)
+	assert.NilError(t, err)
+	targetURL.Host = "localhost:" + targetURL.Port()
+
+	srv := httptest.NewServer(http.RedirectHandler(targetURL.String()+"/LICENSE", http.StatusFound))
+	t.Cleanup(srv.Close)
+
+	s := &TplStencil{
+		s:   &Stencil{m: &configuration.Manifest{FetchURLHosts: []string{"127.0.0.1"}}},
+		log: slogext.NewTestLogger(t),
+	}
+
+	_, err = s.FetchURL(srv.URL + "/LICENSE")
+	assert.ErrorContains(t, err, `host "localhost" is not allowed to be fetched from`)
+}
+
+func TestTplStencil_FetchURLFollowsRedirectToAllowedHost(t *testing.T) {
+	env.Patch(t, "XDG_CACHE_HOME", t.TempDir())
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("Licensed under the Apache License"))
+	}))
+	t.Cleanup(target.Close)
+
+	srv := httptest.NewServer(http.RedirectHandler(target.URL+"/LICENSE", http.StatusFound))
+	t.Cleanup(srv.Close)
+
+	s := &TplStencil{
+		s:   &Stencil{m: &configuration.Manifest{FetchURLHosts: []string{"127.0.0.1"}}},
+		log: slogext.NewTestLogger(t),
+	}
+
+	got, err := s.FetchURL(srv.URL + "/LICENSE")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "Licensed under the Apache License")
+}
+
+func TestTplStencil_FetchURLErrorsOnLargeResponse(t *testing.T) {
+	env.Patch(t, "XDG_CACHE_HOME", t.TempDir())
+
+	origMaxSize := fetchURLMaxSize
+	t.Cleanup(func() { fetchURLMaxSize = origMaxSize })
+	fetchURLMaxSize = 8
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("Licensed under the Apache License"))
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &TplStencil{
+		s:   &Stencil{m: &configuration.Manifest{FetchURLHosts: []string{"127.0.0.1"}}},
+		log: slogext.NewTestLogger(t),
+	}
+
+	_, err := s.FetchURL(srv.URL + "/LICENSE")
+	assert.ErrorContains(t, err, "response is larger than 8 bytes")
+}
@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"errors"
@@ -27,31 +29,41 @@ import (
 	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
+// ValidateArgumentSchemas ensures that the JSON schema of every argument
+// declared by the loaded modules compiles. Schemas are otherwise only
+// compiled when a template reads the argument. All invalid schemas are
+// returned as a single error.
+func (s *Stencil) ValidateArgumentSchemas() error {
+	errs := make([]error, 0)
+	for _, m := range s.modules {
+		names := slices.Sorted(maps.Keys(m.Manifest.Arguments))
+		for _, name := range names {
+			arg := m.Manifest.Arguments[name]
+			if arg.Schema == nil {
+				continue
+			}
+
+			if _, err := compileJSONSchema(m.Name+"/arguments/"+name, arg.Schema); err != nil {
+				errs = append(errs, fmt.Errorf("argument %q of module %q: %w", name, m.Name, err))
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("invalid argument schemas: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
 // validateJSONSchema validates the provided data against the provided
 // schema.
 //
 // If "identifier" is provided its used as a friendly name for the JSON
 // schema in error messages.
 func validateJSONSchema(identifier string, schemaMap map[string]any, data any) error {
-	schemaBuf := new(bytes.Buffer)
-	if err := json.NewEncoder(schemaBuf).Encode(schemaMap); err != nil {
-		return fmt.Errorf("failed to encode schema into JSON: %w", err)
-	}
-
-	jsc := jsonschema.NewCompiler()
-	jsc.DefaultDraft(jsonschema.Draft7)
-
-	doc, err := jsonschema.UnmarshalJSON(schemaBuf)
-	if err != nil {
-		return fmt.Errorf("failed to decode (re)encoded JSON schema: %w", err)
-	}
-	if err := jsc.AddResource(identifier, doc); err != nil {
-		return fmt.Errorf("failed to parse JSON schema (%s): %w", identifier, err)
-	}
-
-	schema, err := jsc.Compile(identifier)
+	schema, err := compileJSONSchema(identifier, schemaMap)
 	if err != nil {
-		return fmt.Errorf("failed to compile JSON schema (%s): %w", identifier, err)
+		return err
 	}
 
 	if err := schema.Validate(data); err != nil {
@@ -59,7 +71,7 @@ func validateJSONSchema(identifier string, schemaMap map[string]any, data any) e
 
 		var validationError *jsonschema.ValidationError
 		if errors.As(err, &validationError) {
-			for _, validationErr := range validationError.DetailedOutput().Errors {
+			for _, validationErr := range outputErrors(validationError.DetailedOutput().Errors) {
 				pth := strings.TrimPrefix(validationErr.InstanceLocation, "/")
 
 				//nolint:errcheck // Why: Best effort way to get the error.
@@ -83,3 +95,44 @@ func validateJSONSchema(identifier string, schemaMap map[string]any, data any) e
 
 	return nil
 }
+
+// compileJSONSchema compiles the provided schema, using identifier as
+// its friendly name in error messages.
+func compileJSONSchema(identifier string, schemaMap map[string]any) (*jsonschema.Schema, error) {
+	schemaBuf := new(bytes.Buffer)
+	if err := json.NewEncoder(schemaBuf).Encode(schemaMap); err != nil {
+		return nil, fmt.Errorf("failed to encode schema into JSON: %w", err)
+	}
+
+	jsc := jsonschema.NewCompiler()
+	jsc.DefaultDraft(jsonschema.Draft7)
+
+	doc, err := jsonschema.UnmarshalJSON(schemaBuf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode (re)encoded JSON schema: %w", err)
+	}
+	if err := jsc.AddResource(identifier, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema (%s): %w", identifier, err)
+	}
+
+	schema, err := jsc.Compile(identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile JSON schema (%s): %w", identifier, err)
+	}
+	return schema, nil
+}
+
+// outputErrors returns the units that contain an error from the
+// provided output units, descending into units that only group other
+// units (e.g., for anyOf).
+func outputErrors(units []jsonschema.OutputUnit) []jsonschema.OutputUnit {
+	out := make([]jsonschema.OutputUnit, 0, len(units))
+	for _, u := range units {
+		if u.Error == nil {
+			out = append(out, outputErrors(u.Errors)...)
+			continue
+		}
+		out = append(out, u)
+	}
+	return out
+}
@@ -2,8 +2,13 @@ package codegen
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
 	"gotest.tools/v3/assert"
 )
 
@@ -70,3 +75,23 @@ func Test_validateJSONSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateArgumentSchemas(t *testing.T) {
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "testing",
+		Arguments: map[string]configuration.Argument{
+			"valid":    {Schema: map[string]any{"type": "string"}},
+			"badType":  {Schema: map[string]any{"type": "strin"}},
+			"badMin":   {Schema: map[string]any{"minLength": "abc"}},
+			"noSchema": {},
+		},
+	})
+	assert.NilError(t, err)
+
+	st := NewStencil(&configuration.Manifest{Name: "test"}, nil, []*modules.Module{m}, slogext.NewTestLogger(t), false)
+	err = st.ValidateArgumentSchemas()
+	assert.ErrorContains(t, err, "invalid argument schemas")
+	assert.ErrorContains(t, err, `argument "badType" of module "testing"`)
+	assert.ErrorContains(t, err, `argument "badMin" of module "testing"`)
+	assert.Assert(t, !strings.Contains(err.Error(), `"valid"`), err.Error())
+}
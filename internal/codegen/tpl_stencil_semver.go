@@ -0,0 +1,107 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains helpers for working with semantic
+// versions in templates.
+
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// SemverInc increments the provided part ("major", "minor" or "patch")
+// of a semantic version and returns the new version. A leading "v" is
+// preserved.
+//
+//	{{- stencil.SemverInc "v1.2.3" "minor" }} {{- /* v1.3.0 */}}
+func (s *TplStencil) SemverInc(version, part string) (string, error) {
+	v, err := parseSemver(version)
+	if err != nil {
+		return "", err
+	}
+
+	var nv semver.Version
+	switch part {
+	case "major":
+		nv = v.IncMajor()
+	case "minor":
+		nv = v.IncMinor()
+	case "patch":
+		nv = v.IncPatch()
+	default:
+		return "", fmt.Errorf("unknown version part %q, expected one of major, minor or patch", part)
+	}
+
+	if strings.HasPrefix(version, "v") {
+		return "v" + nv.String(), nil
+	}
+	return nv.String(), nil
+}
+
+// SemverCompare compares two semantic versions, returning -1 if a is
+// less than b, 0 if they are equal and 1 if a is greater than b.
+//
+//	{{- if eq (stencil.SemverCompare "1.2.3" "1.10.0") -1 }}
+//	1.2.3 is older
+//	{{- end }}
+func (s *TplStencil) SemverCompare(a, b string) (int, error) {
+	av, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+
+	bv, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return av.Compare(bv), nil
+}
+
+// SemverSatisfies returns true if the provided semantic version
+// satisfies the provided constraint (e.g., ">=1.2.0 <2.0.0" or
+// "~1.2").
+//
+//	{{- if stencil.SemverSatisfies "1.2.3" "^1.0.0" }}
+//	supported
+//	{{- end }}
+func (s *TplStencil) SemverSatisfies(version, constraint string) (bool, error) {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false, err
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	return c.Check(v), nil
+}
+
+// parseSemver parses the provided semantic version, returning an error
+// that includes the version if it is not valid.
+func parseSemver(version string) (*semver.Version, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid semantic version %q: %w", version, err)
+	}
+	return v, nil
+}
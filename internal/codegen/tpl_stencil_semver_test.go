@@ -0,0 +1,84 @@
+package codegen
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTplStencil_SemverInc(t *testing.T) {
+	s := &TplStencil{}
+
+	tests := []struct {
+		version string
+		part    string
+		want    string
+	}{
+		{"1.2.3", "major", "2.0.0"},
+		{"1.2.3", "minor", "1.3.0"},
+		{"1.2.3", "patch", "1.2.4"},
+		{"v1.2.3", "minor", "v1.3.0"},
+		{"1.2.3-rc.1", "patch", "1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.part, func(t *testing.T) {
+			got, err := s.SemverInc(tt.version, tt.part)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+
+	_, err := s.SemverInc("1.2.3", "build")
+	assert.ErrorContains(t, err, `unknown version part "build"`)
+
+	_, err = s.SemverInc("not-a-version", "major")
+	assert.ErrorContains(t, err, `invalid semantic version "not-a-version"`)
+}
+
+func TestTplStencil_SemverCompare(t *testing.T) {
+	s := &TplStencil{}
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.10.0", -1},
+		{"v1.2.3", "1.2.3", 0},
+		{"2.0.0", "2.0.0-rc.1", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			got, err := s.SemverCompare(tt.a, tt.b)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+
+	_, err := s.SemverCompare("1.2.3", "latest")
+	assert.ErrorContains(t, err, `invalid semantic version "latest"`)
+}
+
+func TestTplStencil_SemverSatisfies(t *testing.T) {
+	s := &TplStencil{}
+
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.3", "^1.0.0", true},
+		{"2.0.0", "^1.0.0", false},
+		{"1.2.3", ">=1.2.0 <2.0.0", true},
+		{"1.3.0", "~1.2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.constraint, func(t *testing.T) {
+			got, err := s.SemverSatisfies(tt.version, tt.constraint)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+
+	_, err := s.SemverSatisfies("1.2.3", "not a constraint")
+	assert.ErrorContains(t, err, `invalid version constraint "not a constraint"`)
+}
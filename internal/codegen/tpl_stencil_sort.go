@@ -0,0 +1,135 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains helpers for deterministically
+// iterating over maps in templates.
+
+package codegen
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// SortedItem is a key/value pair of a map returned by
+// [TplStencil.SortedItems].
+type SortedItem struct {
+	// Key is the key of the map entry
+	Key any
+
+	// Value is the value of the map entry
+	Value any
+}
+
+// SortedKeys returns the keys of the provided map sorted in ascending
+// order. Go maps have no defined iteration order, so this should be used
+// whenever the order of a map's contents ends up in a rendered file.
+//
+// Numeric keys are sorted numerically, all other keys are sorted by
+// their string representation.
+//
+//	{{- $m := dict "b" 2 "a" 1 }}
+//	{{- range $k := stencil.SortedKeys $m }}
+//	{{ $k }}: {{ index $m $k }}
+//	{{- end }}
+func (s *TplStencil) SortedKeys(m any) ([]any, error) {
+	v, err := mapValue(m)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := v.MapKeys()
+	slices.SortFunc(keys, compareMapKeys)
+
+	rv := make([]any, 0, len(keys))
+	for _, k := range keys {
+		rv = append(rv, k.Interface())
+	}
+	return rv, nil
+}
+
+// SortedItems returns the key/value pairs of the provided map sorted
+// by key. See stencil.SortedKeys for how keys are ordered.
+//
+//	{{- range $item := stencil.SortedItems (dict "b" 2 "a" 1) }}
+//	{{ $item.Key }}: {{ $item.Value }}
+//	{{- end }}
+func (s *TplStencil) SortedItems(m any) ([]SortedItem, error) {
+	v, err := mapValue(m)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := v.MapKeys()
+	slices.SortFunc(keys, compareMapKeys)
+
+	rv := make([]SortedItem, 0, len(keys))
+	for _, k := range keys {
+		rv = append(rv, SortedItem{Key: k.Interface(), Value: v.MapIndex(k).Interface()})
+	}
+	return rv, nil
+}
+
+// mapValue returns the reflect.Value of the provided map, dereferencing
+// pointers and interfaces. A nil map is treated as an empty map.
+func mapValue(m any) (reflect.Value, error) {
+	if m == nil {
+		return reflect.ValueOf(map[string]any{}), nil
+	}
+
+	v := reflect.ValueOf(m)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.ValueOf(map[string]any{}), nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("expected a map, got %T", m)
+	}
+	return v, nil
+}
+
+// compareMapKeys compares two map keys for sorting. Keys of the same
+// numeric kind are compared numerically, everything else is compared by
+// its string representation.
+func compareMapKeys(a, b reflect.Value) int {
+	a, b = unwrapInterface(a), unwrapInterface(b)
+	if a.Kind() == b.Kind() {
+		switch {
+		case a.CanInt():
+			return cmp.Compare(a.Int(), b.Int())
+		case a.CanUint():
+			return cmp.Compare(a.Uint(), b.Uint())
+		case a.CanFloat():
+			return cmp.Compare(a.Float(), b.Float())
+		}
+	}
+
+	return cmp.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+// unwrapInterface returns the concrete value held by an interface
+// value, e.g., the keys of a map[any]any.
+func unwrapInterface(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		return v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTplStencil_SortedKeys(t *testing.T) {
+	s := &TplStencil{}
+
+	tests := []struct {
+		name string
+		m    any
+		want []any
+	}{
+		{
+			name: "should sort string keys",
+			m:    map[string]any{"c": 3, "a": 1, "b": 2},
+			want: []any{"a", "b", "c"},
+		},
+		{
+			name: "should sort int keys numerically",
+			m:    map[int]string{10: "x", 2: "y", 1: "z"},
+			want: []any{1, 2, 10},
+		},
+		{
+			name: "should sort interface keys",
+			m:    map[any]any{"b": 1, "a": 2},
+			want: []any{"a", "b"},
+		},
+		{
+			name: "should support pointers to maps",
+			m:    &map[string]int{"b": 1, "a": 2},
+			want: []any{"a", "b"},
+		},
+		{
+			name: "should treat nil as an empty map",
+			m:    nil,
+			want: []any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.SortedKeys(tt.m)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestTplStencil_SortedKeysErrorsOnNonMap(t *testing.T) {
+	s := &TplStencil{}
+
+	_, err := s.SortedKeys([]string{"a"})
+	assert.ErrorContains(t, err, "expected a map, got []string")
+
+	_, err = s.SortedItems("a")
+	assert.ErrorContains(t, err, "expected a map, got string")
+}
+
+func TestTplStencil_SortedItems(t *testing.T) {
+	s := &TplStencil{}
+
+	m := map[string]any{}
+	for _, k := range []string{"delta", "alpha", "echo", "charlie", "bravo"} {
+		m[k] = len(k)
+	}
+
+	want := []SortedItem{
+		{Key: "alpha", Value: 5},
+		{Key: "bravo", Value: 5},
+		{Key: "charlie", Value: 7},
+		{Key: "delta", Value: 5},
+		{Key: "echo", Value: 4},
+	}
+
+	// Map iteration order is randomized, so ensure that the order is
+	// stable across many calls.
+	for range 50 {
+		got, err := s.SortedItems(m)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, want)
+	}
+}
@@ -3,18 +3,24 @@ package codegen
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-billy/v5"
+	"github.com/jaredallard/vcs/resolver"
 	"github.com/pkg/errors"
+	"go.rgst.io/stencil/v2/internal/ci"
 	"go.rgst.io/stencil/v2/internal/modules"
 	"go.rgst.io/stencil/v2/internal/modules/modulestest"
 	"go.rgst.io/stencil/v2/pkg/configuration"
 	"go.rgst.io/stencil/v2/pkg/slogext"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
 )
 
 func TestTplStencil_ReadBlocks(t *testing.T) {
@@ -76,6 +82,133 @@ func TestTplStencil_ReadBlocks(t *testing.T) {
 	}
 }
 
+func TestTplStencil_ReadBlocksWithPrefix(t *testing.T) {
+	s := &TplStencil{log: slogext.NewTestLogger(t)}
+
+	got, err := s.ReadBlocksWithPrefix("testdata/blocks-prefix-test.txt", "route-")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, []SortedItem{
+		{Key: "route-1", Value: "a"},
+		{Key: "route-2", Value: "b"},
+	})
+
+	got, err = s.ReadBlocksWithPrefix("testdata/blocks-prefix-test.txt", "does-not-match-")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, []SortedItem{})
+
+	_, err = s.ReadBlocksWithPrefix("testdata/does-not-exist.txt", "route-")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestTplStencil_BlockHasContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		fpath string
+		block string
+		want  bool
+	}{
+		{"non-empty block", "testdata/blocks-has-content.txt", "filled", true},
+		{"empty block", "testdata/blocks-has-content.txt", "empty", false},
+		{"missing block", "testdata/blocks-has-content.txt", "missing", false},
+		{"missing file", "testdata/does-not-exist.txt", "filled", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TplStencil{log: slogext.NewTestLogger(t)}
+			got, err := s.BlockHasContent(tt.fpath, tt.block)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+
+	_, err := (&TplStencil{log: slogext.NewTestLogger(t)}).BlockHasContent("../testdata/blocks-test.txt", "e2e")
+	assert.ErrorIs(t, err, billy.ErrCrossedBoundary)
+}
+
+func TestTplStencil_ExistingBlockNames(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	assert.NilError(t, os.MkdirAll("services", 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join("services", "api.yaml"),
+		[]byte("## <<Stencil::Block(api)>>\n## <</Stencil::Block>>\n## <<Stencil::Block(shared)>>\n## <</Stencil::Block>>\n"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join("services", "worker.yaml"),
+		[]byte("## <<Stencil::Block(worker)>>\nreplicas: 2\n## <</Stencil::Block>>\n## <<Stencil::Block(shared)>>\n## <</Stencil::Block>>\n"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join("services", "README.md"),
+		[]byte("<!-- <<Stencil::Block(readme)>> -->\n<!-- <</Stencil::Block>> -->\n"), 0o644))
+
+	// Symlinks pointing outside of the project are not followed.
+	outside := filepath.Join(t.TempDir(), "outside.yaml")
+	assert.NilError(t, os.WriteFile(outside, []byte("## <<Stencil::Block(outside)>>\n## <</Stencil::Block>>\n"), 0o644))
+	assert.NilError(t, os.Symlink(outside, filepath.Join("services", "outside.yaml")))
+
+	s := &TplStencil{log: slogext.NewTestLogger(t)}
+	names, err := s.ExistingBlockNames("services/*.yaml")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"api", "shared", "worker"})
+
+	names, err = s.ExistingBlockNames("missing/*.yaml")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{})
+
+	_, err = s.ExistingBlockNames("../*.yaml")
+	assert.ErrorContains(t, err, `invalid glob "../*.yaml"`)
+}
+
+func TestTplStencil_Env(t *testing.T) {
+	env.Patch(t, "CI_REGISTRY", "registry.example.com")
+	env.Patch(t, "SECRET_TOKEN", "s3cr3t")
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name:       "testing",
+		AllowedEnv: []string{"CI_REGISTRY", "UNSET_VAR"},
+	})
+	assert.NilError(t, err)
+	s := &TplStencil{t: &Template{Module: m}, log: slogext.NewTestLogger(t)}
+
+	got, err := s.Env("CI_REGISTRY")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "registry.example.com")
+
+	got, err = s.Env("UNSET_VAR")
+	assert.NilError(t, err)
+	assert.Equal(t, got, "")
+
+	_, err = s.Env("SECRET_TOKEN")
+	assert.ErrorContains(t, err, `module "testing" is not allowed to read environment variable "SECRET_TOKEN"`)
+}
+
+func TestTplStencil_Glob(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+	for _, fpath := range []string{
+		"api/v1/users.proto", "api/v1/groups.proto", "api/v2/nested/orders.proto",
+		"api/README.md", "root.proto", ".git/objects/a.proto",
+	} {
+		assert.NilError(t, os.MkdirAll(filepath.Dir(fpath), 0o755))
+		assert.NilError(t, os.WriteFile(fpath, []byte{}, 0o644))
+	}
+
+	s := &TplStencil{log: slogext.NewTestLogger(t)}
+	for pattern, want := range map[string][]string{
+		"**/*.proto": {
+			"api/v1/groups.proto", "api/v1/users.proto", "api/v2/nested/orders.proto", "root.proto",
+		},
+		"api/**/*.proto": {"api/v1/groups.proto", "api/v1/users.proto", "api/v2/nested/orders.proto"},
+		"api/*/*.proto":  {"api/v1/groups.proto", "api/v1/users.proto"},
+		"api/v*":         {"api/v1", "api/v2"},
+		"*.md":           {},
+		"missing/**":     {},
+	} {
+		got, err := s.Glob(pattern)
+		assert.NilError(t, err, pattern)
+		assert.DeepEqual(t, got, want)
+	}
+
+	_, err := s.Glob("../**/*.proto")
+	assert.ErrorContains(t, err, `invalid pattern "../**/*.proto"`)
+
+	_, err = s.Glob("api/[")
+	assert.ErrorContains(t, err, "syntax error in pattern")
+}
+
 func must[T any](v T, err error) T {
 	if err != nil {
 		panic(err)
@@ -203,7 +336,7 @@ func TestTplStencil_GetModuleHook(t *testing.T) {
 				}
 			}
 
-			s.s.sharedState.hash() // Sorts the module hooks
+			s.s.sharedState.snapshot() // Sorts the module hooks
 
 			if got := s.GetModuleHook(moduleHookName); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("TplStencil.GetModuleHook() = %v, want %v", got, tt.want)
@@ -212,6 +345,62 @@ func TestTplStencil_GetModuleHook(t *testing.T) {
 	}
 }
 
+func TestTplStencil_AddToModuleHookExtensibleSchema(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+
+	owner := &configuration.TemplateRepositoryManifest{
+		Name: "test",
+		ModuleHooks: map[string]configuration.ModuleHook{
+			"hook": {
+				Extensible: true,
+				Schema: map[string]any{
+					"type":     "object",
+					"required": []any{"name"},
+					"properties": map[string]any{
+						"name": map[string]any{"type": "string"},
+					},
+				},
+			},
+			"closed": {},
+		},
+	}
+	contributor := &configuration.TemplateRepositoryManifest{
+		Name: "contrib",
+		ModuleHookSchemas: map[string]map[string]map[string]any{
+			"test": {
+				"hook": {"type": "string"},
+			},
+		},
+	}
+
+	s := &TplStencil{
+		t: must(NewTemplate(
+			must(modulestest.NewModuleFromTemplates(contributor)),
+			"not-a-real-template.tpl", 0o755, time.Now(), []byte(""), log, nil,
+		)),
+		s: &Stencil{sharedState: newSharedState(), modules: []*modules.Module{
+			{Name: "test", Manifest: owner},
+			{Name: "contrib", Manifest: contributor},
+		}},
+		log: log,
+	}
+
+	// Both the owner's and the contributor's shapes are allowed.
+	_, err := s.AddToModuleHook("test", "hook", map[string]any{"name": "abc"})
+	assert.NilError(t, err)
+	_, err = s.AddToModuleHook("test", "hook", "def")
+	assert.NilError(t, err)
+
+	// Data matching neither of them is not.
+	_, err = s.AddToModuleHook("test", "hook", 1)
+	assert.ErrorContains(t, err, "data failed json schema validation")
+
+	// Schemas for module hooks that aren't extensible are rejected.
+	contributor.ModuleHookSchemas["test"]["closed"] = map[string]any{"type": "string"}
+	_, err = s.AddToModuleHook("test", "closed", "def")
+	assert.ErrorContains(t, err, "which is not extensible")
+}
+
 // TestGlobals contains tests for ensuring that the Set/GetGlobal
 // functions work as expected.
 func TestGlobals(t *testing.T) {
@@ -296,6 +485,45 @@ func TestTplStencil_ReadFile(t *testing.T) {
 	assert.Equal(t, true, errors.Is(err, os.ErrNotExist))
 }
 
+func TestTplStencil_ReadJSONAndTOML(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"valid.json":   `{"name": "stencil", "tags": ["a", "b"]}`,
+		"invalid.json": `{"name": `,
+		"valid.toml":   "name = \"stencil\"\n\n[package]\nversion = 1\n",
+		"invalid.toml": "name = \n",
+	}
+	for name, contents := range files {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+	}
+	env.ChangeWorkingDir(t, dir)
+
+	s := &TplStencil{log: slogext.NewTestLogger(t)}
+
+	got, err := s.ReadJSON("valid.json")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, map[string]any{"name": "stencil", "tags": []any{"a", "b"}})
+
+	_, err = s.ReadJSON("invalid.json")
+	assert.ErrorContains(t, err, `failed to decode "invalid.json" as JSON`)
+
+	gotTOML, err := s.ReadTOML("valid.toml")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, gotTOML, map[string]any{
+		"name":    "stencil",
+		"package": map[string]any{"version": int64(1)},
+	})
+
+	_, err = s.ReadTOML("invalid.toml")
+	assert.ErrorContains(t, err, `failed to decode "invalid.toml" as TOML`)
+
+	_, err = s.ReadJSON("does-not-exist.json")
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = s.ReadTOML("../valid.toml")
+	assert.Assert(t, errors.Is(err, billy.ErrCrossedBoundary))
+}
+
 func TestTplStencil_Include(t *testing.T) {
 	type args struct {
 		name    string
@@ -429,3 +657,119 @@ func TestTplStencil_ReadDir(t *testing.T) {
 		return entry.Name() == "args" && entry.IsDir()
 	}))
 }
+
+func TestTplStencil_IsCI(t *testing.T) {
+	s := &TplStencil{}
+
+	for _, k := range ci.EnvVars {
+		t.Setenv(k, "")
+	}
+	assert.Equal(t, s.IsCI(), false, "expected IsCI to be false without CI env vars")
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.Equal(t, s.IsCI(), true, "expected IsCI to be true with GITHUB_ACTIONS set")
+}
+
+func TestTplStencil_OSArch(t *testing.T) {
+	s := &TplStencil{s: &Stencil{}}
+	assert.Equal(t, s.OS(), runtime.GOOS)
+	assert.Equal(t, s.Arch(), runtime.GOARCH)
+
+	s.s.SetTarget("plan9", "riscv64")
+	assert.Equal(t, s.OS(), "plan9")
+	assert.Equal(t, s.Arch(), "riscv64")
+}
+
+func TestTplStencil_Modules(t *testing.T) {
+	s := &TplStencil{s: &Stencil{modules: []*modules.Module{
+		{
+			Name:    "github.com/rgst-io/stencil-golang",
+			URI:     "https://github.com/rgst-io/stencil-golang",
+			Version: &resolver.Version{Tag: "v1.2.3", Commit: "0123456789abcdef"},
+		},
+		{
+			Name:    "github.com/rgst-io/stencil-base",
+			URI:     "https://github.com/rgst-io/stencil-base",
+			Version: &resolver.Version{Branch: "main", Commit: "fedcba9876543210"},
+		},
+		{
+			Name:    "github.com/rgst-io/local",
+			URI:     "../local",
+			Version: &resolver.Version{Virtual: "local"},
+		},
+	}}}
+
+	assert.DeepEqual(t, s.Modules(), []ModuleInfo{
+		{Name: "github.com/rgst-io/local", Version: "local", URL: "../local"},
+		{Name: "github.com/rgst-io/stencil-base", Version: "main@fedcba9", URL: "https://github.com/rgst-io/stencil-base"},
+		{Name: "github.com/rgst-io/stencil-golang", Version: "v1.2.3", URL: "https://github.com/rgst-io/stencil-golang"},
+	})
+}
+
+func TestTplStencil_GetModuleHookWithSource(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+	st := &Stencil{sharedState: newSharedState()}
+
+	newTplStencil := func(module, tplPath string) *TplStencil {
+		return &TplStencil{
+			t: must(NewTemplate(
+				must(modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+					Name: module,
+				})),
+				tplPath,
+				0o644,
+				time.Now(),
+				[]byte(""),
+				log,
+				nil,
+			)),
+			s:   st,
+			log: log,
+		}
+	}
+
+	owner := newTplStencil("owner", "owner.tpl")
+	a := newTplStencil("a", "a.tpl")
+	b := newTplStencil("b", "b.tpl")
+
+	_, err := a.AddToModuleHook("owner", "hook", "from-a")
+	assert.NilError(t, err)
+	_, err = b.AddToModuleHook("owner", "hook", "from-b", "also-from-b")
+	assert.NilError(t, err)
+
+	got := owner.GetModuleHookWithSource("hook")
+	slices.SortFunc(got, func(x, y ModuleHookEntry) int {
+		return strings.Compare(x.Value.(string), y.Value.(string))
+	})
+	assert.DeepEqual(t, got, []ModuleHookEntry{
+		{Module: "b", Template: "b.tpl", Value: "also-from-b"},
+		{Module: "a", Template: "a.tpl", Value: "from-a"},
+		{Module: "b", Template: "b.tpl", Value: "from-b"},
+	})
+
+	// The values should match what GetModuleHook returns.
+	assert.Equal(t, len(owner.GetModuleHook("hook")), len(got))
+	assert.DeepEqual(t, owner.GetModuleHookWithSource("does-not-exist"), []ModuleHookEntry{})
+}
+
+func TestTplStencil_ModuleHookCount(t *testing.T) {
+	log := slogext.NewTestLogger(t)
+	st := &Stencil{sharedState: newSharedState()}
+	s := &TplStencil{
+		t: must(NewTemplate(
+			must(modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{Name: "owner"})),
+			"owner.tpl", 0o644, time.Now(), []byte(""), log, nil,
+		)),
+		s:   st,
+		log: log,
+	}
+
+	assert.Equal(t, s.ModuleHookCount("hook"), 0)
+
+	for _, v := range []string{"a", "b", "c"} {
+		_, err := s.AddToModuleHook("owner", "hook", v)
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, s.ModuleHookCount("hook"), 3)
+	assert.Equal(t, s.ModuleHookCount("hook"), len(s.GetModuleHook("hook")))
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains helpers for converting durations and
+// quantities, commonly provided as strings, into numbers in templates.
+
+package codegen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quantitySuffixes maps the suffixes supported by ToQuantity to their
+// multiplier. Binary (e.g., "Ki") and decimal (e.g., "k") suffixes
+// are supported.
+var quantitySuffixes = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// ToDuration converts the provided duration (e.g., "30s", "1h30m" or
+// "7d") into a whole number of seconds. Numbers are treated as being
+// in seconds already. An error is returned if the duration is invalid
+// or isn't a whole number of seconds.
+//
+//	timeout: {{ stencil.ToDuration (stencil.Arg "timeout") }}
+func (s *TplStencil) ToDuration(v any) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("duration %v is not a whole number of seconds", v)
+		}
+		return int64(v), nil
+	case string:
+		d, err := parseDuration(v)
+		if err != nil {
+			return 0, err
+		}
+		if d%time.Second != 0 {
+			return 0, fmt.Errorf("duration %q is not a whole number of seconds", v)
+		}
+		return int64(d / time.Second), nil
+	default:
+		return 0, fmt.Errorf("unsupported duration type %T", v)
+	}
+}
+
+// parseDuration parses the provided duration using
+// [time.ParseDuration], with additional support for a plain number of
+// seconds (e.g., "30") and a number of days (e.g., "7d").
+func parseDuration(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	if secs, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+
+	if days, ok := strings.CutSuffix(str, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", str)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", str)
+	}
+	return d, nil
+}
+
+// ToQuantity converts the provided quantity (e.g., "512Mi", "1.5G" or
+// "100") into a whole number, such as a number of bytes. Both binary
+// (Ki, Mi, Gi, Ti, Pi, Ei) and decimal (k, M, G, T, P, E) suffixes are
+// supported. An error is returned if the quantity is invalid or isn't
+// a whole number.
+//
+//	memory: {{ stencil.ToQuantity (stencil.Arg "memory") }}
+func (s *TplStencil) ToQuantity(v any) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		return quantityToInt(v, v)
+	case string:
+		str := strings.TrimSpace(v)
+		num := strings.TrimRightFunc(str, func(r rune) bool {
+			return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		})
+
+		mult, ok := quantitySuffixes[str[len(num):]]
+		if !ok {
+			return 0, fmt.Errorf("invalid quantity %q: unknown suffix %q", v, str[len(num):])
+		}
+
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid quantity %q", v)
+		}
+		return quantityToInt(v, f*mult)
+	default:
+		return 0, fmt.Errorf("unsupported quantity type %T", v)
+	}
+}
+
+// quantityToInt returns the provided quantity as an int64, returning
+// an error referencing the original value if it isn't a whole number
+// or doesn't fit.
+func quantityToInt(orig any, f float64) (int64, error) {
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("quantity %v is not a whole number", orig)
+	}
+	if f >= math.MaxInt64 || f <= math.MinInt64 {
+		return 0, fmt.Errorf("quantity %v is too large", orig)
+	}
+	return int64(f), nil
+}
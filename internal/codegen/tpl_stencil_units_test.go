@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTplStencil_ToDuration(t *testing.T) {
+	s := &TplStencil{}
+
+	tests := []struct {
+		in   any
+		want int64
+	}{
+		{"30s", 30},
+		{"1m30s", 90},
+		{"2h", 7200},
+		{"7d", 604800},
+		{"45", 45},
+		{60, 60},
+		{float64(120), 120},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.in), func(t *testing.T) {
+			got, err := s.ToDuration(tt.in)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+
+	_, err := s.ToDuration("soon")
+	assert.ErrorContains(t, err, `invalid duration "soon"`)
+
+	_, err = s.ToDuration("1.5d")
+	assert.ErrorContains(t, err, `invalid duration "1.5d"`)
+
+	_, err = s.ToDuration("500ms")
+	assert.ErrorContains(t, err, `duration "500ms" is not a whole number of seconds`)
+
+	_, err = s.ToDuration([]any{"30s"})
+	assert.ErrorContains(t, err, "unsupported duration type []interface {}")
+}
+
+func TestTplStencil_ToQuantity(t *testing.T) {
+	s := &TplStencil{}
+
+	tests := []struct {
+		in   any
+		want int64
+	}{
+		{"100", 100},
+		{"1k", 1000},
+		{"512Mi", 512 * 1024 * 1024},
+		{"1Gi", 1 << 30},
+		{"1.5G", 1_500_000_000},
+		{"2Ti", 2 << 40},
+		{" 4Ki ", 4096},
+		{1024, 1024},
+		{float64(2048), 2048},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.in), func(t *testing.T) {
+			got, err := s.ToQuantity(tt.in)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+
+	_, err := s.ToQuantity("1Gb")
+	assert.ErrorContains(t, err, `invalid quantity "1Gb": unknown suffix "Gb"`)
+
+	_, err = s.ToQuantity("lots")
+	assert.ErrorContains(t, err, `invalid quantity "lots"`)
+
+	_, err = s.ToQuantity("-1Gi")
+	assert.ErrorContains(t, err, `invalid quantity "-1Gi"`)
+
+	_, err = s.ToQuantity("1.5")
+	assert.ErrorContains(t, err, "quantity 1.5 is not a whole number")
+
+	_, err = s.ToQuantity("100Ei")
+	assert.ErrorContains(t, err, "quantity 100Ei is too large")
+}
@@ -85,6 +85,11 @@ type module struct {
 
 	// Version is the version of the current module
 	Version *resolver.Version
+
+	// Digest is a digest of the contents of the module, see
+	// [modules.Module.Digest]. This is only set for the modules in
+	// Runtime.Modules.
+	Digest string
 }
 
 // stencilTemplate contains information about the current template
@@ -173,9 +178,12 @@ func NewValues(ctx context.Context, sm *configuration.Manifest, mods []*modules.
 	}
 
 	for _, m := range mods {
+		//nolint:errcheck // Why: Best effort, the digest is informational.
+		digest, _ := m.Digest()
 		vals.Runtime.Modules = append(vals.Runtime.Modules, module{
 			Name:    m.Name,
 			Version: m.Version,
+			Digest:  digest,
 		})
 	}
 
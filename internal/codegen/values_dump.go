@@ -0,0 +1,116 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for dumping the values used by
+// a render for external tooling.
+
+package codegen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+)
+
+// RedactedValue is the value used in place of the value of arguments
+// marked as secret when dumping values.
+const RedactedValue = "<redacted>"
+
+// DumpedValues are the values used to render the templates of a
+// project, as written by [Stencil.DumpValues].
+type DumpedValues struct {
+	// Values are the values passed to all templates
+	Values *Values `json:"values"`
+
+	// Arguments contains the resolved value of every argument declared
+	// by a module, keyed by the module's name and then the argument's
+	// name.
+	Arguments map[string]map[string]any `json:"arguments"`
+}
+
+// DumpValues writes the values used to render the templates of the
+// project, including the resolved arguments of every module, as JSON
+// to the provided writer. The values of arguments marked as secret are
+// replaced with [RedactedValue]. Arguments that can't be resolved
+// (e.g., required but not set) are left out.
+func (s *Stencil) DumpValues(ctx context.Context, w io.Writer) error {
+	dump := &DumpedValues{
+		Values:    NewValues(ctx, s.m, s.modules),
+		Arguments: make(map[string]map[string]any),
+	}
+
+	for _, m := range s.modules {
+		t, err := NewTemplate(m, "dumpValues", 0o000, time.Time{}, nil, s.log, nil)
+		if err != nil {
+			return err
+		}
+		ts := &TplStencil{s: s, t: t, log: s.log}
+
+		args := make(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(m.Manifest.Arguments)) {
+			expl, err := ts.explainArg(name)
+			if err != nil {
+				s.log.WithError(err).Debug("Skipping argument that could not be resolved", "module", m.Name, "argument", name)
+				continue
+			}
+
+			if expl.Secret {
+				args[name] = RedactedValue
+				continue
+			}
+			args[name] = jsonCompatible(expl.Value)
+		}
+		dump.Arguments[m.Name] = args
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dump); err != nil {
+		return fmt.Errorf("failed to encode values as JSON: %w", err)
+	}
+	return nil
+}
+
+// jsonCompatible returns the provided value with all map[any]any, as
+// created for the zero value of object arguments, converted into
+// map[string]any so that it can be encoded as JSON.
+func jsonCompatible(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[k] = jsonCompatible(val)
+		}
+		return m
+	case []any:
+		l := make([]any, len(v))
+		for i, val := range v {
+			l[i] = jsonCompatible(val)
+		}
+		return l
+	}
+	return v
+}
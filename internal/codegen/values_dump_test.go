@@ -0,0 +1,73 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+func TestDumpValues(t *testing.T) {
+	ctx := context.Background()
+	log := slogext.NewTestLogger(t)
+
+	fs := memfs.New()
+	createFiles(t, fs, map[string]string{
+		"manifest.yaml": `name: testing
+arguments:
+  name:
+    schema:
+      type: string
+  token:
+    secret: true
+    schema:
+      type: string
+  settings:
+    schema:
+      type: object
+  required:
+    required: true
+`,
+		"templates/test.tpl": `{{ stencil.Arg "name" }}`,
+	})
+	m, err := modulestest.NewWithFS(ctx, "testing", fs)
+	assert.NilError(t, err, "failed to NewWithFS")
+
+	st := NewStencil(&configuration.Manifest{
+		Name: "test",
+		Arguments: map[string]any{
+			"name":  "hello",
+			"token": "hunter2",
+		},
+	}, nil, []*modules.Module{m}, log, false)
+
+	var buf bytes.Buffer
+	assert.NilError(t, st.DumpValues(ctx, &buf))
+	assert.Assert(t, !bytes.Contains(buf.Bytes(), []byte("hunter2")), "secret leaked: %s", buf.String())
+
+	var got struct {
+		Values struct {
+			Config struct {
+				Name string
+			}
+		} `json:"values"`
+		Arguments map[string]map[string]any `json:"arguments"`
+	}
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	assert.Equal(t, got.Values.Config.Name, "test")
+	assert.DeepEqual(t, got.Arguments, map[string]map[string]any{
+		"testing": {
+			"name":     "hello",
+			"token":    RedactedValue,
+			"settings": map[string]any{},
+		},
+	})
+}
@@ -14,12 +14,14 @@
 // limitations under the License.
 
 // Package dotnotation implements a dotnotation (hello.world) for
-// accessing fields within a map[string]interface{}
+// accessing fields within a map[string]interface{}. Numeric path
+// segments can be used to access elements of slices (servers.0.host).
 package dotnotation
 
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -60,6 +62,45 @@ func getFieldOnMap(data interface{}, key string) (interface{}, error) {
 	return nil, fmt.Errorf("key %q not found", key)
 }
 
+// getIndexOnSlice returns the element at the index key of the provided
+// slice (or array). key must be a non-negative integer.
+func getIndexOnSlice(data interface{}, key string) (interface{}, error) {
+	dataVal := reflect.ValueOf(data)
+	if k := dataVal.Kind(); k != reflect.Slice && k != reflect.Array {
+		return nil, fmt.Errorf("data is not a slice")
+	}
+
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 {
+		return nil, fmt.Errorf("key %q is not a valid index into a slice", key)
+	}
+	if i >= dataVal.Len() {
+		return nil, fmt.Errorf("index %d out of range (length %d)", i, dataVal.Len())
+	}
+
+	return dataVal.Index(i).Interface(), nil
+}
+
+// getField returns the value of key on the provided data, which must
+// either be a map or a slice.
+func getField(data interface{}, key string) (interface{}, error) {
+	if k := reflect.ValueOf(data).Kind(); k == reflect.Slice || k == reflect.Array {
+		return getIndexOnSlice(data, key)
+	}
+	return getFieldOnMap(data, key)
+}
+
+// isTraversable returns true if the provided type can be accessed
+// further using dotnotation.
+func isTraversable(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+
+	k := t.Kind()
+	return k == reflect.Map || k == reflect.Slice || k == reflect.Array
+}
+
 // get is a recursive function to get a field from a map[interface{}]interface{}
 // this is done by splitting the key on "." and using the first part of the
 // split, if there is anymore parts of the key then get() is called with
@@ -67,7 +108,7 @@ func getFieldOnMap(data interface{}, key string) (interface{}, error) {
 func get(data interface{}, key string) (interface{}, error) {
 	spl := strings.Split(key, ".")
 
-	v, err := getFieldOnMap(data, spl[0])
+	v, err := getField(data, spl[0])
 	if err != nil {
 		return nil, err
 	}
@@ -78,8 +119,8 @@ func get(data interface{}, key string) (interface{}, error) {
 		// process
 		nextKey := spl[1:][0]
 		nextDataTyp := reflect.TypeOf(v)
-		if nextDataTyp == nil || nextDataTyp.Kind() != reflect.Map {
-			return nil, fmt.Errorf("key %q is not a map, got %v on %q", nextKey, nextDataTyp, reflect.TypeOf(data))
+		if !isTraversable(nextDataTyp) {
+			return nil, fmt.Errorf("key %q is not a map or slice, got %v on %q", nextKey, nextDataTyp, reflect.TypeOf(data))
 		}
 
 		// pop the first key, and call get() again
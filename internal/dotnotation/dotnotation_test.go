@@ -75,6 +75,68 @@ func TestGet(t *testing.T) {
 			want:    4,
 			wantErr: false,
 		},
+		{
+			name: "should support slice indices",
+			args: args{
+				key: "servers.1",
+				data: map[string]interface{}{
+					"servers": []interface{}{"a", "b"},
+				},
+			},
+			want:    "b",
+			wantErr: false,
+		},
+		{
+			name: "should support keys after slice indices",
+			args: args{
+				key: "servers.0.host",
+				data: map[string]interface{}{
+					"servers": []interface{}{
+						map[string]interface{}{"host": "example.com"},
+					},
+				},
+			},
+			want:    "example.com",
+			wantErr: false,
+		},
+		{
+			name: "should support nested slices",
+			args: args{
+				key: "matrix.1.0",
+				data: map[string]interface{}{
+					"matrix": []interface{}{
+						[]interface{}{1, 2},
+						[]interface{}{3, 4},
+					},
+				},
+			},
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name: "should fail on out of range indices",
+			args: args{
+				key: "servers.2.host",
+				data: map[string]interface{}{
+					"servers": []interface{}{
+						map[string]interface{}{"host": "example.com"},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "should fail on non-numeric keys for slices",
+			args: args{
+				key: "servers.first",
+				data: map[string]interface{}{
+					"servers": []interface{}{"a"},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -89,3 +151,10 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOutOfRangeError(t *testing.T) {
+	_, err := Get(map[string]interface{}{"servers": []interface{}{"a"}}, "servers.3")
+	if err == nil || err.Error() != "index 3 out of range (length 1)" {
+		t.Errorf("Get() error = %v, want index out of range error", err)
+	}
+}
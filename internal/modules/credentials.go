@@ -0,0 +1,128 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for providing credentials used
+// when fetching modules.
+
+package modules
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// CredentialProvider provides the credentials used to fetch modules,
+// allowing them to be sourced programmatically (e.g., from a secrets
+// manager) instead of from git's configuration.
+type CredentialProvider interface {
+	// Token returns the token to authenticate to the provided host (e.g.,
+	// github.com) with. If an empty string is returned, the ambient
+	// credentials from git's configuration are used instead.
+	Token(ctx context.Context, host string) (string, error)
+}
+
+// tokenUsername is the username sent alongside tokens. GitHub requires
+// this exact username for tokens while most other hosts accept any.
+const tokenUsername = "x-access-token"
+
+// gitConfigEnvMu serializes changes to the GIT_CONFIG_* environment
+// variables made by [withGitConfig].
+var gitConfigEnvMu sync.Mutex
+
+// authHeader returns the git configuration key and value that send the
+// token provided by creds for the host of the provided URI as an HTTP
+// header. Empty strings are returned if creds is nil, no token is
+// provided, the URI isn't HTTP(S) or it already contains credentials.
+func authHeader(ctx context.Context, creds CredentialProvider, uri string) (key, value string, err error) {
+	if creds == nil {
+		return "", "", nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		//nolint:nilerr // Why: Only URIs we can parse are authenticated.
+		return "", "", nil
+	}
+
+	token, err := creds.Token(ctx, u.Hostname())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get credentials for %q: %w", u.Hostname(), err)
+	}
+	if token == "" {
+		return "", "", nil
+	}
+
+	// Scope the header to the host so that it is never sent anywhere
+	// else, e.g., to other modules fetched at the same time.
+	key = "http." + u.Scheme + "://" + u.Host + "/.extraHeader"
+	value = "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(tokenUsername+":"+token))
+	return key, value, nil
+}
+
+// withGitConfig runs fn with the provided git configuration key set to
+// value for every git command it runs. The configuration is passed
+// through the GIT_CONFIG_* environment variables, keeping it out of
+// the command line, the cloned repository's .git/config and error
+// messages, and is removed again once fn returns. If key is empty, fn
+// is run as-is.
+func withGitConfig(key, value string, fn func() error) error {
+	if key == "" {
+		return fn()
+	}
+
+	// The environment is shared by the whole process, so only allow one
+	// caller to change it at a time.
+	gitConfigEnvMu.Lock()
+	defer gitConfigEnvMu.Unlock()
+
+	// Append to any configuration provided through the environment by
+	// the user instead of replacing it.
+	count := 0
+	origCount, hasCount := os.LookupEnv("GIT_CONFIG_COUNT")
+	if hasCount {
+		var err error
+		if count, err = strconv.Atoi(origCount); err != nil {
+			return fmt.Errorf("failed to parse GIT_CONFIG_COUNT: %w", err)
+		}
+	}
+
+	keyEnv := "GIT_CONFIG_KEY_" + strconv.Itoa(count)
+	valueEnv := "GIT_CONFIG_VALUE_" + strconv.Itoa(count)
+	defer func() {
+		os.Unsetenv(keyEnv)
+		os.Unsetenv(valueEnv)
+		if hasCount {
+			os.Setenv("GIT_CONFIG_COUNT", origCount)
+		} else {
+			os.Unsetenv("GIT_CONFIG_COUNT")
+		}
+	}()
+	if err := errors.Join(
+		os.Setenv(keyEnv, key),
+		os.Setenv(valueEnv, value),
+		os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count+1)),
+	); err != nil {
+		return fmt.Errorf("failed to set git configuration: %w", err)
+	}
+
+	return fn()
+}
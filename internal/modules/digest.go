@@ -0,0 +1,115 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for computing a digest of the
+// contents of a module.
+
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// Digest returns a deterministic digest (e.g., "sha256:<hex>") of the
+// contents of the module that affect what it generates: its
+// manifest.yaml and all files in its templates/ directory. This allows
+// detecting when the contents of a module changed without its version
+// changing (e.g., a module tracking a branch).
+//
+// The digest is only computed once the module has been fetched (see
+// [Module.GetFS]), an empty string is returned otherwise.
+func (m *Module) Digest() (string, error) {
+	if m.fs == nil {
+		return "", nil
+	}
+	if m.digest != "" {
+		return m.digest, nil
+	}
+
+	digest, err := digestFS(m.fs)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute digest of module %q: %w", m.Name, err)
+	}
+	m.digest = digest
+	return digest, nil
+}
+
+// digestFS returns a digest of the manifest.yaml and templates/
+// directory of the provided filesystem. Files are hashed in order of
+// their path, alongside their path and size, so that moving content
+// between files changes the digest.
+func digestFS(fs billy.Filesystem) (string, error) {
+	files := []string{"manifest.yaml"}
+	err := util.Walk(fs, "templates", func(pth string, inf os.FileInfo, err error) error {
+		if err != nil {
+			// Modules without templates (e.g., extensions) are valid.
+			if os.IsNotExist(err) && pth == "templates" {
+				return nil
+			}
+			return err
+		}
+
+		if inf.Mode().IsRegular() {
+			files = append(files, filepath.ToSlash(pth))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	slices.Sort(files)
+
+	h := sha256.New()
+	for _, name := range files {
+		if err := digestFile(h, fs, name); err != nil {
+			return "", err
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digestFile writes the path, size and contents of the provided file to
+// the provided hash.
+func digestFile(h io.Writer, fs billy.Filesystem, name string) error {
+	f, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	// Separate fields with a NUL byte, which can't be part of a path.
+	_, err = io.WriteString(h, name+"\x00"+strconv.Itoa(len(b))+"\x00")
+	if err != nil {
+		return err
+	}
+	_, err = h.Write(b)
+	return err
+}
@@ -0,0 +1,67 @@
+package modules_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"gotest.tools/v3/assert"
+)
+
+// digestOf returns the digest of a module with the provided files.
+func digestOf(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	fs := memfs.New()
+	for name, contents := range files {
+		f, err := fs.Create(name)
+		assert.NilError(t, err)
+		_, err = f.Write([]byte(contents))
+		assert.NilError(t, err)
+		assert.NilError(t, f.Close())
+	}
+
+	m, err := modulestest.NewWithFS(context.Background(), "testing", fs)
+	assert.NilError(t, err)
+
+	digest, err := m.Digest()
+	assert.NilError(t, err)
+	return digest
+}
+
+func TestModuleDigest(t *testing.T) {
+	files := map[string]string{
+		"manifest.yaml":           "name: testing\n",
+		"templates/a.txt.tpl":     "a",
+		"templates/dir/b.txt.tpl": "b",
+	}
+
+	digest := digestOf(t, files)
+	assert.Assert(t, strings.HasPrefix(digest, "sha256:"), digest)
+	assert.Equal(t, digestOf(t, files), digest, "expected digest to be stable")
+
+	// Files outside of templates/ don't change what a module generates.
+	files["README.md"] = "readme"
+	assert.Equal(t, digestOf(t, files), digest)
+
+	files["templates/a.txt.tpl"] = "changed"
+	assert.Assert(t, digestOf(t, files) != digest, "expected digest to change with contents")
+
+	// Moving content between files changes the digest.
+	assert.Assert(t, digestOf(t, map[string]string{
+		"manifest.yaml":       "name: testing\n",
+		"templates/a.txt.tpl": "ab",
+	}) != digestOf(t, map[string]string{
+		"manifest.yaml":       "name: testing\n",
+		"templates/a.txt.tpl": "a",
+		"templates/b.txt.tpl": "b",
+	}))
+
+	// Modules that haven't been fetched have no digest.
+	digest, err := (&modules.Module{Name: "testing"}).Digest()
+	assert.NilError(t, err)
+	assert.Equal(t, digest, "")
+}
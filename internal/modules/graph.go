@@ -0,0 +1,193 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file implements building and printing the
+// dependency graph of a project's modules.
+
+package modules
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jaredallard/vcs/resolver"
+)
+
+// GraphEdge is a dependency between two modules in a [Graph].
+type GraphEdge struct {
+	// From is the import path of the module that depends on To. It is
+	// empty when the dependency is declared by the project.
+	From string
+
+	// To is the import path of the module being depended on.
+	To string
+
+	// Version is the version that From requested for To, empty if any
+	// version is allowed.
+	Version string
+
+	// Override is the version from the project's moduleOverrides that
+	// was used instead of Version, empty if To was not overridden.
+	Override string
+}
+
+// Graph is the dependency graph of a project's modules.
+type Graph struct {
+	// Project is the name of the project the graph was built for.
+	Project string
+
+	// Modules are the resolved modules, sorted by name.
+	Modules []*Module
+
+	// Edges are the dependencies between the project and modules,
+	// sorted by From and then To.
+	Edges []GraphEdge
+}
+
+// FetchModuleGraph fetches modules for a given Manifest, like
+// [FetchModules], and returns the resulting dependency graph.
+func FetchModuleGraph(ctx context.Context, opts *ModuleResolveOptions) (*Graph, error) {
+	modules, edges, err := fetchModules(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{Project: opts.Manifest.Name}
+	for _, m := range modules {
+		g.Modules = append(g.Modules, m.Module)
+	}
+	slices.SortFunc(g.Modules, func(a, b *Module) int { return strings.Compare(a.Name, b.Name) })
+
+	slices.SortFunc(edges, func(a, b GraphEdge) int {
+		return cmp.Or(
+			cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To),
+			cmp.Compare(a.Version, b.Version), cmp.Compare(a.Override, b.Override),
+		)
+	})
+	g.Edges = slices.Compact(edges)
+
+	return g, nil
+}
+
+// graphVersion returns a short, human readable, representation of the
+// provided version for use in a graph.
+func graphVersion(v *resolver.Version) string {
+	switch {
+	case v == nil:
+		return ""
+	case v.Virtual != "":
+		return v.Virtual
+	case v.Tag != "":
+		return v.Tag
+	case v.Branch != "":
+		return "branch " + v.Branch
+	}
+	return v.Commit
+}
+
+// label returns the label for the edge, empty if it has none.
+func (e *GraphEdge) label() string {
+	if e.Override == "" {
+		return e.Version
+	}
+
+	requested := e.Version
+	if requested == "" {
+		requested = "*"
+	}
+	return requested + " (override: " + e.Override + ")"
+}
+
+// nodes returns the identifier and label of every node in the graph,
+// starting with the project.
+func (g *Graph) nodes() (ids []string, labels []string) {
+	ids = append(ids, "")
+	labels = append(labels, g.Project)
+	for _, m := range g.Modules {
+		ids = append(ids, m.Name)
+
+		label := m.Name
+		if v := graphVersion(m.Version); v != "" {
+			label += "\n" + v
+		}
+		labels = append(labels, label)
+	}
+	return ids, labels
+}
+
+// DOT returns the graph in the Graphviz DOT format.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph stencil {\n")
+
+	ids, labels := g.nodes()
+	name := func(id string) string {
+		if id == "" {
+			return fmt.Sprintf("%q", g.Project)
+		}
+		return fmt.Sprintf("%q", id)
+	}
+	for i, id := range ids {
+		shape := "ellipse"
+		if id == "" {
+			shape = "box"
+		}
+		fmt.Fprintf(&b, "  %s [label=%q, shape=%s];\n", name(id), labels[i], shape)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s", name(e.From), name(e.To))
+		if label := e.label(); label != "" {
+			fmt.Fprintf(&b, " [label=%q]", label)
+		}
+		b.WriteString(";\n")
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid returns the graph as a Mermaid flowchart.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	// Mermaid node identifiers can't contain most of the characters in
+	// an import path, so nodes are numbered instead.
+	ids, labels := g.nodes()
+	nodeIDs := make(map[string]string, len(ids))
+	for i, id := range ids {
+		nodeIDs[id] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodeIDs[id], mermaidEscape(labels[i]))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->", nodeIDs[e.From])
+		if label := e.label(); label != "" {
+			fmt.Fprintf(&b, "|\"%s\"|", mermaidEscape(label))
+		}
+		fmt.Fprintf(&b, " %s\n", nodeIDs[e.To])
+	}
+
+	return b.String()
+}
+
+// mermaidEscape escapes the provided string for use in a quoted Mermaid
+// label.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s)
+}
@@ -0,0 +1,95 @@
+package modules_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+// newTestGraph returns the graph of a project depending on a, which
+// depends on b.
+func newTestGraph(t *testing.T) *modules.Graph {
+	a, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name:    "a",
+		Modules: []*configuration.TemplateRepository{{Name: "b", Version: "~1.2.0"}},
+	})
+	assert.NilError(t, err, "failed to create module")
+	b, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{Name: "b"})
+	assert.NilError(t, err, "failed to create module")
+
+	g, err := modules.FetchModuleGraph(context.Background(), &modules.ModuleResolveOptions{
+		Manifest: &configuration.Manifest{
+			Name:    "testing-project",
+			Modules: []*configuration.TemplateRepository{{Name: "a"}},
+		},
+		Replacements: map[string]*modules.Module{"a": a, "b": b},
+		Log:          slogext.NewTestLogger(t),
+	})
+	assert.NilError(t, err, "failed to call FetchModuleGraph()")
+	return g
+}
+
+func TestFetchModuleGraph(t *testing.T) {
+	g := newTestGraph(t)
+
+	assert.Equal(t, g.Project, "testing-project")
+	assert.Equal(t, len(g.Modules), 2)
+	assert.DeepEqual(t, g.Edges, []modules.GraphEdge{
+		{From: "", To: "a"},
+		{From: "a", To: "b", Version: "~1.2.0"},
+	})
+}
+
+func TestGraphDOT(t *testing.T) {
+	assert.Equal(t, newTestGraph(t).DOT(), `digraph stencil {
+  "testing-project" [label="testing-project", shape=box];
+  "a" [label="a\nvfs", shape=ellipse];
+  "b" [label="b\nvfs", shape=ellipse];
+  "testing-project" -> "a";
+  "a" -> "b" [label="~1.2.0"];
+}
+`)
+}
+
+func TestGraphMermaid(t *testing.T) {
+	assert.Equal(t, newTestGraph(t).Mermaid(), `flowchart TD
+  n0["testing-project"]
+  n1["a<br/>vfs"]
+  n2["b<br/>vfs"]
+  n0 --> n1
+  n1 -->|"~1.2.0"| n2
+`)
+}
+
+func TestGraphShowsModuleOverrides(t *testing.T) {
+	a, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name:    "a",
+		Modules: []*configuration.TemplateRepository{{Name: "b", Version: "~1.2.0"}},
+	})
+	assert.NilError(t, err, "failed to create module")
+	b, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{Name: "b"})
+	assert.NilError(t, err, "failed to create module")
+
+	g, err := modules.FetchModuleGraph(context.Background(), &modules.ModuleResolveOptions{
+		Manifest: &configuration.Manifest{
+			Name:            "testing-project",
+			Modules:         []*configuration.TemplateRepository{{Name: "a"}},
+			ModuleOverrides: map[string]string{"b": "1.5.0"},
+		},
+		Replacements: map[string]*modules.Module{"a": a, "b": b},
+		Log:          slogext.NewTestLogger(t),
+	})
+	assert.NilError(t, err, "failed to call FetchModuleGraph()")
+
+	assert.DeepEqual(t, g.Edges, []modules.GraphEdge{
+		{From: "", To: "a"},
+		{From: "a", To: "b", Version: "~1.2.0", Override: "1.5.0"},
+	})
+	assert.Assert(t, strings.Contains(g.DOT(), `"a" -> "b" [label="~1.2.0 (override: 1.5.0)"];`), g.DOT())
+}
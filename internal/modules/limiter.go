@@ -0,0 +1,61 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for limiting the number of
+// network operations done at the same time when fetching modules.
+
+package modules
+
+import (
+	"context"
+)
+
+// NetworkLimiter limits the number of network operations (listing the
+// versions of and fetching modules) that are done at the same time. A
+// single limiter can be shared by multiple resolutions (e.g., by
+// embedders resolving many projects concurrently) to cap the
+// concurrency across all of them.
+//
+// A nil *NetworkLimiter doesn't limit anything.
+type NetworkLimiter struct {
+	sem chan struct{}
+}
+
+// NewNetworkLimiter returns a [NetworkLimiter] that allows at most n
+// network operations at the same time. If n is less than one, nil is
+// returned, which doesn't limit anything.
+func NewNetworkLimiter(n int) *NetworkLimiter {
+	if n < 1 {
+		return nil
+	}
+	return &NetworkLimiter{sem: make(chan struct{}, n)}
+}
+
+// acquire blocks until a network operation is allowed to start or the
+// provided context is canceled. The returned function must be called
+// once the operation has finished.
+func (l *NetworkLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		return func() { <-l.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
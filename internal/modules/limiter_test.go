@@ -0,0 +1,84 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaredallard/vcs/git"
+	"github.com/jaredallard/vcs/resolver"
+	"gotest.tools/v3/assert"
+)
+
+func TestNetworkLimiterLimitsConcurrentFetches(t *testing.T) {
+	const limit = 2
+
+	var inflight, maxInflight, fetches atomic.Int32
+	origGitClone := gitClone
+	t.Cleanup(func() { gitClone = origGitClone })
+	gitClone = func(_ context.Context, _, url string, _ ...*git.CloneOptions) (string, error) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			cur := maxInflight.Load()
+			if n <= cur || maxInflight.CompareAndSwap(cur, n) {
+				break
+			}
+		}
+		fetches.Add(1)
+		time.Sleep(10 * time.Millisecond)
+
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte("name: "+strings.TrimPrefix(url, "https://")+"\n"), 0o644)
+		return dir, err
+	}
+
+	limiter := NewNetworkLimiter(limit)
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = New(context.Background(), "", NewModuleOpts{
+				ImportPath:     fmt.Sprintf("example.com/org/module-%d", i),
+				Version:        &resolver.Version{Tag: "v1.0.0"},
+				NetworkLimiter: limiter,
+			})
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, fetches.Load(), int32(len(errs)))
+	assert.Assert(t, maxInflight.Load() <= limit, "%d fetches ran at the same time", maxInflight.Load())
+}
+
+func TestNetworkLimiterHonorsContext(t *testing.T) {
+	limiter := NewNetworkLimiter(1)
+	release, err := limiter.acquire(context.Background())
+	assert.NilError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = limiter.acquire(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNilNetworkLimiterDoesNotLimit(t *testing.T) {
+	var limiter *NetworkLimiter
+	assert.Assert(t, NewNetworkLimiter(0) == nil)
+	for range 5 {
+		_, err := limiter.acquire(context.Background())
+		assert.NilError(t, err)
+	}
+}
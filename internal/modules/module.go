@@ -20,6 +20,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -37,6 +40,14 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// gitClone clones a git repository, see [git.Clone]. This is a variable
+// so that tests can replace it.
+var gitClone = git.Clone
+
+// envVarNameRegexp matches valid environment variable names, see
+// [configuration.TemplateRepositoryManifest.AllowedEnv].
+var envVarNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // Module is a stencil module that contains template files.
 type Module struct {
 	// t is a shared go-template that is used for this module. This is
@@ -60,9 +71,25 @@ type Module struct {
 	// Version is the version of the module to use.
 	Version *resolver.Version
 
+	// Optional denotes that this module was only ever depended on as an
+	// optional dependency. See [configuration.TemplateRepository.Optional].
+	Optional bool
+
 	// fs is underlying filesystem for this module
 	fs billy.Filesystem
 
+	// digest is the digest of the contents of this module, computed
+	// on first use. See [Module.Digest].
+	digest string
+
+	// credentials provides the credentials used to fetch this module,
+	// see [NewModuleOpts.Credentials].
+	credentials CredentialProvider
+
+	// limiter limits the number of modules fetched at the same time,
+	// see [NewModuleOpts.NetworkLimiter].
+	limiter *NetworkLimiter
+
 	// dirReplacementsRendered is a rendered list of dirReplacements from the manifest,
 	// ready to be used for immediate replacements.  It's a mapping of relative paths
 	// to just the replacement name for the last path segment.
@@ -84,6 +111,24 @@ func uriForModule(name, replacement string) string {
 	return replacement
 }
 
+// rewriteURI rewrites the provided URI using the first of the provided
+// rules that matches it, see [configuration.Manifest.URIRewrites]. If
+// no rule matches, the URI is returned as-is.
+func rewriteURI(rules []*configuration.URIRewrite, uri string) (string, error) {
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return "", fmt.Errorf("invalid uriRewrites match %q: %w", rule.Match, err)
+		}
+
+		if re.MatchString(uri) {
+			return re.ReplaceAllString(uri, rule.Replace), nil
+		}
+	}
+
+	return uri, nil
+}
+
 type NewModuleOpts struct {
 	// ImportPath is the import path of the module. This should be the
 	// Name field of [configuration.TemplateRepository].
@@ -97,6 +142,14 @@ type NewModuleOpts struct {
 	// FS is an optional filesystem to use for the module. When set, it
 	// will be used instead of fetching the module from the network/disk.
 	FS billy.Filesystem
+
+	// Credentials optionally provides the credentials used to fetch the
+	// module. When not set, git's configuration is used.
+	Credentials CredentialProvider
+
+	// NetworkLimiter optionally limits the number of modules fetched at
+	// the same time, see [ModuleResolveOptions.NetworkLimiter].
+	NetworkLimiter *NetworkLimiter
 }
 
 // New creates a new module from a TemplateRepository. Version must be
@@ -127,10 +180,25 @@ func New(ctx context.Context, uri string, opts NewModuleOpts) (*Module, error) {
 		URI:     uri,
 		Version: opts.Version,
 		fs:      opts.FS,
+
+		credentials: opts.Credentials,
+		limiter:     opts.NetworkLimiter,
+	}
+
+	// Validate local modules up front, since they are almost always
+	// replacements and would otherwise fail later with a confusing error.
+	isLocalReplacement := uriIsLocal(uri) && opts.FS == nil
+	if isLocalReplacement {
+		if err := validateLocalModule(uri); err != nil {
+			return nil, fmt.Errorf("replacement for %q at %q is not a valid module: %w", opts.ImportPath, uri, err)
+		}
 	}
 
 	mani, err := m.getManifest(ctx)
 	if err != nil {
+		if isLocalReplacement {
+			return nil, fmt.Errorf("replacement for %q at %q is not a valid module: %w", opts.ImportPath, uri, err)
+		}
 		return nil, err
 	}
 	m.Manifest = mani
@@ -138,6 +206,27 @@ func New(ctx context.Context, uri string, opts NewModuleOpts) (*Module, error) {
 	return &m, nil
 }
 
+// validateLocalModule ensures that the provided local URI points to a
+// directory containing a manifest.yaml.
+func validateLocalModule(uri string) error {
+	dir := strings.TrimPrefix(uri, "file://")
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "manifest.yaml")); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("manifest.yaml not found in %q", dir)
+		}
+		return err
+	}
+	return nil
+}
+
 // GetTemplate returns the go template for this module
 func (m *Module) GetTemplate() *template.Template {
 	return m.t
@@ -181,6 +270,24 @@ func (m *Module) getManifest(ctx context.Context) (*configuration.TemplateReposi
 		)
 	}
 
+	if manifest.OutputPrefix != "" && !filepath.IsLocal(manifest.OutputPrefix) {
+		return nil, fmt.Errorf(
+			"module %q outputPrefix %q must be a relative path inside of the project",
+			m.Name, manifest.OutputPrefix,
+		)
+	}
+
+	if slices.Contains(manifest.BlockCommentPrefixes, "") {
+		return nil, fmt.Errorf("module %q blockCommentPrefixes must not contain an empty prefix", m.Name)
+	}
+
+	for _, name := range manifest.AllowedEnv {
+		if !envVarNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("module %q allowedEnv contains %q, which is not a valid environment variable name",
+				m.Name, name)
+		}
+	}
+
 	return &manifest, nil
 }
 
@@ -203,8 +310,23 @@ func (m *Module) GetFS(ctx context.Context) (billy.Filesystem, error) {
 		// File URLs are already on disk, so just use that path.
 		storageDir = strings.TrimPrefix(m.URI, "file://")
 	} else {
-		var err error
-		storageDir, err = git.Clone(ctx, m.Version.GitRef(), m.URI, &git.CloneOptions{UseArchive: true})
+		key, header, err := authHeader(ctx, m.credentials, m.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		release, err := m.limiter.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = withGitConfig(key, header, func() error {
+			var err error
+			// Archives are fetched with the ambient credentials, so only use
+			// them when no credentials were provided.
+			storageDir, err = gitClone(ctx, m.Version.GitRef(), m.URI, &git.CloneOptions{UseArchive: header == ""})
+			return err
+		})
+		release()
 		if err != nil {
 			return nil, fmt.Errorf("failed to clone module: %w", err)
 		}
@@ -246,3 +368,19 @@ func (m *Module) ApplyDirReplacements(path string) string {
 	}
 	return strings.Join(pp, string(os.PathSeparator))
 }
+
+// ApplyOutputPrefix prepends the module's outputPrefix, if set, to the
+// provided path. When an outputPrefix is set, absolute paths are
+// treated as relative to the root of the project and are not prefixed.
+// Otherwise, the path is returned as-is. See
+// [configuration.TemplateRepositoryManifest.OutputPrefix].
+func (m *Module) ApplyOutputPrefix(path string) string {
+	if m.Manifest == nil || m.Manifest.OutputPrefix == "" {
+		return path
+	}
+
+	if filepath.IsAbs(path) {
+		return strings.TrimPrefix(filepath.Clean(path), string(os.PathSeparator))
+	}
+	return filepath.Join(m.Manifest.OutputPrefix, path)
+}
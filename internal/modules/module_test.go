@@ -3,9 +3,17 @@
 package modules_test
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/jaredallard/vcs/resolver"
 	"go.rgst.io/stencil/v2/internal/modules"
 	"go.rgst.io/stencil/v2/internal/modules/modulestest"
@@ -55,6 +63,44 @@ func TestReplacementLocalModule(t *testing.T) {
 		"expected module to use replacement URI")
 }
 
+func TestURIRewrites(t *testing.T) {
+	sm := &configuration.Manifest{
+		Name: "testing-project",
+		Modules: []*configuration.TemplateRepository{
+			{Name: "github.com/upstream/a"},
+		},
+		URIRewrites: []*configuration.URIRewrite{
+			{Match: `^https://github\.com/upstream/(.+)$`, Replace: "file://testdata/uri_rewrites/$1"},
+		},
+	}
+
+	mods, err := modules.FetchModules(context.Background(), &modules.ModuleResolveOptions{Manifest: sm, Log: newLogger(t)})
+	assert.NilError(t, err, "expected FetchModules() to not error")
+
+	uris := make(map[string]string)
+	for _, m := range mods {
+		uris[m.Name] = m.URI
+	}
+	assert.DeepEqual(t, uris, map[string]string{
+		"github.com/upstream/a": "file://testdata/uri_rewrites/a",
+		"github.com/upstream/b": "file://testdata/uri_rewrites/b",
+	})
+
+	// Replacements take precedence over rewrites.
+	sm.Replacements = map[string]string{"github.com/upstream/b": "file://testdata/uri_rewrites/b-replacement"}
+	mods, err = modules.FetchModules(context.Background(), &modules.ModuleResolveOptions{Manifest: sm, Log: newLogger(t)})
+	assert.NilError(t, err, "expected FetchModules() to not error")
+	for _, m := range mods {
+		if m.Name == "github.com/upstream/b" {
+			assert.Equal(t, m.URI, "file://testdata/uri_rewrites/b-replacement")
+		}
+	}
+
+	sm.URIRewrites = []*configuration.URIRewrite{{Match: "("}}
+	_, err = modules.FetchModules(context.Background(), &modules.ModuleResolveOptions{Manifest: sm, Log: newLogger(t)})
+	assert.ErrorContains(t, err, `invalid uriRewrites match "("`)
+}
+
 func TestCanGetLatestVersion(t *testing.T) {
 	ctx := context.Background()
 	mods, err := modules.FetchModules(ctx, &modules.ModuleResolveOptions{
@@ -173,6 +219,115 @@ func TestFailOnIncompatibleConstraints(t *testing.T) {
 		"expected GetModulesForProject() to error")
 }
 
+// newTaggedRepository creates a git repository containing a module
+// with the provided name, with a commit for every provided tag, and
+// returns its path.
+func newTaggedRepository(t *testing.T, name string, tags ...string) string {
+	dir := t.TempDir()
+	r, err := gogit.PlainInit(dir, false)
+	assert.NilError(t, err)
+	wrk, err := r.Worktree()
+	assert.NilError(t, err)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte("name: "+name+"\n"), 0o644))
+	for _, tag := range tags {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, "VERSION"), []byte(tag+"\n"), 0o644))
+		_, err = wrk.Add(".")
+		assert.NilError(t, err)
+		hash, err := wrk.Commit(tag, &gogit.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		assert.NilError(t, err)
+		_, err = r.CreateTag(tag, hash, nil)
+		assert.NilError(t, err)
+	}
+	return dir
+}
+
+func TestModuleOverridesTransitiveModule(t *testing.T) {
+	ctx := context.Background()
+
+	// Serve example.com/b from a local repository.
+	dir := newTaggedRepository(t, "example.com/b", "v0.3.0", "v0.3.1", "v0.5.0")
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "url.file://"+filepath.ToSlash(dir)+".insteadOf")
+	t.Setenv("GIT_CONFIG_VALUE_0", "https://example.com/b")
+
+	// a depends on b, which is only depended on transitively.
+	a, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name:    "a",
+		Modules: []*configuration.TemplateRepository{{Name: "example.com/b", Version: "~0.3.0"}},
+	})
+	assert.NilError(t, err, "failed to create module")
+
+	g, err := modules.FetchModuleGraph(ctx, &modules.ModuleResolveOptions{
+		Manifest: &configuration.Manifest{
+			Name:            "testing-project",
+			Modules:         []*configuration.TemplateRepository{{Name: "a"}},
+			ModuleOverrides: map[string]string{"example.com/b": ">=0.5.0"},
+		},
+		Replacements: map[string]*modules.Module{"a": a},
+		Log:          newLogger(t),
+	})
+	assert.NilError(t, err, "failed to call FetchModuleGraph()")
+
+	var edge *modules.GraphEdge
+	for i := range g.Edges {
+		if g.Edges[i].To == "example.com/b" {
+			edge = &g.Edges[i]
+			break
+		}
+	}
+	if edge == nil {
+		t.Fatal("failed to find dependency on b")
+	}
+	assert.Equal(t, edge.Version, "~0.3.0", "expected requested version to be recorded")
+	assert.Equal(t, edge.Override, ">=0.5.0", "expected requested version to be overridden")
+
+	// b must have been resolved using the override, not what a requested.
+	i := slices.IndexFunc(g.Modules, func(m *modules.Module) bool { return m.Name == "example.com/b" })
+	assert.Assert(t, i != -1, "failed to find module b")
+	assert.Equal(t, g.Modules[i].Version.Tag, "v0.5.0")
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	v := &resolver.Version{Commit: "abc", Tag: "v0.5.1"}
+	assert.Assert(t, modules.VersionSatisfies(v, "v0.5.1"))
+	assert.Assert(t, modules.VersionSatisfies(v, "abc"))
+	assert.Assert(t, modules.VersionSatisfies(v, "0.5.1"))
+	assert.Assert(t, modules.VersionSatisfies(v, ">=0.5.0"))
+	assert.Assert(t, !modules.VersionSatisfies(v, "~0.4.0"))
+	assert.Assert(t, !modules.VersionSatisfies(v, "main"))
+	assert.Assert(t, modules.VersionSatisfies(&resolver.Version{Branch: "main"}, "main"))
+}
+
+func TestWarnsOnUnusedModuleOverride(t *testing.T) {
+	ctx := context.Background()
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{Name: "test"})
+	assert.NilError(t, err, "failed to create module")
+
+	var buf bytes.Buffer
+	_, err = modules.FetchModules(ctx, &modules.ModuleResolveOptions{
+		Manifest: &configuration.Manifest{
+			Name:    "testing-project",
+			Modules: []*configuration.TemplateRepository{{Name: "test"}},
+			ModuleOverrides: map[string]string{
+				"test":      "v1.0.0",
+				"not-a-dep": "v1.0.0",
+			},
+		},
+		Replacements: map[string]*modules.Module{"test": m},
+		Log:          slogext.NewWithWriter(&buf),
+	})
+	assert.NilError(t, err, "failed to call FetchModules()")
+
+	out := buf.String()
+	assert.Assert(t, strings.Contains(out, "moduleOverrides contains a module that is not used by this project"), out)
+	assert.Assert(t, strings.Contains(out, "not-a-dep"), out)
+	assert.Assert(t, !strings.Contains(out, "module=test "), out)
+}
+
 func TestCanUseBranch(t *testing.T) {
 	ctx := context.Background()
 	mods, err := modules.FetchModules(ctx, &modules.ModuleResolveOptions{
@@ -359,3 +514,85 @@ func TestShouldErrorOnNonExistentRepo(t *testing.T) {
 	//nolint:lll // Why: Error message is long.
 	assert.Error(t, err, "failed to resolve module 'github.com/rgst-io/i-am-not-a-real-repo': failed to get remote branches: exec failed (exit status 128): remote: Repository not found.\nfatal: repository 'https://github.com/rgst-io/i-am-not-a-real-repo/' not found\n\n\nThis error could be due to invalid credentials. Ensure your git configuration is correct.", "expected GetModulesForProject() to error")
 }
+
+func TestOptionalModuleOnlyWhenAllDependentsAreOptional(t *testing.T) {
+	ctx := context.Background()
+
+	m, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{
+		Name: "test",
+		Modules: []*configuration.TemplateRepository{
+			{Name: "test-optional-ext", Optional: true},
+			{Name: "test-ext", Optional: true},
+		},
+	})
+	assert.NilError(t, err, "failed to create module")
+
+	optionalExt, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{Name: "test-optional-ext"})
+	assert.NilError(t, err, "failed to create optional extension module")
+	ext, err := modulestest.NewModuleFromTemplates(&configuration.TemplateRepositoryManifest{Name: "test-ext"})
+	assert.NilError(t, err, "failed to create extension module")
+
+	mods, err := modules.FetchModules(ctx, &modules.ModuleResolveOptions{
+		Manifest: &configuration.Manifest{
+			Name: "testing-project",
+			Modules: []*configuration.TemplateRepository{
+				{Name: "test"},
+				// test-ext is also required by the project, so it should
+				// not be optional.
+				{Name: "test-ext"},
+			},
+		},
+		Replacements: map[string]*modules.Module{
+			"test":              m,
+			"test-optional-ext": optionalExt,
+			"test-ext":          ext,
+		},
+		Log: newLogger(t),
+	})
+	assert.NilError(t, err, "failed to call FetchModules()")
+
+	optional := make(map[string]bool)
+	for _, m := range mods {
+		optional[m.Name] = m.Optional
+	}
+	assert.DeepEqual(t, optional, map[string]bool{
+		"test":              false,
+		"test-optional-ext": true,
+		"test-ext":          false,
+	})
+}
+
+func TestReplacementMissingManifestErrors(t *testing.T) {
+	sm := &configuration.Manifest{
+		Name: "testing-project",
+		Modules: []*configuration.TemplateRepository{
+			{
+				Name: "github.com/rgst-io/stencil-module",
+			},
+		},
+		Replacements: map[string]string{
+			"github.com/rgst-io/stencil-module": "file://" + t.TempDir(),
+		},
+	}
+
+	_, err := modules.FetchModules(context.Background(), &modules.ModuleResolveOptions{Manifest: sm, Log: newLogger(t)})
+	assert.ErrorContains(t, err, `replacement for "github.com/rgst-io/stencil-module" at`)
+	assert.ErrorContains(t, err, "is not a valid module: manifest.yaml not found")
+}
+
+func TestReplacementNameMismatchErrors(t *testing.T) {
+	sm := &configuration.Manifest{
+		Name: "testing-project",
+		Modules: []*configuration.TemplateRepository{
+			{
+				Name: "github.com/rgst-io/not-stencil-module",
+			},
+		},
+		Replacements: map[string]string{
+			"github.com/rgst-io/not-stencil-module": "file://testdata",
+		},
+	}
+
+	_, err := modules.FetchModules(context.Background(), &modules.ModuleResolveOptions{Manifest: sm, Log: newLogger(t)})
+	assert.ErrorContains(t, err, "is not a valid module: module declares its import path as")
+}
@@ -25,6 +25,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
@@ -45,6 +47,10 @@ type resolvedModule struct {
 
 	// version is the version that was resolved for this module
 	version *resolver.Version
+
+	// required denotes that at least one module depends on this module
+	// without marking it as optional.
+	required bool
 }
 
 type history struct {
@@ -65,6 +71,10 @@ type resolveModule struct {
 
 	// parent is the name of the module that imported this module
 	parent string
+
+	// parentImportPath is the import path of the module that imported
+	// this module, or empty if it was imported by the project.
+	parentImportPath string
 }
 
 // ModuleResolveOptions contains options for resolving modules
@@ -80,6 +90,16 @@ type ModuleResolveOptions struct {
 	// where a specific version of a module should be used (e.g.,
 	// lockfile).
 	Replacements map[string]*Module
+
+	// Credentials optionally provides the credentials used to list the
+	// versions of and fetch modules. When not set, git's configuration
+	// is used.
+	Credentials CredentialProvider
+
+	// NetworkLimiter optionally limits the number of network operations
+	// (listing the versions of and fetching modules) that are done at the
+	// same time. See [NewNetworkLimiter].
+	NetworkLimiter *NetworkLimiter
 }
 
 // criteriaForVersionString returns a resolver.Criteria for a given
@@ -114,6 +134,31 @@ func criteriaForVersionString(version string) *resolver.Criteria {
 	}
 }
 
+// VersionSatisfies returns true if the provided resolved version
+// satisfies the provided version string, which supports the same
+// formats as [configuration.TemplateRepository.Version] (a version,
+// constraint, branch or commit).
+func VersionSatisfies(v *resolver.Version, version string) bool {
+	if slices.Contains([]string{v.Commit, v.Tag, v.Branch}, version) {
+		return true
+	}
+
+	criteria := criteriaForVersionString(version)
+	if criteria.Branch != "" || v.Tag == "" {
+		return false
+	}
+
+	c, err := semver.NewConstraint(criteria.Constraint)
+	if err != nil {
+		return false
+	}
+	sv, err := semver.NewVersion(v.Tag)
+	if err != nil {
+		return false
+	}
+	return c.Check(sv)
+}
+
 // resolutionError returns an error for a failed module resolution
 // with a given import path and history of constraints that were used
 // to resolve the module.
@@ -169,13 +214,31 @@ func resolutionError(err error, importPath string, history []history) error {
 // FetchModules fetches modules for a given Manifest. See
 // [ModuleResolveOptions] for more information on the various options
 // that this function supports.
+func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, error) {
+	modules, _, err := fetchModules(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the resolved modules to a list of modules
+	modulesList := make([]*Module, 0, len(modules))
+	for _, m := range modules {
+		modulesList = append(modulesList, m.Module)
+	}
+	return modulesList, nil
+}
+
+// fetchModules resolves and fetches the modules for a given Manifest,
+// returning them keyed by import path along with every dependency edge
+// that was encountered while resolving them.
 //
 //nolint:funlen // Why(jaredallard): Refactoring later.
-func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, error) {
+func fetchModules(ctx context.Context, opts *ModuleResolveOptions) (map[string]*resolvedModule, []GraphEdge, error) {
 	// Used to track which modules to resolve and which one's have been
 	// resolved, for returning later.
 	resolveList := make([]resolveModule, 0)
 	modules := make(map[string]*resolvedModule)
+	edges := make([]GraphEdge, 0)
 
 	// Create a new resolver
 	r := resolver.NewResolver()
@@ -193,8 +256,27 @@ func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, e
 	for len(resolveList) > 0 {
 		mod := resolveList[0]
 		importPath := mod.conf.Name
-		wantedVerCriteria := criteriaForVersionString(mod.conf.Version)
+		wantedVer := mod.conf.Version
+		edge := GraphEdge{From: mod.parentImportPath, To: importPath, Version: mod.conf.Version}
+		if v, ok := opts.Manifest.ModuleOverrides[importPath]; ok {
+			opts.Log.With("module", importPath).With("version", v).With("requested", mod.conf.Version).
+				Debug("Using version from moduleOverrides")
+			wantedVer = v
+			edge.Override = v
+		}
+		wantedVerCriteria := criteriaForVersionString(wantedVer)
+		edges = append(edges, edge)
 		uri := uriForModule(importPath, opts.Manifest.Replacements[importPath])
+		if _, ok := opts.Manifest.Replacements[importPath]; !ok {
+			rewritten, err := rewriteURI(opts.Manifest.URIRewrites, uri)
+			if err != nil {
+				return nil, nil, err
+			}
+			if rewritten != uri {
+				opts.Log.With("module", importPath).With("uri", rewritten).Debug("Rewrote module URI")
+				uri = rewritten
+			}
+		}
 
 		opts.Log.With("module", importPath).With("criteria", wantedVerCriteria).Debug("Resolving module")
 
@@ -207,6 +289,9 @@ func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, e
 				history: []history{},
 			}
 		}
+		if !mod.conf.Optional {
+			modules[importPath].required = true
+		}
 
 		// Check if we've already attempted to resolve this module with this
 		// criteria before. If we have, then we can skip resolving it again.
@@ -255,10 +340,23 @@ func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, e
 				criteria = append(criteria, h.criteria)
 			}
 
-			var err error
-			version, err = r.Resolve(ctx, uri, criteria...)
+			key, header, err := authHeader(ctx, opts.Credentials, uri)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			release, err := opts.NetworkLimiter.acquire(ctx)
 			if err != nil {
-				return nil, resolutionError(err, importPath, modules[importPath].history)
+				return nil, nil, err
+			}
+			err = withGitConfig(key, header, func() error {
+				var err error
+				version, err = r.Resolve(ctx, uri, criteria...)
+				return err
+			})
+			release()
+			if err != nil {
+				return nil, nil, resolutionError(err, importPath, modules[importPath].history)
 			}
 
 			// Track that we got this version for this module
@@ -278,11 +376,13 @@ func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, e
 		} else {
 			var err error
 			m, err = New(ctx, uri, NewModuleOpts{
-				ImportPath: importPath,
-				Version:    version,
+				ImportPath:     importPath,
+				Version:        version,
+				Credentials:    opts.Credentials,
+				NetworkLimiter: opts.NetworkLimiter,
 			})
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			opts.Log.With("module", importPath).With("version", version).Debug("Created module")
@@ -292,8 +392,9 @@ func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, e
 		for _, mfm := range m.Manifest.Modules {
 			opts.Log.With("module", importPath).With("dependency", mfm.Name).Debug("Adding dependency")
 			resolveList = append(resolveList, resolveModule{
-				conf:   mfm,
-				parent: importPath + "@" + version.String(),
+				conf:             mfm,
+				parent:           importPath + "@" + version.String(),
+				parentImportPath: importPath,
 			})
 		}
 
@@ -305,10 +406,15 @@ func FetchModules(ctx context.Context, opts *ModuleResolveOptions) ([]*Module, e
 		resolveList = resolveList[1:]
 	}
 
-	// Convert the resolved modules to a list of modules
-	modulesList := make([]*Module, 0, len(modules))
+	for _, importPath := range slices.Sorted(maps.Keys(opts.Manifest.ModuleOverrides)) {
+		if _, ok := modules[importPath]; !ok {
+			opts.Log.With("module", importPath).
+				Warn("moduleOverrides contains a module that is not used by this project")
+		}
+	}
+
 	for _, m := range modules {
-		modulesList = append(modulesList, m.Module)
+		m.Module.Optional = !m.required
 	}
-	return modulesList, nil
+	return modules, edges, nil
 }
@@ -59,6 +59,13 @@ func addTemplateToFS(fs billy.Filesystem, tpl string) error {
 func NewModuleFromTemplates(manifest *configuration.TemplateRepositoryManifest,
 	templates ...string) (*modules.Module, error) {
 	fs := memfs.New()
+
+	// Always create the templates directory, even if there are no
+	// templates, so that template discovery doesn't fail.
+	if err := fs.MkdirAll("templates", 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create templates directory in memfs")
+	}
+
 	for _, tpl := range templates {
 		if err := addTemplateToFS(fs, tpl); err != nil {
 			return nil, err
@@ -0,0 +1,122 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains logic for finding the release asset
+// of a native extension for the host platform.
+
+package nativeext
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	gogithub "github.com/google/go-github/v68/github"
+	"github.com/jaredallard/vcs"
+	"github.com/jaredallard/vcs/token"
+)
+
+// listReleaseAssets returns the names of the assets of the release
+// with the provided tag in the repository at repoURL. This is a
+// variable so that it can be replaced in tests.
+var listReleaseAssets = listGitHubReleaseAssets
+
+// platformAssetNames returns the globs that match the release asset of
+// the extension with the provided name for the host platform.
+func platformAssetNames(name string) []string {
+	return []string{
+		filepath.Base(name) + "_*_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.*",
+		filepath.Base(name) + "_*_" + runtime.GOOS + "_" + runtime.GOARCH + ".zip",
+	}
+}
+
+// checkPlatformAsset ensures that the release with the provided tag of
+// the extension has an asset for the host platform, returning an error
+// listing the available assets if it does not. If the assets of the
+// release can't be listed (e.g., the extension isn't hosted on GitHub)
+// the check is skipped.
+func (h *Host) checkPlatformAsset(ctx context.Context, source, name, tag string) error {
+	assets, err := listReleaseAssets(ctx, source, tag)
+	if err != nil {
+		h.log.WithError(err).With("repo", source).Debug("Unable to list release assets, skipping platform check")
+		return nil
+	}
+
+	patterns := platformAssetNames(name)
+	if slices.ContainsFunc(assets, func(asset string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			ok, err := filepath.Match(pattern, asset)
+			return err == nil && ok
+		})
+	}) {
+		return nil
+	}
+
+	available := "none"
+	if len(assets) != 0 {
+		available = strings.Join(assets, ", ")
+	}
+	return fmt.Errorf("release %s of extension %q has no asset for %s/%s (expected one matching %s), available assets: %s",
+		tag, name, runtime.GOOS, runtime.GOARCH, strings.Join(patterns, " or "), available)
+}
+
+// listGitHubReleaseAssets lists the assets of a release in a GitHub
+// repository. Other providers are not supported.
+func listGitHubReleaseAssets(ctx context.Context, repoURL, tag string) ([]string, error) {
+	vcsp, err := vcs.ProviderFromURL(repoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if vcsp != vcs.ProviderGithub {
+		return nil, fmt.Errorf("listing release assets is not supported for %s", vcsp)
+	}
+
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// /rgst-io/stencil -> ["rgst-io", "stencil"]
+	spl := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(spl) != 2 {
+		return nil, fmt.Errorf("invalid GitHub URL: %s", repoURL)
+	}
+
+	t, err := token.Fetch(ctx, vcsp, true)
+	if err != nil {
+		return nil, err
+	}
+
+	gh := gogithub.NewClient(nil)
+	if !t.IsUnauthenticated() {
+		gh = gh.WithAuthToken(t.Value)
+	}
+
+	rel, _, err := gh.Repositories.GetReleaseByTag(ctx, spl[0], spl[1], tag)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make([]string, 0, len(rel.Assets))
+	for _, a := range rel.Assets {
+		assets = append(assets, a.GetName())
+	}
+	return assets, nil
+}
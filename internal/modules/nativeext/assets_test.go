@@ -0,0 +1,29 @@
+package nativeext
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/jaredallard/vcs/resolver"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gotest.tools/v3/assert"
+)
+
+func TestDownloadFromRemoteMissingPlatformAsset(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	listReleaseAssets = func(_ context.Context, _, _ string) ([]string, error) {
+		return []string{"plugin_1.0.0_plan9_386.tar.gz", "checksums.txt"}, nil
+	}
+	t.Cleanup(func() { listReleaseAssets = listGitHubReleaseAssets })
+
+	h, err := NewHost(slogext.NewTestLogger(t))
+	assert.NilError(t, err)
+
+	_, err = h.downloadFromRemote(context.Background(), "https://github.com/rgst-io/plugin",
+		"github.com/rgst-io/plugin", &resolver.Version{Tag: "v1.0.0", Commit: "abc"})
+	assert.ErrorContains(t, err,
+		`release v1.0.0 of extension "github.com/rgst-io/plugin" has no asset for `+runtime.GOOS+"/"+runtime.GOARCH)
+	assert.ErrorContains(t, err, "available assets: plugin_1.0.0_plan9_386.tar.gz, checksums.txt")
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains logic for cleaning the native
+// extension cache.
+
+package nativeext
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rogpeppe/go-internal/lockedfile"
+)
+
+// CleanCacheOptions contains options for [CleanCache].
+type CleanCacheOptions struct {
+	// OlderThan, if set, only removes cache entries that were last
+	// modified longer than this duration ago.
+	OlderThan time.Duration
+
+	// DryRun, if set, reports what would be removed without removing
+	// anything.
+	DryRun bool
+}
+
+// CleanedCacheEntry is an entry that was (or, in dry-run mode, would
+// have been) removed from the cache by [CleanCache].
+type CleanedCacheEntry struct {
+	// Path is the absolute path to the entry
+	Path string
+
+	// Size is the size of the entry, in bytes
+	Size int64
+}
+
+// CleanCache removes entries from the stencil cache directory. Native
+// extensions are removed per version (e.g.,
+// nativeexts/github.com--rgst-io--plugin/v1.3.0), everything else is
+// removed per top-level entry. The cache lock is held while cleaning
+// to avoid removing extensions that are being downloaded by another
+// stencil process.
+func CleanCache(opts *CleanCacheOptions) ([]CleanedCacheEntry, error) {
+	cacheDir, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(cacheDir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	unlock, err := lockedfile.MutexAt(filepath.Join(cacheDir, "cache.lock")).Lock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cache: %w", err)
+	}
+	defer unlock()
+
+	entries, err := cacheEntries(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-opts.OlderThan)
+	cleaned := make([]CleanedCacheEntry, 0)
+	for _, entry := range entries {
+		size, modTime, err := diskUsage(entry)
+		if err != nil {
+			return cleaned, fmt.Errorf("failed to inspect %s: %w", entry, err)
+		}
+		if opts.OlderThan > 0 && modTime.After(cutoff) {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := os.RemoveAll(entry); err != nil {
+				return cleaned, fmt.Errorf("failed to remove %s: %w", entry, err)
+			}
+		}
+		cleaned = append(cleaned, CleanedCacheEntry{Path: entry, Size: size})
+	}
+
+	if !opts.DryRun {
+		// Remove extension directories that no longer have any versions.
+		extDirs, _ := filepath.Glob(filepath.Join(cacheDir, "nativeexts", "*"))
+		for _, dir := range extDirs {
+			os.Remove(dir) // only succeeds if empty
+		}
+	}
+
+	return cleaned, nil
+}
+
+// cacheEntries returns the paths that can be individually removed from
+// the cache.
+func cacheEntries(cacheDir string) ([]string, error) {
+	topLevel, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	entries := make([]string, 0)
+	for _, e := range topLevel {
+		switch e.Name() {
+		case "cache.lock":
+			continue
+		case "nativeexts":
+			versions, err := filepath.Glob(filepath.Join(cacheDir, e.Name(), "*", "*"))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, versions...)
+		default:
+			entries = append(entries, filepath.Join(cacheDir, e.Name()))
+		}
+	}
+
+	return entries, nil
+}
+
+// diskUsage returns the total size of all files under path and the most
+// recent modification time of any of them.
+func diskUsage(path string) (int64, time.Time, error) {
+	var size int64
+	var modTime time.Time
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return size, modTime, err
+}
@@ -26,7 +26,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"syscall"
 
@@ -62,8 +61,9 @@ type extension struct {
 	closer func() error
 }
 
-// getCacheDir returns the directory where extensions are cached in
-func getCacheDir() (string, error) {
+// CacheDir returns the directory where stencil caches data, e.g.,
+// native extensions and the responses of stencil.FetchURL.
+func CacheDir() (string, error) {
 	cacheDir := os.Getenv("XDG_CACHE_HOME")
 	if cacheDir == "" { // default to $HOME/.cache as per XDG spec
 		homeDir, err := os.UserHomeDir()
@@ -77,7 +77,7 @@ func getCacheDir() (string, error) {
 
 // NewHost creates a new extension host
 func NewHost(log slogext.Logger) (*Host, error) {
-	cacheDir, err := getCacheDir()
+	cacheDir, err := CacheDir()
 	if err != nil {
 		return nil, err
 	}
@@ -122,16 +122,15 @@ func (h *Host) createFunctionFromTemplateFunction(extName string, ext apiv1.Impl
 // GetExtensionCaller returns an extension caller that's
 // aware of all extension functions
 func (h *Host) GetExtensionCaller(_ context.Context) (*ExtensionCaller, error) {
+	extFuncs, err := h.TemplateFunctions()
+	if err != nil {
+		return nil, err
+	}
+
 	// funcMap stores the extension functions discovered
 	funcMap := map[string]map[string]generatedTemplateFunc{}
-
-	// Call all extensions to get the template functions provided
-	for extName, ext := range h.extensions {
-		funcs, err := ext.impl.GetTemplateFunctions()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get template functions from plugin %q: %w", extName, err)
-		}
-
+	for extName, funcs := range extFuncs {
+		ext := h.extensions[extName]
 		for _, f := range funcs {
 			h.log.With("extension", extName).With("function", f.Name).Debug("Registering extension function")
 			tfunc := h.createFunctionFromTemplateFunction(extName, ext.impl, f)
@@ -147,6 +146,21 @@ func (h *Host) GetExtensionCaller(_ context.Context) (*ExtensionCaller, error) {
 	return &ExtensionCaller{funcMap}, nil
 }
 
+// TemplateFunctions returns the template functions provided by each
+// registered extension, keyed by the name of the extension.
+func (h *Host) TemplateFunctions() (map[string][]*apiv1.TemplateFunction, error) {
+	extFuncs := make(map[string][]*apiv1.TemplateFunction, len(h.extensions))
+	for extName, ext := range h.extensions {
+		funcs, err := ext.impl.GetTemplateFunctions()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template functions from plugin %q: %w", extName, err)
+		}
+		extFuncs[extName] = funcs
+	}
+
+	return extFuncs, nil
+}
+
 // RegisterExtension registers a ext from a given source
 // and compiles/downloads it. A client is then created
 // that is able to communicate with the ext.
@@ -187,9 +201,16 @@ func (h *Host) RegisterInprocExtension(name string, ext apiv1.Implementation) {
 	h.extensions[name] = extension{ext, func() error { return nil }}
 }
 
+// HasExtension returns true if an extension with the provided name has
+// been registered with the host.
+func (h *Host) HasExtension(name string) bool {
+	_, ok := h.extensions[name]
+	return ok
+}
+
 // getExtensionPath returns the path to an extension binary
 func (h *Host) getExtensionPath(version *resolver.Version, name string) (string, error) {
-	cacheDir, err := getCacheDir()
+	cacheDir, err := CacheDir()
 	if err != nil {
 		return "", err
 	}
@@ -228,14 +249,15 @@ func (h *Host) downloadFromRemote(ctx context.Context, source, name string, vers
 		return dlPath, nil
 	}
 
+	if err := h.checkPlatformAsset(ctx, source, name, version.Tag); err != nil {
+		return "", err
+	}
+
 	h.log.With("version", version).With("repo", source).Debug("Downloading native extension")
 	resp, fi, err := releases.Fetch(ctx, &releases.FetchOptions{
-		AssetNames: []string{
-			filepath.Base(name) + "_*_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.*",
-			filepath.Base(name) + "_*_" + runtime.GOOS + "_" + runtime.GOARCH + ".zip",
-		},
-		RepoURL: source,
-		Tag:     version.Tag,
+		AssetNames: platformAssetNames(name),
+		RepoURL:    source,
+		Tag:        version.Tag,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch release: %w", err)
@@ -21,8 +21,13 @@ package configuration
 
 import (
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -43,8 +48,96 @@ func LoadManifest(path string) (*Manifest, error) {
 	}
 	defer f.Close()
 
+	s, err := ReadManifest(f, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyExtends(s, path, []string{filepath.Clean(path)}); err != nil {
+		return nil, err
+	}
+
+	if err := s.RenderModules(); err != nil {
+		return nil, fmt.Errorf("failed to render modules of %q: %w", path, err)
+	}
+	return s, nil
+}
+
+// applyExtends merges the manifest that the provided manifest, read
+// from path, extends (if any) into it. See [Manifest.Extends]. seen
+// contains the paths of the manifests read so far, used to detect
+// cycles.
+func applyExtends(m *Manifest, path string, seen []string) error {
+	if m.Extends == "" {
+		return nil
+	}
+
+	basePath := m.Extends
+	if !filepath.IsAbs(basePath) {
+		basePath = filepath.Join(filepath.Dir(path), basePath)
+	}
+	if slices.Contains(seen, basePath) {
+		return fmt.Errorf("extends contains a cycle: %s -> %s", strings.Join(seen, " -> "), basePath)
+	}
+
+	f, err := os.Open(basePath)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest %q extended by %q: %w", m.Extends, path, err)
+	}
+	defer f.Close()
+
+	// A base manifest doesn't describe a project, so it isn't required
+	// to have a (valid) name.
+	var base *Manifest
+	if err := yaml.NewDecoder(f).Decode(&base); err != nil {
+		return fmt.Errorf("failed to parse manifest %q extended by %q: %w", m.Extends, path, err)
+	}
+	if base == nil {
+		return nil
+	}
+
+	if err := applyExtends(base, basePath, append(seen, basePath)); err != nil {
+		return err
+	}
+
+	m.Arguments = mergeArguments(base.Arguments, m.Arguments)
+	for _, bm := range base.Modules {
+		if !slices.ContainsFunc(m.Modules, func(tr *TemplateRepository) bool { return tr.Name == bm.Name }) {
+			m.Modules = append(m.Modules, bm)
+		}
+	}
+	return nil
+}
+
+// mergeArguments returns the provided base arguments with the provided
+// arguments merged on top of them. Maps are merged recursively, all
+// other values (including lists) in args replace the value in base.
+func mergeArguments(base, args map[string]any) map[string]any {
+	if base == nil {
+		return args
+	}
+
+	merged := maps.Clone(base)
+	for k, v := range args {
+		baseMap, baseOk := merged[k].(map[string]any)
+		argMap, argOk := v.(map[string]any)
+		if baseOk && argOk {
+			merged[k] = mergeArguments(baseMap, argMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// ReadManifest parses a manifest from the provided reader, e.g., the
+// contents of a manifest from a previous commit. The provided path is
+// only used in error messages. Unlike LoadManifest, the manifest it
+// extends (see [Manifest.Extends]) is not merged into it and its
+// modules are not rendered (see [Manifest.RenderModules]).
+func ReadManifest(r io.Reader, path string) (*Manifest, error) {
 	var s *Manifest
-	if err := yaml.NewDecoder(f).Decode(&s); err != nil {
+	if err := yaml.NewDecoder(r).Decode(&s); err != nil {
 		return nil, err
 	}
 
@@ -58,14 +151,24 @@ func LoadManifest(path string) (*Manifest, error) {
 // LoadDefaultManifest returns a parsed project manifest from a set
 // default path on disk.
 func LoadDefaultManifest() (*Manifest, error) {
+	path, err := DefaultManifestPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadManifest(path)
+}
+
+// DefaultManifestPath returns the path of the project manifest in the
+// current working directory, as loaded by [LoadDefaultManifest].
+func DefaultManifestPath() (string, error) {
 	manifestFiles := []string{"stencil.yaml", "service.yaml"}
 	for _, file := range manifestFiles {
 		if _, err := os.Stat(file); err == nil {
-			return LoadManifest(file)
+			return file, nil
 		}
 	}
 
-	return nil, fmt.Errorf("no manifest found (searched %v)", manifestFiles)
+	return "", fmt.Errorf("no manifest found (searched %v)", manifestFiles)
 }
 
 // Manifest is a manifest used to describe a project and impact
@@ -74,8 +177,19 @@ type Manifest struct {
 	// Name is the name of the project
 	Name string `yaml:"name" jsonschema:"required"`
 
+	// Extends is the path, relative to this manifest, of a base manifest
+	// (e.g., containing an organization's defaults) to inherit the
+	// arguments and modules of. Arguments set in this manifest take
+	// precedence, with maps being merged, and modules listed in this
+	// manifest take precedence over modules with the same name in the
+	// base manifest. A base manifest may extend another manifest.
+	Extends string `yaml:"extends,omitempty"`
+
 	// Modules are the template modules that this project depends
-	// on and utilizes
+	// on and utilizes. The name and version of a module may be
+	// templates using the arguments of the manifest, modules whose name
+	// renders to an empty string are not used. See
+	// [Manifest.RenderModules].
 	Modules []*TemplateRepository `yaml:"modules,omitempty"`
 
 	// Versions is a map of versions of certain tools, this is used by templates
@@ -85,12 +199,63 @@ type Manifest struct {
 	// Arguments is a map of arbitrary arguments to pass to the generator
 	Arguments map[string]any `yaml:"arguments"`
 
+	// Project is a map of project-wide values that are available to the
+	// templates of every module through stencil.Project, without
+	// modules having to declare them as arguments.
+	Project map[string]any `yaml:"project,omitempty"`
+
 	// Replacements is a list of module names to replace their URI.
 	//
 	// Expected format:
 	// - local file: path/to/module
 	// - remote file: https://github.com/rgst-io/stencil-base
 	Replacements map[string]string `yaml:"replacements,omitempty"`
+
+	// ModuleOverrides is a map of module import paths to a version that
+	// is forced for that module anywhere in the dependency graph,
+	// including modules that are only depended on transitively. The
+	// version uses the same format as [TemplateRepository.Version] and
+	// replaces the version requested by every dependent.
+	ModuleOverrides map[string]string `yaml:"moduleOverrides,omitempty"`
+
+	// URIRewrites is a list of rules that rewrite the URI of every
+	// module (e.g., https://github.com/upstream/module) that doesn't
+	// have an entry in Replacements, which is useful when working with
+	// forks. The first rule that matches a URI is used.
+	URIRewrites []*URIRewrite `yaml:"uriRewrites,omitempty"`
+
+	// LockBlocks enables storing the contents of blocks in the lockfile,
+	// keyed by module and block name. When a generated file no longer
+	// exists on disk (e.g., it was renamed outside of stencil) its blocks
+	// are recovered from the lockfile when it is generated again.
+	LockBlocks bool `yaml:"lockBlocks,omitempty"`
+
+	// SkipMiseTrust disables automatically trusting the project's
+	// .mise.toml after rendering, which stencil otherwise does when mise
+	// is installed.
+	SkipMiseTrust bool `yaml:"skipMiseTrust,omitempty"`
+
+	// FetchURLHosts is the list of hosts that templates are allowed to
+	// download files from with stencil.FetchURL (e.g.,
+	// raw.githubusercontent.com). When empty, stencil.FetchURL is
+	// disabled.
+	FetchURLHosts []string `yaml:"fetchURLHosts,omitempty"`
+
+	// MaxRenderPasses is the maximum number of times templates are
+	// rendered for the shared state between them (e.g., globals and
+	// module hooks) to stabilize before stencil gives up. Defaults to 20.
+	MaxRenderPasses int `yaml:"maxRenderPasses,omitempty"`
+
+	// RecordLastRun enables writing a report of the files written by
+	// every run, and the action taken on them, to
+	// .stencil/last-run.json. This allows the changes made by a run to
+	// be reviewed, or compared with the next run, by other tools.
+	RecordLastRun bool `yaml:"recordLastRun,omitempty"`
+
+	// PostRunCommand is a list of commands to be ran after rendering,
+	// once the post-run commands of all modules have been ran, e.g., to
+	// run the project's tests.
+	PostRunCommand []*PostRunCommandSpec `yaml:"postRunCommand,omitempty"`
 }
 
 // TemplateRepository is a repository of template files.
@@ -109,6 +274,70 @@ type TemplateRepository struct {
 	// will change as the module is resolved on subsequent runs.
 	// Eventually, this will be changed to use the lockfile by default.
 	Version string `yaml:"version,omitempty"`
+
+	// Optional denotes that this module is an optional dependency. This
+	// is currently only supported for native extensions: if the
+	// extension fails to load, a warning is logged and rendering
+	// continues without it. Templates can use stencil.HasExtension to
+	// check if the extension was loaded.
+	//
+	// Optional only applies to loading the extension. The module itself
+	// must still be resolvable and fetchable, failing to fetch it is
+	// still an error.
+	//
+	// A module is only treated as optional if every module that depends
+	// on it marks it as optional.
+	Optional bool `yaml:"optional,omitempty"`
+
+	// Alias is a short name for this module that can be used in place of
+	// its name when referencing it with "from", stencil.AddToModuleHook
+	// and module.Call. This is only supported in a project's manifest
+	// and is useful to disambiguate modules from different hosts that
+	// share the same last path element (e.g., github.com/a/base and
+	// gitlab.com/b/base). An alias may only contain letters, numbers,
+	// "-" and "_".
+	Alias string `yaml:"alias,omitempty"`
+}
+
+// URIRewrite is a rule that rewrites the URI of a module, see
+// [Manifest.URIRewrites].
+type URIRewrite struct {
+	// Match is a regular expression that is matched against the URI of
+	// a module, e.g., "^https://github.com/upstream/".
+	Match string `yaml:"match" jsonschema:"required"`
+
+	// Replace is what the matched part of the URI is replaced with.
+	// Capture groups of Match can be referenced with $1, $2, etc.
+	Replace string `yaml:"replace,omitempty"`
+}
+
+// aliasPattern is the pattern that the alias of a module must match,
+// see [TemplateRepository.Alias].
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ModuleAliases returns the aliases of the modules in the manifest
+// (see [TemplateRepository.Alias]) mapped to the name of the module
+// they refer to. An error is returned if an alias is invalid, used by
+// more than one module or is the name of a module.
+func (m *Manifest) ModuleAliases() (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, tr := range m.Modules {
+		if tr.Alias == "" {
+			continue
+		}
+
+		if !aliasPattern.MatchString(tr.Alias) {
+			return nil, fmt.Errorf("alias %q of module %q may only contain letters, numbers, \"-\" and \"_\"", tr.Alias, tr.Name)
+		}
+		if other, ok := aliases[tr.Alias]; ok {
+			return nil, fmt.Errorf("alias %q is used by both module %q and %q", tr.Alias, other, tr.Name)
+		}
+		if slices.ContainsFunc(m.Modules, func(o *TemplateRepository) bool { return o.Name == tr.Alias }) {
+			return nil, fmt.Errorf("alias %q of module %q is the name of another module", tr.Alias, tr.Name)
+		}
+		aliases[tr.Alias] = tr.Name
+	}
+	return aliases, nil
 }
 
 // ValidateName ensures that the name of a project in the manifest
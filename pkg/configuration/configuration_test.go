@@ -2,6 +2,7 @@ package configuration_test
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"go.rgst.io/stencil/v2/pkg/configuration"
@@ -65,3 +66,151 @@ func TestLoadDefaultTemplateRepositoryManifestShouldLoad(t *testing.T) {
 
 	assert.Equal(t, sm.Name, "github.com/rgst-io/test-module")
 }
+
+func TestTemplateExtension(t *testing.T) {
+	var m *configuration.TemplateRepositoryManifest
+	ext, ok := m.TemplateExtension("a.yaml.tpl")
+	assert.Equal(t, ext, ".tpl")
+	assert.Equal(t, ok, true)
+
+	m = &configuration.TemplateRepositoryManifest{TemplateExtensions: []string{".tmpl", ".go.tmpl"}}
+	ext, ok = m.TemplateExtension("main.go.tmpl")
+	assert.Equal(t, ext, ".go.tmpl", "expected the longest matching extension")
+	assert.Equal(t, ok, true)
+
+	_, ok = m.TemplateExtension("a.yaml.tpl")
+	assert.Equal(t, ok, false, "expected .tpl to not be used when extensions are set")
+}
+
+func TestTagsForTemplate(t *testing.T) {
+	var m *configuration.TemplateRepositoryManifest
+	assert.Equal(t, len(m.TagsForTemplate("README.md.tpl")), 0)
+
+	m = &configuration.TemplateRepositoryManifest{TemplateTags: map[string][]string{
+		"docs":                   {"docs"},
+		"docs/api/index.md.tpl":  {"api", "docs"},
+		"ci/workflow.yaml.tpl":   {"ci"},
+		"docs/api/README.md.tpl": {"readme"},
+	}}
+	assert.DeepEqual(t, m.TagsForTemplate("docs/api/index.md.tpl"), []string{"api", "docs"})
+	assert.DeepEqual(t, m.TagsForTemplate("docs/guide.md.tpl"), []string{"docs"})
+	assert.DeepEqual(t, m.TagsForTemplate("ci/workflow.yaml.tpl"), []string{"ci"})
+	assert.Equal(t, len(m.TagsForTemplate("main.go.tpl")), 0)
+}
+
+func TestFileMode(t *testing.T) {
+	m := &configuration.TemplateRepositoryManifest{FileModes: map[string]string{
+		"scripts/*":    "0644",
+		"scripts/*.sh": "0755",
+	}}
+
+	mode, ok, err := m.FileMode("scripts/build.sh")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, mode, os.FileMode(0o755))
+
+	mode, ok, err = m.FileMode("scripts/README.md")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, mode, os.FileMode(0o644))
+
+	_, ok, err = m.FileMode("main.go")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	m.FileModes["main.go"] = "rwx"
+	_, _, err = m.FileMode("main.go")
+	assert.ErrorContains(t, err, `invalid mode "rwx" for "main.go" in fileModes`)
+}
+
+func TestLoadManifestExtends(t *testing.T) {
+	sm, err := configuration.LoadManifest("testdata/extends/stencil.yaml")
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, sm.Arguments, map[string]any{
+		// Set by the project, overriding both base manifests.
+		"license": "MIT",
+		// Maps are merged, the project's values take precedence.
+		"ci": map[string]any{"provider": "github", "lint": true},
+		// Inherited from the base of the base manifest.
+		"org": "rgst-io",
+	})
+	assert.DeepEqual(t, sm.Modules, []*configuration.TemplateRepository{
+		{Name: "github.com/rgst-io/stencil-golang", Version: "v1.0.0"},
+		{Name: "github.com/rgst-io/stencil-base"},
+	})
+}
+
+func TestLoadManifestExtendsCycle(t *testing.T) {
+	_, err := configuration.LoadManifest("testdata/extends-cycle/stencil.yaml")
+	assert.ErrorContains(t, err, "extends contains a cycle: testdata/extends-cycle/stencil.yaml -> "+
+		"testdata/extends-cycle/base.yaml -> testdata/extends-cycle/stencil.yaml")
+}
+
+func TestLoadManifestRendersModules(t *testing.T) {
+	sm, err := configuration.LoadManifest("testdata/modules-template/stencil.yaml")
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, sm.Modules, []*configuration.TemplateRepository{
+		{Name: "github.com/rgst-io/stencil-golang", Version: "v1.2.3"},
+		// Included because grpc is true, stencil-kafka is left out
+		// because kafka is false.
+		{Name: "github.com/rgst-io/stencil-grpc"},
+	})
+}
+
+func TestRenderModulesInvalidTemplate(t *testing.T) {
+	m := &configuration.Manifest{
+		Name:    "testing",
+		Modules: []*configuration.TemplateRepository{{Name: "{{ if .Arguments.grpc }}"}},
+	}
+	err := m.RenderModules()
+	assert.ErrorContains(t, err, "failed to parse modules[0].name as a template")
+}
+
+func TestModuleAliases(t *testing.T) {
+	m := &configuration.Manifest{
+		Name: "testing",
+		Modules: []*configuration.TemplateRepository{
+			{Name: "github.com/a/base", Alias: "a-base"},
+			{Name: "gitlab.com/b/base"},
+		},
+	}
+	aliases, err := m.ModuleAliases()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, aliases, map[string]string{"a-base": "github.com/a/base"})
+
+	m.Modules[1].Alias = "b.base"
+	_, err = m.ModuleAliases()
+	assert.ErrorContains(t, err, `alias "b.base" of module "gitlab.com/b/base" may only contain letters`)
+}
+
+func TestMigrateArguments(t *testing.T) {
+	path := t.TempDir() + "/stencil.yaml"
+	assert.NilError(t, os.WriteFile(path, []byte(`name: test
+arguments:
+  old:
+    port: 8080
+  other: true
+`), 0o644))
+
+	applied, err := configuration.MigrateArguments(path, []configuration.ArgumentMove{
+		{From: "old.port", To: "server.port"},
+		{From: "missing", To: "unused"},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, applied, []configuration.ArgumentMove{{From: "old.port", To: "server.port"}})
+
+	b, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `name: test
+arguments:
+  other: true
+  server:
+    port: 8080
+`)
+
+	// Values are never overwritten.
+	_, err = configuration.MigrateArguments(path, []configuration.ArgumentMove{{From: "other", To: "server"}})
+	assert.ErrorContains(t, err, `can't move argument "other" to "server"`)
+}
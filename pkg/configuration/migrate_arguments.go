@@ -0,0 +1,171 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for rewriting a manifest on disk
+// to move the values of renamed arguments.
+
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ArgumentMove is the move of the value of an argument in a manifest
+// from one path to another, e.g., when an argument was renamed.
+type ArgumentMove struct {
+	// From is the dot-separated path of the argument to move.
+	From string
+
+	// To is the dot-separated path to move the argument to.
+	To string
+}
+
+// MigrateArguments rewrites the manifest at the provided path, moving
+// the values of the arguments set at the From path of the provided
+// moves to their To path. Moves whose From path isn't set in the
+// manifest (e.g., because it's set by a manifest it extends) are
+// ignored. The moves that were applied are returned, the file is only
+// written if there are any. An error is returned if the To path of a
+// move is already set.
+func MigrateArguments(path string, moves []ArgumentMove) ([]ArgumentMove, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %q: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	args := mappingValue(doc.Content[0], "arguments")
+	if args == nil {
+		return nil, nil
+	}
+
+	applied := make([]ArgumentMove, 0, len(moves))
+	for _, mv := range moves {
+		key, val := removeMappingPath(args, strings.Split(mv.From, "."))
+		if val == nil {
+			continue
+		}
+
+		if !setMappingPath(args, strings.Split(mv.To, "."), key, val) {
+			return nil, fmt.Errorf("can't move argument %q to %q in %q, %q is already set", mv.From, mv.To, path, mv.To)
+		}
+		applied = append(applied, mv)
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest %q: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	inf, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), inf.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write manifest %q: %w", path, err)
+	}
+	return applied, nil
+}
+
+// mappingValue returns the value of the provided key in the provided
+// mapping node, or nil if it's not a mapping or the key isn't set.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingPath removes the value at the provided path from the
+// provided mapping node, returning its key and value nodes. Mappings
+// left empty by the removal are removed as well. nil is returned if
+// the path isn't set.
+func removeMappingPath(n *yaml.Node, path []string) (key, val *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			key, val = n.Content[i], n.Content[i+1]
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return key, val
+		}
+
+		child := n.Content[i+1]
+		key, val = removeMappingPath(child, path[1:])
+		if val != nil && child.Kind == yaml.MappingNode && len(child.Content) == 0 {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+		}
+		return key, val
+	}
+	return nil, nil
+}
+
+// setMappingPath sets the value at the provided path in the provided
+// mapping node, creating mappings as needed. The provided key node is
+// reused for the last element of the path to keep its comments. false
+// is returned if the path is already set, or a parent isn't a mapping.
+func setMappingPath(n *yaml.Node, path []string, key, val *yaml.Node) bool {
+	if n.Kind != yaml.MappingNode {
+		return false
+	}
+
+	child := mappingValue(n, path[0])
+	if len(path) == 1 {
+		if child != nil {
+			return false
+		}
+		key.Value = path[0]
+		n.Content = append(n.Content, key, val)
+		return true
+	}
+
+	if child == nil {
+		child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}, child)
+	}
+	return setMappingPath(child, path[1:], key, val)
+}
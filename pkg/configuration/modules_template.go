@@ -0,0 +1,93 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file contains code for rendering the module entries
+// of a manifest as templates.
+
+package configuration
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// modulesTemplateData is the data that module entries of a manifest
+// are rendered with, see [Manifest.RenderModules].
+type modulesTemplateData struct {
+	// Arguments are the arguments of the manifest.
+	Arguments map[string]any
+
+	// Project are the project-wide values of the manifest.
+	Project map[string]any
+}
+
+// RenderModules renders the name and version of every module in the
+// manifest as a template, with the arguments and project values of the
+// manifest available as .Arguments and .Project respectively. Modules
+// whose name renders to an empty string are removed, which allows a
+// module to be used based on an argument, e.g.:
+//
+//	modules:
+//	  - name: '{{ if .Arguments.grpc }}github.com/rgst-io/stencil-grpc{{ end }}'
+//
+// Entries without template actions are left untouched.
+func (m *Manifest) RenderModules() error {
+	data := &modulesTemplateData{Arguments: m.Arguments, Project: m.Project}
+
+	modules := make([]*TemplateRepository, 0, len(m.Modules))
+	for i, tr := range m.Modules {
+		name, err := renderModuleField(fmt.Sprintf("modules[%d].name", i), tr.Name, data)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		version, err := renderModuleField(fmt.Sprintf("modules[%d].version", i), tr.Version, data)
+		if err != nil {
+			return err
+		}
+
+		tr.Name = name
+		tr.Version = version
+		modules = append(modules, tr)
+	}
+	m.Modules = modules
+	return nil
+}
+
+// renderModuleField renders the provided value of a field of a module
+// entry as a template. name is used to identify the field in errors.
+func renderModuleField(name, value string, data *modulesTemplateData) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	t, err := template.New(name).Funcs(sprig.TxtFuncMap()).Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s as a template: %w", name, err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -18,7 +18,13 @@ package configuration
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -47,12 +53,165 @@ type TemplateRepositoryManifest struct {
 	// Arguments are a declaration of arguments to the template generator
 	Arguments map[string]Argument `yaml:"arguments,omitempty"`
 
-	// DirReplacements is a list of directory name replacement templates to render
+	// ArgumentGroups are constraints on groups of arguments, e.g., that
+	// two arguments are mutually exclusive. They are validated against
+	// the resolved arguments before rendering.
+	ArgumentGroups []*ArgumentGroup `yaml:"argumentGroups,omitempty"`
+
+	// DirReplacements is a list of directory name replacement templates to render.
+	// A replacement that renders to an empty string is skipped, leaving
+	// the directory name as-is.
 	DirReplacements map[string]string `yaml:"dirReplacements,omitempty"`
 
+	// OutputPrefix is a directory, relative to the root of the project,
+	// that all files generated by this module are placed into. It is
+	// applied after dirReplacements. Absolute paths set by templates
+	// (e.g., file.SetPath "/README.md") are treated as relative to the
+	// root of the project and are not prefixed.
+	OutputPrefix string `yaml:"outputPrefix,omitempty"`
+
+	// BlockCommentPrefixes are comment prefixes (e.g., ";" for ini files
+	// or "REM" for batch files) that are recognized in front of blocks
+	// in the files generated by this module, in addition to "//", "##",
+	// "--" and "<!--".
+	BlockCommentPrefixes []string `yaml:"blockCommentPrefixes,omitempty"`
+
 	// ModuleHooks contains configuration for module hooks, keyed by their
 	// name.
 	ModuleHooks map[string]ModuleHook `yaml:"moduleHooks,omitempty"`
+
+	// ModuleHookSchemas contains additional JSON schemas for module hooks
+	// owned by other modules, keyed by the import path of the owning
+	// module and then the name of the module hook. They are only allowed
+	// for module hooks that are marked as extensible, see
+	// [ModuleHook.Extensible].
+	ModuleHookSchemas map[string]map[string]map[string]any `yaml:"moduleHookSchemas,omitempty"`
+
+	// TemplateDirConditions is a map of directories, relative to the
+	// templates/ directory, to a template that is rendered with the
+	// project's values to determine if the templates in that directory
+	// (and its subdirectories) should be rendered. The template must
+	// render to either "true" or "false".
+	TemplateDirConditions map[string]string `yaml:"templateDirConditions,omitempty"`
+
+	// BinarySources is a map of the paths of binary files generated by
+	// this module, relative to the templates/ directory like other
+	// templates, to a template that is rendered with the project's values
+	// to select the file, relative to the root of the module, that is
+	// copied verbatim into it (e.g., a binary for the target platform).
+	// When the template renders to multiple lines, the files are
+	// concatenated in order. If it renders to an empty string, the file
+	// is not generated.
+	BinarySources map[string]string `yaml:"binarySources,omitempty"`
+
+	// TemplateExtensions is a list of file extensions (e.g., ".gotmpl")
+	// that denote a template. The extension is stripped from the
+	// template's path to determine the path of the file it generates.
+	// Defaults to [DefaultTemplateExtensions] when not set.
+	TemplateExtensions []string `yaml:"templateExtensions,omitempty"`
+
+	// TemplateTags is a map of templates, or directories of templates,
+	// relative to the templates/ directory to a list of tags. Tags allow
+	// users to render only a subset of templates (e.g., stencil --tag
+	// docs). Templates inherit the tags of the directories they are in.
+	TemplateTags map[string][]string `yaml:"templateTags,omitempty"`
+
+	// FileModes is a map of globs to the octal mode (e.g., "0755") of the
+	// files generated by the templates they match. Globs are matched
+	// against the path of a template, relative to the templates/
+	// directory, without its template extension. Templates can override
+	// this with file.SetMode. See [TemplateRepositoryManifest.FileMode].
+	FileModes map[string]string `yaml:"fileModes,omitempty"`
+
+	// Helpers is a list of functions, defined in the module's library
+	// templates, that are callable by name from all of the module's
+	// templates (e.g., {{ FormatName "x" }}) without having to be
+	// exported with module.Export and called with module.Call.
+	Helpers []string `yaml:"helpers,omitempty"`
+
+	// TemplateDependsOn is a map of templates, relative to the
+	// templates/ directory, to the templates of this module that must
+	// be rendered before them (e.g., because they read a block of a
+	// file the other template generates).
+	TemplateDependsOn map[string][]string `yaml:"templateDependsOn,omitempty"`
+
+	// AllowedEnv is a list of environment variables that the templates
+	// of this module are allowed to read with stencil.Env. Reading any
+	// other environment variable is an error.
+	AllowedEnv []string `yaml:"allowedEnv,omitempty"`
+}
+
+// DefaultTemplateExtensions are the template extensions used when a
+// manifest does not set TemplateExtensions.
+var DefaultTemplateExtensions = []string{".tpl"}
+
+// TemplateExtension returns the template extension that the provided
+// path ends with, and whether it ended with one. When multiple
+// extensions match, the longest is returned.
+func (m *TemplateRepositoryManifest) TemplateExtension(path string) (string, bool) {
+	exts := DefaultTemplateExtensions
+	if m != nil && len(m.TemplateExtensions) != 0 {
+		exts = m.TemplateExtensions
+	}
+
+	var match string
+	for _, ext := range exts {
+		if ext != "" && strings.HasSuffix(path, ext) && len(ext) > len(match) {
+			match = ext
+		}
+	}
+	return match, match != ""
+}
+
+// TagsForTemplate returns the tags of the template at the provided
+// path, relative to the templates/ directory. This includes the tags
+// of all of the directories that contain it. The returned tags are
+// sorted and deduplicated.
+func (m *TemplateRepositoryManifest) TagsForTemplate(fpath string) []string {
+	if m == nil || len(m.TemplateTags) == 0 {
+		return nil
+	}
+
+	var tags []string
+	for p := filepath.ToSlash(fpath); p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		tags = append(tags, m.TemplateTags[p]...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// FileMode returns the mode declared in FileModes for the files
+// generated by the template at the provided path, relative to the
+// templates/ directory and without its template extension. When
+// multiple globs match, the longest one is used. False is returned if
+// no glob matches.
+func (m *TemplateRepositoryManifest) FileMode(fpath string) (os.FileMode, bool, error) {
+	if m == nil || len(m.FileModes) == 0 {
+		return 0, false, nil
+	}
+
+	fpath = filepath.ToSlash(fpath)
+
+	var match string
+	for _, glob := range slices.Sorted(maps.Keys(m.FileModes)) {
+		ok, err := path.Match(glob, fpath)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid glob %q in fileModes: %w", glob, err)
+		}
+		if ok && len(glob) > len(match) {
+			match = glob
+		}
+	}
+	if match == "" {
+		return 0, false, nil
+	}
+
+	mode, err := strconv.ParseUint(m.FileModes[match], 8, 32)
+	if err != nil || mode > 0o7777 {
+		return 0, false, fmt.Errorf("invalid mode %q for %q in fileModes, expected an octal mode (e.g., \"0755\")",
+			m.FileModes[match], match)
+	}
+	return os.FileMode(mode), true, nil
 }
 
 // PostRunCommandSpec is the spec of a command to be ran and its
@@ -64,6 +223,22 @@ type PostRunCommandSpec struct {
 	// Command is the command to be ran, note: this is ran inside
 	// of a bash shell.
 	Command string `yaml:"command" jsonschema:"required"`
+
+	// If contains conditions that must all be met for the command to be
+	// ran. When not set, the command is always ran.
+	If *PostRunCondition `yaml:"if,omitempty"`
+}
+
+// PostRunCondition contains conditions for running a post-run command.
+// All conditions that are set must be met.
+type PostRunCondition struct {
+	// FileExists is a path, relative to the root of the project, that
+	// must exist.
+	FileExists string `yaml:"fileExists,omitempty"`
+
+	// CommandExists is the name of a command that must be found in the
+	// PATH.
+	CommandExists string `yaml:"commandExists,omitempty"`
 }
 
 // Argument is a user-input argument that can be passed to
@@ -75,6 +250,13 @@ type Argument struct {
 	// Required denotes this argument as required.
 	Required bool `yaml:"required,omitempty"`
 
+	// RequiredWhen is a template, rendered like other templates of the
+	// module, that denotes this argument as required when it renders to
+	// "true" (e.g., '{{ stencil.Arg "tls" }}'). It must render to either
+	// "true" or "false". When it renders to "false", Default is used if
+	// the argument isn't set.
+	RequiredWhen string `yaml:"requiredWhen,omitempty"`
+
 	// Default is the default value for this argument if it's not set.
 	// This cannot be set when required is true.
 	Default interface{} `yaml:"default,omitempty"`
@@ -82,6 +264,30 @@ type Argument struct {
 	// Schema is a JSON schema, in YAML, for the argument.
 	Schema map[string]any `yaml:"schema"`
 
+	// Validation contains additional validation for the value of this
+	// argument, checked after Schema.
+	Validation *ArgumentValidation `yaml:"validation,omitempty"`
+
+	// Secret denotes the value of this argument as sensitive. Secret
+	// values are redacted when values are dumped for external tooling.
+	Secret bool `yaml:"secret,omitempty"`
+
+	// Examples are example values for this argument. They are included
+	// in the error when the argument is not set or its value is
+	// invalid.
+	Examples []any `yaml:"examples,omitempty"`
+
+	// Deprecated is a message explaining why this argument is
+	// deprecated and what to use instead. A warning is logged when a
+	// deprecated argument is set by a project.
+	Deprecated string `yaml:"deprecated,omitempty"`
+
+	// ReplacedBy is the name of the argument of this module that
+	// replaces this one, which deprecates this argument. When stencil is
+	// ran with --migrate, the value of this argument in stencil.yaml is
+	// moved to it.
+	ReplacedBy string `yaml:"replacedBy,omitempty"`
+
 	// From is a reference to an argument in another module, if this is
 	// set, all other fields are ignored and instead the module referenced
 	// field's are used instead. The name of the argument, the key in the map,
@@ -89,11 +295,53 @@ type Argument struct {
 	From string `yaml:"from,omitempty"`
 }
 
+// ArgumentGroup is a constraint on a group of arguments of a module. An
+// argument of the group is considered set when its value, or default
+// if the project doesn't set it, is not empty (e.g., not false, "" or
+// an empty list).
+type ArgumentGroup struct {
+	// Arguments are the names of the arguments in the group.
+	Arguments []string `yaml:"arguments" jsonschema:"required"`
+
+	// Exclusive denotes that at most one of the arguments may be set.
+	Exclusive bool `yaml:"exclusive,omitempty"`
+
+	// AtLeastOne denotes that at least one of the arguments must be set.
+	AtLeastOne bool `yaml:"atLeastOne,omitempty"`
+}
+
+// ArgumentValidation contains additional validation for the value of
+// an argument, allowing for friendlier error messages than a JSON
+// schema.
+type ArgumentValidation struct {
+	// Pattern is a regular expression (RE2 syntax) that the value of the
+	// argument, which must be a string, has to match.
+	Pattern string `yaml:"pattern,omitempty"`
+
+	// Message is the error message returned when the value does not
+	// match Pattern.
+	Message string `yaml:"message,omitempty"`
+}
+
 // ModuleHook contains configuration for a module hook.
 type ModuleHook struct {
 	// Schema is a JSON schema. When set this is used to validate all
 	// module hook data as it is inserted.
 	Schema map[string]any `yaml:"schema,omitempty"`
+
+	// MinItems is the minimum number of items that must be added to this
+	// module hook during a run.
+	MinItems int `yaml:"minItems,omitempty"`
+
+	// MaxItems is the maximum number of items that may be added to this
+	// module hook during a run. Zero means there is no maximum.
+	MaxItems int `yaml:"maxItems,omitempty"`
+
+	// Extensible denotes if other modules may provide additional schemas
+	// for this module hook through their ModuleHookSchemas. Data added
+	// to the module hook must then match at least one of the schemas
+	// (anyOf).
+	Extensible bool `yaml:"extensible,omitempty"`
 }
 
 // LoadTemplateRepositoryManifest reads a template repository manifest
@@ -19,6 +19,7 @@ package slogext
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 
@@ -55,7 +56,13 @@ const (
 
 // New creates a new logger using the slog package.
 func New() Logger {
-	handler := charmlog.New(os.Stdout)
+	return NewWithWriter(os.Stdout)
+}
+
+// NewWithWriter creates a new logger using the slog package that writes
+// to the provided writer instead of stdout.
+func NewWithWriter(w io.Writer) Logger {
+	handler := charmlog.New(w)
 	return &logger{slog.New(handler), handler}
 }
 
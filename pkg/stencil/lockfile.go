@@ -20,6 +20,7 @@ package stencil
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
@@ -49,6 +50,11 @@ type LockfileModuleEntry struct {
 	// Version is the version of the module that was
 	// downloaded at the time.
 	Version *resolver.Version
+
+	// Digest is a digest of the contents of the module that was
+	// downloaded at the time, e.g., "sha256:<hex>". This changes when
+	// the contents of a module change, even if its version did not.
+	Digest string `yaml:"digest,omitempty"`
 }
 
 // LockfileFileEntry is an entry in the lockfile for a file
@@ -68,6 +74,23 @@ type LockfileFileEntry struct {
 	Module string
 }
 
+// LockfileBlockEntry is an entry in the lockfile for the contents of a
+// block in a file generated by a module. Blocks are stored by the
+// module and their name, rather than the file they're in, so that they
+// can be recovered after the file, or the template that generates it,
+// is renamed.
+type LockfileBlockEntry struct {
+	// Module is the import path (Name) of the module that generated the
+	// file containing this block.
+	Module string
+
+	// Name is the name of the block.
+	Name string
+
+	// Contents are the contents of the block.
+	Contents string
+}
+
 // Lockfile is generated by stencil on a ran to store version
 // information.
 type Lockfile struct {
@@ -83,6 +106,11 @@ type Lockfile struct {
 	// Files is a list of files and metadata about them that were
 	// generated by stencil
 	Files []*LockfileFileEntry `yaml:"files"`
+
+	// Blocks contains the contents of the blocks in the files generated
+	// by stencil. This is only set when the project has lockBlocks
+	// enabled.
+	Blocks []*LockfileBlockEntry `yaml:"blocks,omitempty"`
 }
 
 // LoadLockfile loads a lockfile at the specified path. If 'path' is
@@ -94,12 +122,18 @@ func LoadLockfile(path string) (*Lockfile, error) {
 	}
 	defer f.Close()
 
+	return ReadLockfile(f)
+}
+
+// ReadLockfile parses a lockfile from the provided reader, e.g., the
+// contents of a lockfile from a previous commit.
+func ReadLockfile(r io.Reader) (*Lockfile, error) {
 	var lock *Lockfile
-	err = yaml.NewDecoder(f).Decode(&lock)
+	err := yaml.NewDecoder(r).Decode(&lock)
 	return lock, err
 }
 
-// MergeMissingInfoFromOlderLockfile merges missing files and modules from the older lockfile into the current one, for use with file.Once.
+// MergeMissingInfoFromOlderLockfile merges missing files, modules and blocks from the older lockfile into the current one, for use with file.Once.
 func (lf *Lockfile) MergeMissingInfoFromOlderLockfile(older *Lockfile) {
 	for _, f := range older.Files {
 		if !slices.ContainsFunc(lf.Files, func(fe *LockfileFileEntry) bool {
@@ -117,9 +151,35 @@ func (lf *Lockfile) MergeMissingInfoFromOlderLockfile(older *Lockfile) {
 		}
 	}
 
+	for _, b := range older.Blocks {
+		if !slices.ContainsFunc(lf.Blocks, func(be *LockfileBlockEntry) bool {
+			return be.Module == b.Module && be.Name == b.Name
+		}) {
+			lf.Blocks = append(lf.Blocks, b)
+		}
+	}
+
 	lf.Sort()
 }
 
+// BlocksForModule returns the contents of the blocks stored in the
+// lockfile for the provided module, keyed by their name. nil is
+// returned if no blocks were found.
+func (lf *Lockfile) BlocksForModule(module string) map[string]string {
+	var blocks map[string]string
+	for _, b := range lf.Blocks {
+		if b.Module != module {
+			continue
+		}
+
+		if blocks == nil {
+			blocks = make(map[string]string)
+		}
+		blocks[b.Name] = b.Contents
+	}
+	return blocks
+}
+
 // Sort maintains the alphabetic sort of files/modules to ensure deterministic output
 func (lf *Lockfile) Sort() {
 	sort.SliceStable(lf.Files, func(i, j int) bool {
@@ -129,10 +189,34 @@ func (lf *Lockfile) Sort() {
 	sort.SliceStable(lf.Modules, func(i, j int) bool {
 		return lf.Modules[i].Name < lf.Modules[j].Name
 	})
+
+	sort.SliceStable(lf.Blocks, func(i, j int) bool {
+		if lf.Blocks[i].Module != lf.Blocks[j].Module {
+			return lf.Blocks[i].Module < lf.Blocks[j].Module
+		}
+		return lf.Blocks[i].Name < lf.Blocks[j].Name
+	})
 }
 
 // Write writes the finished lockfile out to disk
+//
+// If a lockfile already exists, its comments and the order of its
+// fields are kept for the entries that are still present. Entries of
+// lists are matched by their name.
 func (lf *Lockfile) Write() error {
+	var n yaml.Node
+	if err := n.Encode(lf); err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&n}}
+	if b, err := os.ReadFile(LockfileName); err == nil {
+		var old yaml.Node
+		if err := yaml.Unmarshal(b, &old); err == nil && old.Kind == yaml.DocumentNode {
+			mergeYAMLNodes(&old, doc)
+		}
+	}
+
 	f, err := os.Create(LockfileName)
 	if err != nil {
 		return fmt.Errorf("failed to create lockfile: %w", err)
@@ -142,13 +226,94 @@ func (lf *Lockfile) Write() error {
 	enc := yaml.NewEncoder(f)
 	defer enc.Close()
 
-	if err := enc.Encode(lf); err != nil {
+	if err := enc.Encode(doc); err != nil {
 		return fmt.Errorf("failed to write lockfile: %w", err)
 	}
 
 	return nil
 }
 
+// mergeYAMLNodes copies the comments of the nodes in old onto the
+// matching nodes in n, and orders the keys of the mappings in n like
+// they are ordered in old. Keys that are only in n are kept after the
+// ones in both. Items of sequences are matched by their "name" key if
+// they are mappings, otherwise by their index.
+func mergeYAMLNodes(old, n *yaml.Node) {
+	if old == nil || n == nil || old.Kind != n.Kind {
+		return
+	}
+
+	n.HeadComment = old.HeadComment
+	n.LineComment = old.LineComment
+	n.FootComment = old.FootComment
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(old.Content) == 1 && len(n.Content) == 1 {
+			mergeYAMLNodes(old.Content[0], n.Content[0])
+		}
+	case yaml.MappingNode:
+		oldKeys := make(map[string]int, len(old.Content)/2)
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			oldKeys[old.Content[i].Value] = i
+		}
+
+		type pair struct{ key, val *yaml.Node }
+		pairs := make([]pair, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			pairs = append(pairs, pair{n.Content[i], n.Content[i+1]})
+		}
+
+		position := func(p pair) int {
+			if i, ok := oldKeys[p.key.Value]; ok {
+				return i
+			}
+			return len(old.Content)
+		}
+		slices.SortStableFunc(pairs, func(a, b pair) int { return position(a) - position(b) })
+
+		n.Content = n.Content[:0]
+		for _, p := range pairs {
+			if i, ok := oldKeys[p.key.Value]; ok {
+				mergeYAMLNodes(old.Content[i], p.key)
+				mergeYAMLNodes(old.Content[i+1], p.val)
+			}
+			n.Content = append(n.Content, p.key, p.val)
+		}
+	case yaml.SequenceNode:
+		oldNames := make(map[string]*yaml.Node)
+		for _, item := range old.Content {
+			if name := yamlItemName(item); name != "" {
+				oldNames[name] = item
+			}
+		}
+
+		for i, item := range n.Content {
+			if name := yamlItemName(item); name != "" {
+				mergeYAMLNodes(oldNames[name], item)
+			} else if i < len(old.Content) {
+				mergeYAMLNodes(old.Content[i], item)
+			}
+		}
+	}
+}
+
+// yamlItemName returns the value of the "name" key of the provided
+// mapping node, or an empty string if it isn't a mapping or has no
+// name.
+func yamlItemName(n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "name" && n.Content[i+1].Kind == yaml.ScalarNode {
+			return n.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
 func (lf *Lockfile) PruneFiles(onlyFiles []string) []string {
 	missingFilesList := []*LockfileFileEntry{}
 	for _, lf := range lf.Files {
@@ -192,5 +357,10 @@ func (lf *Lockfile) PruneModules(newModuleNames, onlyModules []string) []string
 		lf.Modules = slices.Delete(lf.Modules, idx, idx+1)
 	}
 
+	// Blocks are stored by module, so they're pruned along with it.
+	lf.Blocks = slices.DeleteFunc(lf.Blocks, func(b *LockfileBlockEntry) bool {
+		return slices.Contains(missingModuleNames, b.Module)
+	})
+
 	return missingModuleNames
 }
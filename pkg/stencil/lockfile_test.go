@@ -2,10 +2,12 @@ package stencil_test
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"go.rgst.io/stencil/v2/pkg/stencil"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
 )
 
 func ExampleLoadLockfile() {
@@ -97,6 +99,8 @@ func TestLockfilePruneModulesMissingModule(t *testing.T) {
 func TestLockfilePruneModulesMissingModuleSpecified(t *testing.T) {
 	l := stencil.Lockfile{Modules: []*stencil.LockfileModuleEntry{
 		{Name: "foo"},
+	}, Blocks: []*stencil.LockfileBlockEntry{
+		{Module: "foo", Name: "x"},
 	}}
 
 	ret := l.PruneModules([]string{}, []string{"foo"})
@@ -104,6 +108,7 @@ func TestLockfilePruneModulesMissingModuleSpecified(t *testing.T) {
 
 	l.Sort()
 	assert.Equal(t, 0, len(l.Modules))
+	assert.Equal(t, 0, len(l.Blocks))
 }
 
 // TestLockfilePruneModulesMissingModuleSpecifiedWrong tests lockfile's prunemodules function for a missing module that was specified wrong
@@ -129,3 +134,75 @@ func TestLockfileSortList(t *testing.T) {
 	assert.Equal(t, "bar.foo", l.Files[0].Name)
 	assert.Equal(t, "foo.bar", l.Files[1].Name)
 }
+
+func TestLockfileBlocksForModule(t *testing.T) {
+	l := stencil.Lockfile{Blocks: []*stencil.LockfileBlockEntry{
+		{Module: "mod", Name: "x", Contents: "a"},
+		{Module: "mod", Name: "y", Contents: "b"},
+		{Module: "other", Name: "x", Contents: "c"},
+	}}
+
+	assert.DeepEqual(t, l.BlocksForModule("mod"), map[string]string{"x": "a", "y": "b"})
+	assert.DeepEqual(t, l.BlocksForModule("other"), map[string]string{"x": "c"})
+	assert.Assert(t, l.BlocksForModule("unknown") == nil)
+}
+
+func TestLockfileMergeMissingBlocks(t *testing.T) {
+	l := stencil.Lockfile{Blocks: []*stencil.LockfileBlockEntry{
+		{Module: "mod", Name: "x", Contents: "new"},
+	}}
+	l.MergeMissingInfoFromOlderLockfile(&stencil.Lockfile{Blocks: []*stencil.LockfileBlockEntry{
+		{Module: "mod", Name: "x", Contents: "old"},
+		{Module: "mod", Name: "y", Contents: "old"},
+	}})
+
+	assert.DeepEqual(t, l.Blocks, []*stencil.LockfileBlockEntry{
+		{Module: "mod", Name: "x", Contents: "new"},
+		{Module: "mod", Name: "y", Contents: "old"},
+	})
+}
+
+func TestLockfileWritePreservesComments(t *testing.T) {
+	env.ChangeWorkingDir(t, t.TempDir())
+
+	assert.NilError(t, os.WriteFile(stencil.LockfileName, []byte(`# Managed by stencil, see docs/stencil.md.
+files:
+    # Generated once, edited by hand since.
+    - name: README.md
+      template: README.md.tpl
+      module: github.com/rgst-io/stencil-base
+version: v1.0.0 # bumped by CI
+modules: []
+`), 0o644))
+
+	l := &stencil.Lockfile{
+		Version: "v1.1.0",
+		Modules: []*stencil.LockfileModuleEntry{},
+		Files: []*stencil.LockfileFileEntry{
+			{Name: "LICENSE", Template: "LICENSE.tpl", Module: "github.com/rgst-io/stencil-base"},
+			{Name: "README.md", Template: "README.md.tpl", Module: "github.com/rgst-io/stencil-base"},
+		},
+	}
+	assert.NilError(t, l.Write())
+
+	b, err := os.ReadFile(stencil.LockfileName)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `# Managed by stencil, see docs/stencil.md.
+files:
+    - name: LICENSE
+      template: LICENSE.tpl
+      module: github.com/rgst-io/stencil-base
+    # Generated once, edited by hand since.
+    - name: README.md
+      template: README.md.tpl
+      module: github.com/rgst-io/stencil-base
+version: v1.1.0 # bumped by CI
+modules: []
+`)
+
+	// The written lockfile is still valid.
+	written, err := stencil.LoadLockfile("")
+	assert.NilError(t, err)
+	assert.Equal(t, written.Version, "v1.1.0")
+	assert.Equal(t, len(written.Files), 2)
+}
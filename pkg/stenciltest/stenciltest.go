@@ -0,0 +1,468 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package stenciltest contains code for testing templates inside of
+// a stencil module.
+package stenciltest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy/v2"
+	"github.com/jaredallard/cmdexec"
+	"go.rgst.io/stencil/v2/internal/ci"
+	"go.rgst.io/stencil/v2/internal/codegen"
+	"go.rgst.io/stencil/v2/internal/modules"
+	"go.rgst.io/stencil/v2/internal/modules/modulestest"
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/extensions/apiv1"
+	"go.rgst.io/stencil/v2/pkg/slogext"
+	"gopkg.in/yaml.v3"
+	"gotest.tools/v3/assert"
+)
+
+// Template is a template that is being tested by the stenciltest
+// framework.
+type Template struct {
+	// path is the path to the template.
+	path string
+
+	// additionalTemplates is a list of additional templates to add to
+	// the renderer, but not to snapshot.
+	additionalTemplates []string
+
+	// m is the template repository manifest for this test
+	m *configuration.TemplateRepositoryManifest
+
+	// t is a testing object.
+	t *testing.T
+
+	// args are the arguments to the template.
+	args map[string]any
+
+	// exts holds the inproc extensions
+	exts map[string]apiv1.Implementation
+
+	// errStr is the string an error should contain, if this is set then
+	// the template MUST error.
+	errStr string
+
+	// persist denotes if we should create snapshots that do not already
+	// exist or not. This is disabled when running in CI to ensure that
+	// missing snapshots fail tests instead of being silently created.
+	persist bool
+
+	// files are the files generated by the last call to [Template.Run],
+	// keyed by their path. This is nil until Run has been called.
+	files map[string]*codegen.File
+
+	// mockModules are the manifests of the mock modules registered
+	// through [Template.MockModuleHook], keyed by the module's name.
+	mockModules map[string]*configuration.TemplateRepositoryManifest
+
+	// moduleHooks contains the data added to module hooks registered
+	// through [Template.MockModuleHook] by the last call to
+	// [Template.Run], keyed by module and hook name. This is nil until
+	// Run has been called.
+	moduleHooks map[string]map[string][]any
+
+	// exampleDir is the directory the generated files are written to
+	// after a successful run, see [Template.EmitExample].
+	exampleDir string
+}
+
+// New creates a new test for a given template. The manifest.yaml at
+// the root of the Go module the test is being ran from is used as the
+// manifest for the module being tested.
+func New(t *testing.T, templatePath string, additionalTemplates ...string) *Template {
+	// GOMOD: <module path>/go.mod
+	b, err := cmdexec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		t.Fatalf("failed to determine path to manifest: %v", err)
+	}
+	basepath := strings.TrimSuffix(strings.TrimSpace(string(b)), "/go.mod")
+
+	b, err = os.ReadFile(filepath.Join(basepath, "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m configuration.TemplateRepositoryManifest
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Template{
+		t:                   t,
+		m:                   &m,
+		path:                templatePath,
+		additionalTemplates: additionalTemplates,
+		persist:             !ci.IsCI(),
+		exts:                make(map[string]apiv1.Implementation),
+		mockModules:         make(map[string]*configuration.TemplateRepositoryManifest),
+	}
+}
+
+// Args sets the arguments to the template.
+func (t *Template) Args(args map[string]any) *Template {
+	t.args = args
+	return t
+}
+
+// Ext registers an in-proc extension with the current stencil
+// template. The stenciltest library does not load the real extensions
+// (because extensions can invoke outbound network calls). It is up to
+// the unit test to provide each extension used by their template with
+// this API. Unit tests can decide if they can use the real
+// implementation of the extension AS IS, or if a mock extension is
+// required to feed fake data.
+func (t *Template) Ext(name string, ext apiv1.Implementation) *Template {
+	t.exts[name] = ext
+	return t
+}
+
+// MockModuleHook registers a mock module that owns the provided module
+// hook. This allows testing the data a template contributes to a
+// module hook owned by another module (through
+// stencil.AddToModuleHook) without needing the real module. If schema
+// is provided, it is used to validate all data added to the hook, just
+// like a schema declared in a module's manifest would be.
+//
+// The accumulated contributions can be asserted on after
+// [Template.Run] with [Template.AssertModuleHookContains] or retrieved
+// with [Template.ModuleHook].
+//
+//	st.MockModuleHook("github.com/myorg/repo", "myModuleHook", nil)
+//	st.Run(false)
+//	st.AssertModuleHookContains("github.com/myorg/repo", "myModuleHook", "myData")
+func (t *Template) MockModuleHook(module, name string, schema map[string]any) *Template {
+	m, ok := t.mockModules[module]
+	if !ok {
+		m = &configuration.TemplateRepositoryManifest{
+			Name:        module,
+			ModuleHooks: make(map[string]configuration.ModuleHook),
+		}
+		t.mockModules[module] = m
+	}
+	m.ModuleHooks[name] = configuration.ModuleHook{Schema: schema}
+	return t
+}
+
+// Libraries registers library templates (templates ending in .library
+// followed by a template extension, e.g., .library.tpl) from the module
+// being tested with the renderer. This allows testing a template that
+// uses functions exported (through module.Export) by a library template
+// of the same module with module.Call. Like additional templates,
+// library templates are not snapshotted.
+//
+//	st := stenciltest.New(t, "config.yaml.tpl")
+//	st.Libraries("helpers.library.tpl")
+//	st.Run(false)
+func (t *Template) Libraries(paths ...string) *Template {
+	t.t.Helper()
+	for _, p := range paths {
+		ext, ok := t.m.TemplateExtension(p)
+		if !ok {
+			t.t.Fatalf("expected library template %q to have a template extension", p)
+		}
+		if !strings.HasSuffix(strings.TrimSuffix(p, ext), ".library") {
+			t.t.Fatalf("expected library template %q to have the .library%s extension", p, ext)
+		}
+		if !slices.Contains(t.additionalTemplates, p) {
+			t.additionalTemplates = append(t.additionalTemplates, p)
+		}
+	}
+	return t
+}
+
+// EmitExample writes the files generated by a successful [Template.Run]
+// into the provided directory, e.g., to keep an example of a module's
+// output in its documentation. Files that already exist in the
+// directory are overwritten, files that are no longer generated are
+// not removed. Skipped and deleted files are not written.
+//
+//	st := stenciltest.New(t, "config.yaml.tpl")
+//	st.EmitExample("examples/basic")
+//	st.Run(false)
+func (t *Template) EmitExample(dir string) *Template {
+	t.exampleDir = dir
+	return t
+}
+
+// ErrorContains denotes that this test run should fail, and the message
+// should contain the provided string.
+//
+//	t.ErrorContains("i am an error")
+func (t *Template) ErrorContains(msg string) {
+	t.errStr = msg
+}
+
+// Run runs the test. If save is true, a snapshot of every file
+// generated by the template being tested is created (or compared
+// against).
+func (t *Template) Run(save bool) {
+	t.t.Run(t.path, func(got *testing.T) {
+		m, err := modulestest.NewModuleFromTemplates(t.m, append([]string{t.path}, t.additionalTemplates...)...)
+		if err != nil {
+			got.Fatalf("failed to create module from template %q: %v", t.path, err)
+		}
+
+		mods := []*modules.Module{m}
+		for _, mm := range t.mockModules {
+			mock, err := modulestest.NewModuleFromTemplates(mm)
+			if err != nil {
+				got.Fatalf("failed to create mock module %q: %v", mm.Name, err)
+			}
+			mods = append(mods, mock)
+		}
+
+		log := slogext.NewTestLogger(got)
+		mf := &configuration.Manifest{
+			Name:      "testing",
+			Arguments: t.args,
+			Modules:   []*configuration.TemplateRepository{{Name: m.Name}},
+		}
+		st := codegen.NewStencil(mf, nil, mods, log, false)
+		defer st.Close()
+
+		for name, ext := range t.exts {
+			st.RegisterInprocExtensions(name, ext)
+		}
+
+		tpls, err := st.Render(context.Background(), log)
+		if t.errStr != "" {
+			// If t.errStr was set then we expected an error, since that was
+			// set via t.ErrorContains().
+			assert.ErrorContains(got, err, t.errStr, "expected render to fail with error containing %q", t.errStr)
+			return
+		}
+		if err != nil {
+			got.Fatalf("failed to render: %v", err)
+		}
+
+		t.moduleHooks = make(map[string]map[string][]any)
+		for _, mm := range t.mockModules {
+			t.moduleHooks[mm.Name] = make(map[string][]any)
+			for name := range mm.ModuleHooks {
+				t.moduleHooks[mm.Name][name] = st.GetModuleHook(mm.Name, name)
+			}
+		}
+
+		t.files = make(map[string]*codegen.File)
+		for _, tpl := range tpls {
+			for _, f := range tpl.Files {
+				t.files[f.Name()] = f
+			}
+
+			// Only snapshot the template being tested.
+			if !save || tpl.Path != t.path {
+				continue
+			}
+
+			for _, f := range tpl.Files {
+				got.Run(f.Name(), func(got *testing.T) {
+					// Create snapshots with a .snapshot ext to keep them away
+					// from linters, see:
+					// https://github.com/bradleyjkemp/cupaloy/issues/85
+					snapshot := cupaloy.New(
+						cupaloy.SnapshotSubdirectory("testdata"),
+						cupaloy.SnapshotFileExtension(".snapshot"),
+						cupaloy.CreateNewAutomatically(t.persist),
+					)
+					snapshot.SnapshotT(got, f.String())
+				})
+			}
+		}
+
+		// Don't update the example with output that didn't match the
+		// snapshots.
+		if got.Failed() {
+			return
+		}
+
+		if t.exampleDir != "" {
+			if err := writeExample(t.exampleDir, tpls); err != nil {
+				got.Fatalf("failed to write example to %q: %v", t.exampleDir, err)
+			}
+		}
+	})
+}
+
+// writeExample writes the files generated by the provided templates
+// into dir, see [Template.EmitExample].
+func writeExample(dir string, tpls []*codegen.Template) error {
+	for _, tpl := range tpls {
+		for _, f := range tpl.Files {
+			if f.Skipped || f.Deleted {
+				continue
+			}
+			if !filepath.IsLocal(f.Name()) {
+				return fmt.Errorf("file %q is outside of the project", f.Name())
+			}
+
+			p := filepath.Join(dir, f.Name())
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(p, f.Bytes(), f.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AssertFileContains asserts that the file at the provided path was
+// generated by the last call to [Template.Run] and that its contents
+// contain substr.
+//
+//	st := stenciltest.New(t, "config.yaml.tpl")
+//	st.Run(false)
+//	st.AssertFileContains("config.yaml", "name: testing")
+func (t *Template) AssertFileContains(path, substr string) {
+	t.t.Helper()
+	if err := t.fileContains(path, substr); err != nil {
+		t.t.Error(err)
+	}
+}
+
+// AssertFileEquals asserts that the file at the provided path was
+// generated by the last call to [Template.Run] and that its contents
+// are exactly equal to content.
+//
+//	st := stenciltest.New(t, "config.yaml.tpl")
+//	st.Run(false)
+//	st.AssertFileEquals("config.yaml", "name: testing\n")
+func (t *Template) AssertFileEquals(path, content string) {
+	t.t.Helper()
+	if err := t.fileEquals(path, content); err != nil {
+		t.t.Error(err)
+	}
+}
+
+// fileContains returns an error if the generated file at path does not
+// contain substr.
+func (t *Template) fileContains(path, substr string) error {
+	f, err := t.file(path)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(f.String(), substr) {
+		return fmt.Errorf("expected file %q to contain %q, got:\n%s", path, substr, f.String())
+	}
+	return nil
+}
+
+// fileEquals returns an error if the generated file at path does not
+// have exactly the provided contents.
+func (t *Template) fileEquals(path, content string) error {
+	f, err := t.file(path)
+	if err != nil {
+		return err
+	}
+
+	if f.String() != content {
+		return fmt.Errorf("expected file %q to equal %q, got %q", path, content, f.String())
+	}
+	return nil
+}
+
+// file returns a file generated by the last call to [Template.Run].
+// Skipped and deleted files are treated as not being generated.
+func (t *Template) file(path string) (*codegen.File, error) {
+	if t.files == nil {
+		return nil, fmt.Errorf("no files were generated, Run must be called (and succeed) before asserting on files")
+	}
+
+	f, ok := t.files[path]
+	if !ok || f.Skipped || f.Deleted {
+		generated := make([]string, 0, len(t.files))
+		for name, f := range t.files {
+			if f.Skipped || f.Deleted {
+				continue
+			}
+			generated = append(generated, name)
+		}
+		slices.Sort(generated)
+
+		return nil, fmt.Errorf("file %q was not generated (generated files: %v)", path, generated)
+	}
+	return f, nil
+}
+
+// ModuleHook returns the data added to a module hook registered with
+// [Template.MockModuleHook] by the last call to [Template.Run]. The
+// order of the returned data is not guaranteed.
+func (t *Template) ModuleHook(module, name string) []any {
+	t.t.Helper()
+	v, err := t.moduleHook(module, name)
+	if err != nil {
+		t.t.Fatal(err)
+	}
+	return v
+}
+
+// AssertModuleHookContains asserts that the provided data was added to
+// a module hook registered with [Template.MockModuleHook] during the
+// last call to [Template.Run].
+//
+//	st.MockModuleHook("github.com/myorg/repo", "myModuleHook", nil)
+//	st.Run(false)
+//	st.AssertModuleHookContains("github.com/myorg/repo", "myModuleHook", "myData")
+func (t *Template) AssertModuleHookContains(module, name string, data any) {
+	t.t.Helper()
+	if err := t.moduleHookContains(module, name, data); err != nil {
+		t.t.Error(err)
+	}
+}
+
+// moduleHookContains returns an error if data was not added to the
+// provided module hook.
+func (t *Template) moduleHookContains(module, name string, data any) error {
+	v, err := t.moduleHook(module, name)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range v {
+		if reflect.DeepEqual(d, data) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected module hook %q of module %q to contain %#v, got: %#v", name, module, data, v)
+}
+
+// moduleHook returns the data added to a mocked module hook by the last
+// call to [Template.Run].
+func (t *Template) moduleHook(module, name string) ([]any, error) {
+	if t.moduleHooks == nil {
+		return nil, fmt.Errorf("no module hooks were captured, Run must be called (and succeed) before asserting on module hooks")
+	}
+
+	v, ok := t.moduleHooks[module][name]
+	if !ok {
+		return nil, fmt.Errorf("module hook %q of module %q was not registered, MockModuleHook must be called before Run", name, module)
+	}
+	return v, nil
+}
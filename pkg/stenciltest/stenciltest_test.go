@@ -0,0 +1,127 @@
+package stenciltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rgst.io/stencil/v2/pkg/configuration"
+	"go.rgst.io/stencil/v2/pkg/extensions/apiv1"
+	"gotest.tools/v3/assert"
+)
+
+// newTestTemplate returns a [Template] for the provided template path
+// that does not persist snapshots.
+func newTestTemplate(t *testing.T, path string) *Template {
+	return &Template{
+		path:                path,
+		additionalTemplates: make([]string, 0),
+		m: &configuration.TemplateRepositoryManifest{
+			Name: "testing",
+			Arguments: map[string]configuration.Argument{
+				"name":      {Schema: map[string]any{"type": "string"}},
+				"adjective": {Schema: map[string]any{"type": "string"}},
+			},
+		},
+		t:           t,
+		exts:        make(map[string]apiv1.Implementation),
+		mockModules: make(map[string]*configuration.TemplateRepositoryManifest),
+		persist:     false,
+	}
+}
+
+func TestCanRunTemplate(t *testing.T) {
+	st := newTestTemplate(t, "testdata/test.tpl")
+	st.Args(map[string]any{"name": "stencil", "adjective": "cool"})
+	st.Run(false)
+}
+
+func TestErrorHandling(t *testing.T) {
+	st := newTestTemplate(t, "testdata/error.tpl")
+	st.ErrorContains("sad")
+	st.Run(false)
+}
+
+func TestAssertFileContains(t *testing.T) {
+	st := newTestTemplate(t, "testdata/test.tpl")
+	st.Args(map[string]any{"name": "stencil", "adjective": "cool"})
+	st.Run(false)
+
+	st.AssertFileContains("testdata/test", "stencil is cool")
+	st.AssertFileEquals("testdata/test", "stencil is cool\n")
+}
+
+func TestEmitExample(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "examples", "basic")
+
+	st := newTestTemplate(t, "testdata/test.tpl")
+	st.Args(map[string]any{"name": "stencil", "adjective": "cool"})
+	st.EmitExample(dir)
+	st.Run(false)
+
+	b, err := os.ReadFile(filepath.Join(dir, "testdata", "test"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "stencil is cool\n")
+}
+
+func TestAssertFileContainsNonMatching(t *testing.T) {
+	st := newTestTemplate(t, "testdata/test.tpl")
+	st.Args(map[string]any{"name": "stencil", "adjective": "cool"})
+	st.Run(false)
+
+	assert.ErrorContains(t, st.fileContains("testdata/test", "stencil is lame"),
+		`expected file "testdata/test" to contain "stencil is lame"`)
+	assert.ErrorContains(t, st.fileEquals("testdata/test", "stencil is cool"),
+		`expected file "testdata/test" to equal "stencil is cool"`)
+}
+
+func TestAssertFileMissing(t *testing.T) {
+	st := newTestTemplate(t, "testdata/test.tpl")
+	assert.ErrorContains(t, st.fileContains("testdata/test", "stencil"), "Run must be called")
+
+	st.Args(map[string]any{"name": "stencil", "adjective": "cool"})
+	st.Run(false)
+	assert.ErrorContains(t, st.fileContains("does-not-exist", "stencil"),
+		`file "does-not-exist" was not generated (generated files: [testdata/test])`)
+}
+
+func TestCanAssertModuleHookContributions(t *testing.T) {
+	st := newTestTemplate(t, "testdata/hook.tpl")
+	st.MockModuleHook("github.com/rgst-io/stencil-golang", "extraDeps", map[string]any{
+		"type":     "object",
+		"required": []any{"name"},
+	})
+	st.Run(false)
+
+	st.AssertModuleHookContains("github.com/rgst-io/stencil-golang", "extraDeps", map[string]any{"name": "foo"})
+	assert.DeepEqual(t, st.ModuleHook("github.com/rgst-io/stencil-golang", "extraDeps")[0],
+		map[string]any{"name": "foo"})
+
+	assert.ErrorContains(t, st.moduleHookContains("github.com/rgst-io/stencil-golang", "extraDeps", "bar"),
+		`expected module hook "extraDeps" of module "github.com/rgst-io/stencil-golang" to contain "bar"`)
+	assert.ErrorContains(t, st.moduleHookContains("github.com/rgst-io/stencil-golang", "notRegistered", "bar"),
+		"MockModuleHook must be called before Run")
+}
+
+func TestMockModuleHookValidatesSchema(t *testing.T) {
+	st := newTestTemplate(t, "testdata/hook.tpl")
+	st.MockModuleHook("github.com/rgst-io/stencil-golang", "extraDeps", map[string]any{
+		"type":     "object",
+		"required": []any{"version"},
+	})
+	st.ErrorContains("data failed json schema validation")
+	st.Run(false)
+}
+
+func TestCanCallLibraryTemplate(t *testing.T) {
+	// Render more than once to ensure exported functions don't leak
+	// between renders.
+	for range 2 {
+		st := newTestTemplate(t, "testdata/greeting.tpl")
+		st.Libraries("testdata/greet.library.tpl")
+		st.Args(map[string]any{"name": "stencil"})
+		st.Run(false)
+
+		st.AssertFileEquals("testdata/greeting", "Hello, stencil!\n")
+	}
+}
@@ -0,0 +1 @@
+{{- fail "sad" }}
@@ -0,0 +1,4 @@
+{{- define "Greet" }}
+{{- return (printf "Hello, %s!" .Data) }}
+{{- end }}
+{{- module.Export "Greet" }}
@@ -0,0 +1 @@
+{{ module.Call "testing.Greet" (stencil.Arg "name") }}
@@ -0,0 +1 @@
+{{- stencil.AddToModuleHook "github.com/rgst-io/stencil-golang" "extraDeps" (dict "name" "foo") }}
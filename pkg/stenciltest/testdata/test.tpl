@@ -0,0 +1 @@
+{{- stencil.Arg "name" }} is {{ stencil.Arg "adjective" }}
@@ -0,0 +1,35 @@
+// Copyright (C) 2025 stencil contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//         http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Description: This file embeds the JSON schemas of stencil's
+// configuration files.
+
+// Package schemas contains the JSON schemas of stencil's configuration
+// files, as generated by tools/schemagen.
+package schemas
+
+import _ "embed"
+
+// Project is the JSON schema of a project's manifest (stencil.yaml).
+//
+//go:embed stencil.jsonschema.json
+var Project []byte
+
+// Module is the JSON schema of a template repository's manifest
+// (manifest.yaml).
+//
+//go:embed manifest.jsonschema.json
+var Module []byte